@@ -6,6 +6,7 @@ import (
 
 	"github.com/openshift-knative/hack/pkg/dockerfilegen"
 	"github.com/openshift-knative/hack/pkg/util/errors"
+	"github.com/openshift-knative/hack/pkg/verify"
 	"github.com/spf13/pflag"
 )
 
@@ -26,10 +27,19 @@ func GenerateMain(args []string) error {
 	if fset, err = params.ConfigureFlags(); err != nil {
 		return err
 	}
+	verifyOnly := fset.Bool("verify", false, "Fail with a unified diff when the generated files differ from the existing ones, without modifying them")
 	if err = fset.Parse(args); err != nil {
 		return err
 	}
 
+	if *verifyOnly {
+		diff, err := dockerfilegen.VerifyDockerfiles(params)
+		if err != nil {
+			return err
+		}
+		return verify.Check(os.Stdout, diff)
+	}
+
 	if err = dockerfilegen.GenerateDockerfiles(params); err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 
 	"github.com/openshift-knative/hack/pkg/konfluxgen"
+	"github.com/openshift-knative/hack/pkg/prowgen"
 	"github.com/spf13/pflag"
 
 	"github.com/openshift-knative/hack/pkg/konfluxapply"
@@ -26,7 +28,10 @@ func main() {
 	inputConfig := pflag.String("config", filepath.Join("config"), "Specify repositories config")
 	konfluxDir := pflag.String("konflux-dir", filepath.Join(".konflux", konfluxgen.ApplicationsDirectoryName), "Konflux directory containing applications, components, etc")
 	additionalDirs := pflag.StringArray("additional-dirs", defaultAdditionalDirs, "Additional directories to apply")
+	workspace := prowgen.RegisterWorkspaceFlags(flag.CommandLine)
+	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 	pflag.Parse()
+	ctx = prowgen.WithWorkspace(ctx, workspace)
 
 	err := konfluxapply.Apply(ctx, konfluxapply.ApplyConfig{
 		InputConfigPath: *inputConfig,
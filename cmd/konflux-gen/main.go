@@ -3,10 +3,12 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/spf13/pflag"
 
 	"github.com/openshift-knative/hack/pkg/konfluxgen"
+	"github.com/openshift-knative/hack/pkg/verify"
 )
 
 const (
@@ -18,6 +20,7 @@ const (
 	fbcBuilderImagesFlag     = "fbc-images"
 	outputFlag               = "output"
 	pipelineOutputFlag       = "pipeline-output"
+	verifyFlag               = "verify"
 )
 
 func main() {
@@ -29,6 +32,7 @@ func main() {
 func run() error {
 
 	cfg := konfluxgen.Config{}
+	var verifyOnly bool
 
 	pflag.StringVar(&cfg.OpenShiftReleasePath, openShiftReleasePathFlag, "", "openshift/release repository path")
 	pflag.StringVar(&cfg.ApplicationName, applicationNameFlag, "", "Konflux application name")
@@ -38,6 +42,7 @@ func run() error {
 	pflag.StringArrayVar(&cfg.Excludes, excludesFlag, nil, "Regex to select CI config files to exclude")
 	pflag.StringArrayVar(&cfg.ExcludesImages, excludeImagesFlag, nil, "Regex to select CI config images to exclude")
 	pflag.StringArrayVar(&cfg.FBCImages, fbcBuilderImagesFlag, nil, "Regex to select File-Based Catalog images")
+	pflag.BoolVar(&verifyOnly, verifyFlag, false, "Fail with a unified diff when the generated files differ from the existing ones, without modifying them")
 	pflag.Parse()
 
 	if cfg.OpenShiftReleasePath == "" {
@@ -47,5 +52,13 @@ func run() error {
 		return fmt.Errorf("expected %q flag to be non empty", includesFlag)
 	}
 
+	if verifyOnly {
+		diff, err := konfluxgen.Verify(cfg)
+		if err != nil {
+			return err
+		}
+		return verify.Check(os.Stdout, diff)
+	}
+
 	return konfluxgen.Generate(cfg)
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/openshift-knative/hack/pkg/konfluxgen"
 	"github.com/openshift-knative/hack/pkg/project"
 	"github.com/openshift-knative/hack/pkg/prowgen"
+	"github.com/openshift-knative/hack/pkg/publish"
 	"github.com/openshift-knative/hack/pkg/soversion"
 	"github.com/spf13/pflag"
 )
@@ -33,12 +34,17 @@ func run() error {
 		prodEnv  = "prod"
 	)
 
-	var environment, soRevision, overrideSnapshotDir, output, releaseType string
+	var environment, soRevision, overrideSnapshotDir, output, releaseType, forkOwner string
+	var publishPR bool
+	var labels []string
 	pflag.StringVar(&environment, "environment", "prod", fmt.Sprintf("Environment to use. Available values: [%s, %s]", stageEnv, prodEnv))
 	pflag.StringVar(&soRevision, "so-revision", "main", "SO revision to get snapshots from")
 	pflag.StringVar(&releaseType, "type", "component", fmt.Sprintf("Type of the release. Available values: [%s, %s]", componentReleaseType, fbcReleaseType))
 	pflag.StringVar(&overrideSnapshotDir, "so-snapshot-directory", ".konflux-release", "The directory containing Serverless Operator override snapshots")
 	pflag.StringVar(&output, "output", ".konflux", "Path to output directory")
+	pflag.BoolVar(&publishPR, "publish", false, "Push the branch to the fork and create or update the pull request")
+	pflag.StringVar(&forkOwner, "fork-owner", publish.DefaultForkOwner, "Owner of the fork the branch is pushed to")
+	pflag.StringSliceVar(&labels, "label", nil, "Labels added to the pull request")
 	pflag.Parse()
 
 	if environment != stageEnv && environment != prodEnv {
@@ -55,7 +61,7 @@ func run() error {
 		return fmt.Errorf("could not checkout Git revision %s of Serverless Operator: %w", soRevision, err)
 	}
 
-	soProjectYamlPath := filepath.Join(soRepo.RepositoryDirectory(), "olm-catalog", "serverless-operator", "project.yaml")
+	soProjectYamlPath := filepath.Join(soRepo.LocalDirectory(ctx), "olm-catalog", "serverless-operator", "project.yaml")
 	soMetadata, err := project.ReadMetadataFile(soProjectYamlPath)
 	if err != nil {
 		return fmt.Errorf("could not read project.yaml: %w", err)
@@ -64,11 +70,11 @@ func run() error {
 	soVersion := semver.New(soMetadata.Project.Version)
 	soReleaseBranch := soversion.BranchName(soVersion)
 
-	overrideSnapshotsPath := filepath.Join(soRepo.RepositoryDirectory(), overrideSnapshotDir)
+	overrideSnapshotsPath := filepath.Join(soRepo.LocalDirectory(ctx), overrideSnapshotDir)
 
 	// clone hack repo so we can commit the changes
 	hackRepo := prowgen.Repository{Org: "openshift-knative", Repo: "hack"}
-	outputDir := filepath.Join(hackRepo.RepositoryDirectory(), output)
+	outputDir := filepath.Join(hackRepo.LocalDirectory(ctx), output)
 
 	if err := prowgen.GitMirror(ctx, hackRepo); err != nil {
 		return fmt.Errorf("could not clone Git repository: %w", err)
@@ -130,6 +136,15 @@ func run() error {
 		return fmt.Errorf("could not push to branch %s: %w", pushBranch, err)
 	}
 
+	if publishPR {
+		p := publish.NewPublisher(publish.WithForkOwner(forkOwner))
+		result, err := p.PublishBranch(ctx, hackRepo, pushBranch, "main", commitMsg, commitMsg, labels...)
+		if err != nil {
+			return fmt.Errorf("could not publish pull request for branch %s: %w", pushBranch, err)
+		}
+		log.Println("Pull request", result.URL)
+	}
+
 	return nil
 }
 
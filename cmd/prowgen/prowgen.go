@@ -1,9 +1,17 @@
 package main
 
 import (
+	"os"
+
 	"github.com/openshift-knative/hack/pkg/prowgen"
+	"github.com/openshift-knative/hack/pkg/publish"
 )
 
 func main() {
+	// publish depends on prowgen, so its subcommand can't be dispatched by prowgen.Main.
+	if len(os.Args) > 1 && os.Args[1] == "publish" {
+		publish.Main(os.Args[2:])
+		return
+	}
 	prowgen.Main()
 }
@@ -11,6 +11,8 @@ import (
 	"text/template"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/openshift-knative/hack/pkg/verify"
 )
 
 const (
@@ -297,6 +299,22 @@ func (cfg *DependabotConfig) Write(repoDir string, run string) error {
 	return nil
 }
 
+// Verify generates the dependabot configuration, workflow and renovate configuration into a
+// temporary directory and returns the unified diff with the existing ones in repoDir, the diff
+// is empty when they are up-to-date.
+func (cfg *DependabotConfig) Verify(repoDir string, run string) (string, error) {
+	tmp, err := os.MkdirTemp("", "dependabotgen-verify-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := cfg.Write(tmp, run); err != nil {
+		return "", err
+	}
+	return verify.Dirs(repoDir, tmp, nil)
+}
+
 func WriteDependabotWorkflow(repoDir string, run string) error {
 	if run == "" {
 		return nil
@@ -30,6 +30,10 @@ const (
 )
 
 func Main() {
+	if len(os.Args) > 1 && os.Args[1] == "publish" {
+		PublishMain(os.Args[2:])
+		return
+	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancel()
@@ -37,7 +41,9 @@ func Main() {
 	inputConfig := flag.String("config", filepath.Join("config"), "Specify repositories config")
 	inputAction := flag.String("input", filepath.Join(".github", "workflows", "release-generate-ci-template.yaml"), "Input action (template)")
 	outputAction := flag.String("output", filepath.Join(".github", "workflows", "release-generate-ci.yaml"), "Output action")
+	workspace := prowgen.RegisterWorkspaceFlags(flag.CommandLine)
 	flag.Parse()
+	ctx = prowgen.WithWorkspace(ctx, workspace)
 
 	unsupportedBranches := make([]Unsupported, 0)
 	if err := readYaml(unsupportedConfig, &unsupportedBranches); err != nil {
@@ -178,6 +184,12 @@ func discover(ctx context.Context, path string, unsupported []Unsupported, ignor
 
 	inConfig = removeIgnoredBranches(inConfig, ignored)
 
+	// New branches are copied from the latest branch as written, so they keep extending the
+	// same profile, make sure that the resulting configuration can still be resolved.
+	if _, err := inConfig.Config.Resolve(); err != nil {
+		return fmt.Errorf("failed to resolve config %q: %w", path, err)
+	}
+
 	return writeYaml(path, inConfig)
 }
 
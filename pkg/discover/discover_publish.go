@@ -0,0 +1,48 @@
+package discover
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+	"github.com/openshift-knative/hack/pkg/publish"
+)
+
+const (
+	discoverBranch = "discover-serverless-ci"
+	discoverTitle  = "Add new midstream branches and update pipelines"
+	discoverBody   = "Discover new branches and update Konflux pipelines using openshift-knative/hack."
+	discoverCommit = "Discover new branches and update pipelines"
+)
+
+// PublishMain is the entry point of `discover publish`, it commits the changes of the
+// openshift-knative/hack clone in the workspace, the discovered branches and the updated
+// pipelines, to a branch and publishes it as a pull request.
+func PublishMain(args []string) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	fset := flag.NewFlagSet("publish", flag.ExitOnError)
+	branch := fset.String("branch", discoverBranch, "Branch the changes are committed to, pushed to the fork with the same name")
+	base := fset.String("base", "main", "Branch the pull request is merged into")
+	forkOwner := fset.String("fork-owner", publish.DefaultForkOwner, "Owner of the fork the branch is pushed to")
+	workspace := prowgen.RegisterWorkspaceFlags(fset)
+	_ = fset.Parse(args)
+	ctx = prowgen.WithWorkspace(ctx, workspace)
+
+	hackRepo := prowgen.Repository{Org: "openshift-knative", Repo: "hack"}
+	if err := prowgen.PushBranch(ctx, hackRepo, nil, *branch, discoverCommit); err != nil {
+		log.Fatalln("Failed to commit discovered branches", err)
+	}
+	p := publish.NewPublisher(publish.WithForkOwner(*forkOwner))
+	result, err := p.PublishBranch(ctx, hackRepo, *branch, *base, discoverTitle, discoverBody)
+	if err != nil {
+		log.Fatalln("Failed to publish pull request", err)
+	}
+	if result.URL != "" {
+		log.Println("Pull request", result.URL)
+	}
+}
@@ -389,7 +389,7 @@ func generateDockerfile(params Params, mainPackagesPaths sets.Set[string]) error
 		}
 
 		if rpmsLockTemplate != nil && !rpmsLockFileWritten {
-			if err = writeRPMLockFile(rpmsLockTemplate, params.RootDir); err != nil {
+			if err = writeRPMLockFile(rpmsLockTemplate, params.rpmsLockDir()); err != nil {
 				return err
 			}
 			rpmsLockFileWritten = true
@@ -596,7 +596,7 @@ func generateMustGatherDockerfile(params Params) error {
 	if _, err = saveDockerfile(d, DockerfileMustGatherTemplate, templateFile, out, ""); err != nil {
 		return err
 	}
-	if err = writeRPMLockFile(rpmsLockTemplate, params.RootDir); err != nil {
+	if err = writeRPMLockFile(rpmsLockTemplate, params.rpmsLockDir()); err != nil {
 		return err
 	}
 
@@ -29,6 +29,9 @@ type Params struct {
 	AdditionalBuildEnvVars       []string `json:"additional-build-env" desc:"Additional env vars to be added to builder in the image"`
 	TemplateName                 string   `json:"template-name" desc:"Dockerfile template name to use. Supported values are [default, func-util]"`
 	RpmsLockFileEnabled          bool     `json:"generate-rpms-lock-file" desc:"Enable the creation of the rpms.lock.yaml file"`
+
+	// rpmsLockFileDir is the directory of the rpms.lock.yaml file, RootDir when empty.
+	rpmsLockFileDir string
 }
 
 func (p *Params) ConfigureFlags() (*pflag.FlagSet, error) {
@@ -40,6 +43,13 @@ func (p *Params) ConfigureFlags() (*pflag.FlagSet, error) {
 	return fs, nil
 }
 
+func (p Params) rpmsLockDir() string {
+	if p.rpmsLockFileDir != "" {
+		return p.rpmsLockFileDir
+	}
+	return p.RootDir
+}
+
 func DefaultParams(wd string) Params {
 	return Params{
 		RootDir: wd,
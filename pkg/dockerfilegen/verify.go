@@ -0,0 +1,55 @@
+package dockerfilegen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openshift-knative/hack/pkg/verify"
+)
+
+// VerifyDockerfiles generates Dockerfiles into a temporary directory and returns the unified diff
+// with the existing files, the diff is empty when they are up-to-date.
+func VerifyDockerfiles(params Params) (string, error) {
+	if params.RootDir == "" {
+		return "", fmt.Errorf("%w: root-dir cannot be empty", ErrBadConf)
+	}
+	// GenerateDockerfiles changes the working directory.
+	rootDir, err := filepath.Abs(params.RootDir)
+	if err != nil {
+		return "", fmt.Errorf("%w: Abs: %w", ErrIO, err)
+	}
+	output := params.Output
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(rootDir, output)
+	}
+
+	tmp, err := os.MkdirTemp("", "dockerfilegen-verify-")
+	if err != nil {
+		return "", fmt.Errorf("%w: MkdirTemp: %w", ErrIO, err)
+	}
+	defer os.RemoveAll(tmp)
+
+	generated := params
+	generated.RootDir = rootDir
+	generated.Output = filepath.Join(tmp, "output")
+	generated.rpmsLockFileDir = filepath.Join(tmp, "root")
+	if err := os.MkdirAll(generated.rpmsLockFileDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("%w: MkdirAll: %w", ErrIO, err)
+	}
+	if err := GenerateDockerfiles(generated); err != nil {
+		return "", err
+	}
+
+	// Existing Dockerfiles that aren't generated are left untouched by the generator, so only
+	// generated files are compared.
+	diff, err := verify.Dirs(output, generated.Output, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrIO, err)
+	}
+	lockDiff, err := verify.Dirs(rootDir, generated.rpmsLockFileDir, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrIO, err)
+	}
+	return diff + lockDiff, nil
+}
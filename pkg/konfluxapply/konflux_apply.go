@@ -89,7 +89,7 @@ func apply(ctx context.Context, cfg ApplyConfig, config *prowgen.Config) error {
 				continue
 			}
 
-			if _, err := os.Stat(filepath.Join(r.RepositoryDirectory(), cfg.KonfluxDir)); err != nil {
+			if _, err := os.Stat(filepath.Join(r.LocalDirectory(ctx), cfg.KonfluxDir)); err != nil {
 				if errors.Is(err, os.ErrNotExist) {
 					continue // Skip repositories without Konflux components directory
 				}
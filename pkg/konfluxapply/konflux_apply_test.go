@@ -0,0 +1,62 @@
+package konfluxapply
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+)
+
+func TestApply(t *testing.T) {
+	ctx := prowgen.WithWorkspace(context.Background(), &prowgen.Workspace{Root: t.TempDir()})
+
+	serving := prowgen.Repository{Org: "testorg", Repo: "serving"}
+	if err := os.MkdirAll(filepath.Join(serving.LocalDirectory(ctx), ".konflux"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	configs := t.TempDir()
+	config := `repositories:
+- org: testorg
+  repo: serving
+config:
+  branches:
+    release-v1.15:
+      konflux:
+        enabled: true
+    release-v1.16:
+      konflux:
+        enabled: true
+    release-v1.14: {}
+`
+	if err := os.WriteFile(filepath.Join(configs, "serving.yaml"), []byte(config), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := prowgen.NewFakeExecutor().
+		On("", errors.New("pathspec 'release-v1.16' did not match any file(s) known to git"), "git", "checkout", "release-v1.16")
+	prowgen.SetExecutor(fake)
+	t.Cleanup(func() { prowgen.SetExecutor(nil) })
+
+	if err := Apply(ctx, ApplyConfig{InputConfigPath: configs, KonfluxDir: ".konflux"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var applied []prowgen.Command
+	for _, c := range fake.Invocations() {
+		if c.Name == "kubectl" {
+			applied = append(applied, c)
+		}
+	}
+	want := []prowgen.Command{
+		{Name: "kubectl", Args: []string{"apply", "-Rf", ".konflux"}, Dir: serving.LocalDirectory(ctx)},
+	}
+	if diff := cmp.Diff(want, applied); diff != "" {
+		t.Error("kubectl invocations (-want, +got):", diff)
+	}
+}
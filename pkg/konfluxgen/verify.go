@@ -0,0 +1,63 @@
+package konfluxgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openshift-knative/hack/pkg/verify"
+)
+
+// Verify generates Konflux configurations into a temporary directory and returns the unified
+// diff with the existing ones, the diff is empty when they are up-to-date.
+//
+// The existing directories are copied first since the generation preserves newer task images
+// of existing pipelines, existing files that aren't generated anymore are reported as removed.
+func Verify(cfg Config) (string, error) {
+	tmp, err := os.MkdirTemp("", "konfluxgen-verify-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	type dir struct {
+		existing  string
+		generated string
+	}
+	resources := dir{existing: cfg.ResourcesOutputPath, generated: filepath.Join(tmp, "resources")}
+	pipelines := dir{existing: cfg.PipelinesOutputPath, generated: filepath.Join(tmp, "pipelines")}
+	dirs := []dir{resources, pipelines}
+
+	generated := cfg
+	generated.ResourcesOutputPath = resources.generated
+	generated.PipelinesOutputPath = pipelines.generated
+	if cfg.GlobalResourcesOutputPath == cfg.ResourcesOutputPath {
+		generated.GlobalResourcesOutputPath = resources.generated
+	} else {
+		// Only applications are written to the global resources directory.
+		generated.GlobalResourcesOutputPath = filepath.Join(tmp, "global")
+		dirs = append(dirs, dir{
+			existing:  filepath.Join(cfg.GlobalResourcesOutputPath, ApplicationsDirectoryName),
+			generated: filepath.Join(generated.GlobalResourcesOutputPath, ApplicationsDirectoryName),
+		})
+	}
+
+	for _, d := range dirs {
+		if err := verify.CopyDir(d.existing, d.generated); err != nil {
+			return "", fmt.Errorf("failed to copy %q: %w", d.existing, err)
+		}
+	}
+	if err := Generate(generated); err != nil {
+		return "", err
+	}
+
+	var diff string
+	for _, d := range dirs {
+		dirDiff, err := verify.Dirs(d.existing, d.generated, func(string) bool { return true })
+		if err != nil {
+			return "", err
+		}
+		diff += dirDiff
+	}
+	return diff, nil
+}
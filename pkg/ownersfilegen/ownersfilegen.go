@@ -11,6 +11,8 @@ import (
 	"slices"
 	"strings"
 	"text/template"
+
+	"github.com/openshift-knative/hack/pkg/verify"
 )
 
 const (
@@ -54,3 +56,18 @@ func WriteOwnersFile(repoDir string, reviewers, approvers []string) error {
 
 	return nil
 }
+
+// VerifyOwnersFile generates the OWNERS file into a temporary directory and returns the unified
+// diff with the existing one in repoDir, the diff is empty when it is up-to-date.
+func VerifyOwnersFile(repoDir string, reviewers, approvers []string) (string, error) {
+	tmp, err := os.MkdirTemp("", "ownersfilegen-verify-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := WriteOwnersFile(tmp, reviewers, approvers); err != nil {
+		return "", err
+	}
+	return verify.Dirs(repoDir, tmp, nil)
+}
@@ -12,6 +12,7 @@ import (
 // project.yaml that contains such metadata.
 type Metadata struct {
 	Project      Project       `json:"project" yaml:"project"`
+	Olm          *Olm          `json:"olm,omitempty" yaml:"olm,omitempty"`
 	Requirements *Requirements `json:"requirements,omitempty" yaml:"requirements,omitempty"`
 }
 
@@ -21,6 +22,12 @@ type Project struct {
 	Version     string `json:"version" yaml:"version"`
 }
 
+// Olm is the OLM metadata of the operator bundle.
+type Olm struct {
+	// Replaces is the released version the bundle replaces.
+	Replaces string `json:"replaces,omitempty" yaml:"replaces,omitempty"`
+}
+
 type Requirements struct {
 	OcpVersion OcpVersion `json:"ocpVersion" yaml:"ocpVersion"`
 }
@@ -0,0 +1,227 @@
+package prowcopy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+	"github.com/openshift-knative/hack/pkg/util"
+)
+
+// existingJobConfigs returns the existing configurations of the target branch by path, without
+// the configurations ignored by the repository IgnoreConfigs.
+func existingJobConfigs(ctx context.Context, openShiftRelease prowgen.Repository, r prowgen.Repository, c Config) (map[string]*prowgen.ReleaseBuildConfiguration, error) {
+	files, err := discoverJobConfigs(ctx, openShiftRelease, c, c.Branch)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*prowgen.ReleaseBuildConfiguration, len(files))
+	for _, path := range files {
+		ignored, err := isIgnored(r, path)
+		if err != nil {
+			return nil, err
+		}
+		if ignored {
+			continue
+		}
+		cfg, err := readJobConfig(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing job config %s: %w", path, err)
+		}
+		existing[path] = cfg
+	}
+	return existing, nil
+}
+
+// isIgnored returns true when the repository IgnoreConfigs match the configuration path, ignored
+// configurations are maintained by hand and never overwritten.
+func isIgnored(r prowgen.Repository, path string) (bool, error) {
+	ignoreConfigs, err := util.ToRegexp(r.IgnoreConfigs.Matches)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse ignore configs regex: %w", err)
+	}
+	for _, ignore := range ignoreConfigs {
+		if ignore.MatchString(path) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reconcile compares the copied configurations with the existing configurations of the target
+// branch and records the conflicts in the reports, with merge the tests only in the existing
+// configurations are added to the copied configurations.
+//
+// It returns the reports of the existing configurations that aren't copied and the number of
+// conflicts.
+func reconcile(jobs []prowgen.ReleaseBuildConfiguration, reports []FileReport, existing map[string]*prowgen.ReleaseBuildConfiguration, merge bool) ([]FileReport, int) {
+	n := 0
+	copied := make(map[string]bool, len(jobs))
+	for i := range jobs {
+		copied[jobs[i].Path] = true
+		e, ok := existing[jobs[i].Path]
+		if !ok {
+			continue
+		}
+		report := reportFor(reports, jobs[i].Path)
+		conflicts := jobConfigConflicts(&jobs[i], e)
+		n += len(conflicts)
+		if report != nil {
+			report.Conflicts = append(report.Conflicts, conflicts...)
+		}
+		if merge {
+			kept := mergeUnmanagedTests(&jobs[i], e)
+			if report != nil {
+				report.Changes = append(report.Changes, kept...)
+			}
+		}
+	}
+
+	var extra []FileReport
+	for _, path := range sortedKeys(existing) {
+		if copied[path] {
+			continue
+		}
+		report := FileReport{To: path, Conflicts: []string{"existing configuration isn't copied"}}
+		if merge {
+			report.Changes = []string{"kept existing configuration"}
+		}
+		extra = append(extra, report)
+		n++
+	}
+	return extra, n
+}
+
+func reportFor(reports []FileReport, path string) *FileReport {
+	for i := range reports {
+		if reports[i].To == path {
+			return &reports[i]
+		}
+	}
+	return nil
+}
+
+// jobConfigConflicts returns the differences between the copied configuration and the existing
+// one that the copy would lose or silently change.
+func jobConfigConflicts(copied, existing *prowgen.ReleaseBuildConfiguration) []string {
+	var conflicts []string
+
+	copiedTests := make(map[string]cioperatorapi.TestStepConfiguration, len(copied.Tests))
+	for _, t := range copied.Tests {
+		copiedTests[t.As] = t
+	}
+	for _, t := range existing.Tests {
+		ct, ok := copiedTests[t.As]
+		if !ok {
+			conflicts = append(conflicts, fmt.Sprintf("test %s is only in the existing configuration", t.As))
+			continue
+		}
+		if !jsonEqual(ct, t) {
+			conflicts = append(conflicts, fmt.Sprintf("test %s differs from the existing configuration", t.As))
+		}
+	}
+
+	if !jsonEqual(copied.PromotionConfiguration, existing.PromotionConfiguration) {
+		conflicts = append(conflicts, fmt.Sprintf("promotion: existing %s, copied %s",
+			promotionString(existing.PromotionConfiguration), promotionString(copied.PromotionConfiguration)))
+	}
+
+	names := make(map[string]bool)
+	for name := range existing.BaseImages {
+		names[name] = true
+	}
+	for name := range copied.BaseImages {
+		names[name] = true
+	}
+	for _, name := range sortedKeys(names) {
+		e, eok := existing.BaseImages[name]
+		c, cok := copied.BaseImages[name]
+		if eok == cok && jsonEqual(e, c) {
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("base image %s: existing %s, copied %s",
+			name, imageString(e, eok), imageString(c, cok)))
+	}
+	return conflicts
+}
+
+// mergeUnmanagedTests adds the tests only in the existing configuration, with their reporter
+// config, to the copied configuration.
+func mergeUnmanagedTests(copied, existing *prowgen.ReleaseBuildConfiguration) []string {
+	var changes []string
+	for _, t := range existing.Tests {
+		managed := false
+		for _, ct := range copied.Tests {
+			if ct.As == t.As {
+				managed = true
+				break
+			}
+		}
+		if managed {
+			continue
+		}
+		copied.Tests = append(copied.Tests, t)
+		if rc, ok := existing.ReporterConfigs[t.As]; ok {
+			if copied.ReporterConfigs == nil {
+				copied.ReporterConfigs = make(map[string]prowgen.ReporterConfig)
+			}
+			copied.ReporterConfigs[t.As] = rc
+		}
+		changes = append(changes, fmt.Sprintf("kept existing test %s", t.As))
+	}
+	return changes
+}
+
+func promotionString(p *cioperatorapi.PromotionConfiguration) string {
+	if p == nil || len(p.Targets) == 0 {
+		return "none"
+	}
+	targets := make([]string, 0, len(p.Targets))
+	for _, t := range p.Targets {
+		target := t.Namespace + "/" + t.Name
+		if t.Tag != "" {
+			target = t.Namespace + " tag " + t.Tag
+		}
+		if t.Disabled {
+			target += " (disabled)"
+		}
+		targets = append(targets, target)
+	}
+	return strings.Join(targets, ", ")
+}
+
+func imageString(image cioperatorapi.ImageStreamTagReference, ok bool) string {
+	if !ok {
+		return "none"
+	}
+	return image.Namespace + "/" + image.Name + ":" + image.Tag
+}
+
+func jsonEqual(a, b interface{}) bool {
+	aj, aErr := json.Marshal(a)
+	bj, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aj) == string(bj)
+}
+
+// relativeReports makes the paths of the reports relative to the ci-operator config directory.
+func relativeReports(dir string, reports []FileReport) {
+	for i := range reports {
+		reports[i].From = relativePath(dir, reports[i].From)
+		reports[i].To = relativePath(dir, reports[i].To)
+	}
+}
+
+func relativePath(dir, path string) string {
+	if path == "" {
+		return ""
+	}
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,171 @@
+package prowcopy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+)
+
+func TestCopyRepositoryConflicts(t *testing.T) {
+	ctx := context.Background()
+	openShiftRelease := prowgen.Repository{Org: "openshift", Repo: "release"}
+	r := prowgen.Repository{
+		Org:           "openshift-knative",
+		Repo:          "eventing",
+		IgnoreConfigs: prowgen.IgnoreConfigs{Matches: []string{".*__custom.yaml$"}},
+	}
+	dir := filepath.Join("openshift", "release", "ci-operator", "config", "openshift-knative", "eventing")
+	path := func(branch, variant string) string {
+		return filepath.Join(dir, "openshift-knative-eventing-"+branch+"__"+variant+".yaml")
+	}
+
+	setup := func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		write := func(branch, variant string, promotion string, tests ...string) {
+			cfg := prowgen.ReleaseBuildConfiguration{
+				ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+					Metadata: cioperatorapi.Metadata{Org: r.Org, Repo: r.Repo, Branch: branch, Variant: variant},
+					InputConfiguration: cioperatorapi.InputConfiguration{
+						BaseImages: map[string]cioperatorapi.ImageStreamTagReference{
+							"base": {Namespace: "ocp", Name: "builder", Tag: "rhel-8"},
+						},
+					},
+				},
+				Path:            path(branch, variant),
+				ReporterConfigs: map[string]prowgen.ReporterConfig{"test-manual": {Channel: "#manual"}},
+			}
+			if promotion != "" {
+				cfg.PromotionConfiguration = &cioperatorapi.PromotionConfiguration{
+					Targets: []cioperatorapi.PromotionTarget{{Namespace: "openshift", Name: promotion}},
+				}
+			}
+			for _, test := range tests {
+				cfg.Tests = append(cfg.Tests, cioperatorapi.TestStepConfiguration{As: test})
+			}
+			if err := prowgen.SaveReleaseBuildConfiguration(pointer.String(""), cfg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		write("release-next", "416", "knative-nightly", "test-e2e")
+		write("release-next", "custom", "", "test-e2e")
+		write("release-v1.17", "416", "knative-v1.16", "test-e2e", "test-manual")
+		write("release-v1.17", "415", "", "test-e2e")
+		write("release-v1.17", "custom", "", "test-custom")
+	}
+
+	wantFiles := func(merge bool) []FileReport {
+		files := []FileReport{
+			{
+				From: "openshift-knative/eventing/openshift-knative-eventing-release-next__416.yaml",
+				To:   "openshift-knative/eventing/openshift-knative-eventing-release-v1.17__416.yaml",
+				Conflicts: []string{
+					"test test-manual is only in the existing configuration",
+					"promotion: existing openshift/knative-v1.16, copied openshift/knative-nightly",
+				},
+			},
+			{
+				From:    "openshift-knative/eventing/openshift-knative-eventing-release-next__custom.yaml",
+				Changes: []string{"not copied, openshift-knative-eventing-release-v1.17__custom.yaml is ignored by ignoreConfigs"},
+			},
+			{
+				To:        "openshift-knative/eventing/openshift-knative-eventing-release-v1.17__415.yaml",
+				Conflicts: []string{"existing configuration isn't copied"},
+			},
+		}
+		if merge {
+			files[0].Changes = []string{"kept existing test test-manual"}
+			files[2].Changes = []string{"kept existing configuration"}
+		}
+		return files
+	}
+
+	t.Run("conflicts", func(t *testing.T) {
+		setup(t)
+		c := Config{FromBranch: "release-next", Branch: "release-v1.17"}
+		report := copyRepository(ctx, openShiftRelease, r, c, nil)
+		want := RepositoryReport{
+			Repository: "openshift-knative/eventing",
+			Files:      wantFiles(false),
+			Error:      "3 conflicts with the existing configurations of branch release-v1.17, use --merge or --overwrite",
+		}
+		if diff := cmp.Diff(want, report); diff != "" {
+			t.Error("report (-want, +got):", diff)
+		}
+		cfg, err := readJobConfig(path("release-v1.17", "416"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff("knative-v1.16", cfg.PromotionConfiguration.Targets[0].Name); diff != "" {
+			t.Error("existing configuration changed (-want, +got):", diff)
+		}
+	})
+
+	t.Run("preview", func(t *testing.T) {
+		setup(t)
+		c := Config{FromBranch: "release-next", Branch: "release-v1.17", Merge: true, Preview: true}
+		report := copyRepository(ctx, openShiftRelease, r, c, nil)
+		if diff := cmp.Diff(wantFiles(true), report.Files); diff != "" {
+			t.Error("files (-want, +got):", diff)
+		}
+		cfg, err := readJobConfig(path("release-v1.17", "416"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff("knative-v1.16", cfg.PromotionConfiguration.Targets[0].Name); diff != "" {
+			t.Error("existing configuration changed (-want, +got):", diff)
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		setup(t)
+		c := Config{FromBranch: "release-next", Branch: "release-v1.17", Merge: true}
+		report := copyRepository(ctx, openShiftRelease, r, c, nil)
+		if report.Error != "" {
+			t.Fatal(report.Error)
+		}
+		if diff := cmp.Diff(wantFiles(true), report.Files); diff != "" {
+			t.Error("files (-want, +got):", diff)
+		}
+
+		cfg, err := readJobConfig(path("release-v1.17", "416"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tests []string
+		for _, test := range cfg.Tests {
+			tests = append(tests, test.As)
+		}
+		if diff := cmp.Diff([]string{"test-e2e", "test-manual"}, tests); diff != "" {
+			t.Error("tests (-want, +got):", diff)
+		}
+		if diff := cmp.Diff(map[string]prowgen.ReporterConfig{"test-manual": {Channel: "#manual"}}, cfg.ReporterConfigs); diff != "" {
+			t.Error("reporter configs (-want, +got):", diff)
+		}
+		if diff := cmp.Diff("knative-nightly", cfg.PromotionConfiguration.Targets[0].Name); diff != "" {
+			t.Error("promotion (-want, +got):", diff)
+		}
+
+		for _, variant := range []string{"415", "custom"} {
+			if _, err := os.Stat(path("release-v1.17", variant)); err != nil {
+				t.Errorf("existing configuration %s: %v", variant, err)
+			}
+		}
+		custom, err := readJobConfig(path("release-v1.17", "custom"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff("test-custom", custom.Tests[0].As); diff != "" {
+			t.Error("ignored configuration changed (-want, +got):", diff)
+		}
+	})
+}
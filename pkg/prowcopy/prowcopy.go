@@ -27,6 +27,46 @@ type Config struct {
 	RemovePeriodic bool
 	Remote         string
 	Config         string
+	// AllRepositories copies the configurations of every repository in Config instead of Org
+	// and Repo.
+	AllRepositories bool
+	// Rules is the rules file, see Rules.
+	Rules string
+	// Report is the path of the JSON report, see Report.
+	Report string
+	// Preview computes the copied configurations and reports the changes and the conflicts with
+	// the existing configurations of Branch without writing them.
+	Preview bool
+	// Merge keeps the tests and the configurations of Branch that the copy doesn't produce,
+	// instead of failing on conflicts.
+	Merge bool
+	// Overwrite deletes the existing configurations of Branch, except the ones ignored by
+	// IgnoreConfigs, without checking for conflicts.
+	Overwrite bool
+}
+
+// Report is the outcome of the copy of each repository.
+type Report struct {
+	Repositories []RepositoryReport `json:"repositories"`
+}
+
+type RepositoryReport struct {
+	// Repository is <org>/<repo>.
+	Repository string       `json:"repository"`
+	Files      []FileReport `json:"files,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+type FileReport struct {
+	// From is the source configuration, relative to the ci-operator config directory, empty for
+	// existing configurations of the target branch that aren't copied.
+	From string `json:"from,omitempty"`
+	// To is the copied configuration, empty when the configuration is dropped.
+	To      string   `json:"to,omitempty"`
+	Changes []string `json:"changes,omitempty"`
+	// Conflicts are the differences with the existing configuration of the target branch that
+	// the copy would lose or change, for example, manually added tests.
+	Conflicts []string `json:"conflicts,omitempty"`
 }
 
 // Main is the main function for prowcopy.
@@ -46,14 +86,45 @@ func Main() error {
 	flag.StringVar(&c.Tag, "tag", "", "Target promotion name or tag")
 	flag.BoolVar(&c.RemovePeriodic, "remove-periodic-tests", true, "Remove periodic tests")
 	flag.StringVar(&c.Remote, "remote", "", "Git remote URL")
-	flag.StringVar(&c.Config, "config", filepath.Join("config", "repositories.yaml"), "Specify repositories config")
+	flag.StringVar(&c.Config, "config", filepath.Join("config", "repositories.yaml"), "Specify repositories config file or directory")
+	flag.BoolVar(&c.AllRepositories, "all-repositories", false, "Copy the configurations of every repository in --config instead of --org and --repo")
+	flag.StringVar(&c.Rules, "rules", "", "Rules file applied to the copied configurations")
+	flag.StringVar(&c.Report, "report", "", "Write the JSON report of the copy to the given file")
+	flag.BoolVar(&c.Preview, "preview", false, "Report the changes and the conflicts with the existing configurations of --branch without writing them")
+	flag.BoolVar(&c.Merge, "merge", false, "Keep the tests and the configurations of --branch the copy doesn't produce instead of failing on conflicts")
+	flag.BoolVar(&c.Overwrite, "overwrite", false, "Overwrite the existing configurations of --branch without checking for conflicts")
 	flag.Parse()
 
-	prowgenConfig, err := prowgen.LoadConfig(c.Config)
+	if c.Merge && c.Overwrite {
+		return fmt.Errorf("--merge and --overwrite are mutually exclusive")
+	}
+
+	prowgenConfigs, err := prowgen.LoadConfigs(c.Config)
 	if err != nil {
 		log.Fatalln("Failed to load config", err)
 	}
 
+	var rules *Rules
+	if c.Rules != "" {
+		rules, err = LoadRules(c.Rules)
+		if err != nil {
+			return err
+		}
+	}
+
+	var repositories []prowgen.Repository
+	if c.AllRepositories {
+		for _, prowgenConfig := range prowgenConfigs {
+			repositories = append(repositories, prowgenConfig.Repositories...)
+		}
+	} else {
+		r, err := configuredRepository(prowgenConfigs, c.Org, c.Repo)
+		if err != nil {
+			return fmt.Errorf("%w in %s", err, c.Config)
+		}
+		repositories = append(repositories, r)
+	}
+
 	// Clone openshift/release and clean up existing jobs for the configured branches
 	openShiftRelease := prowgen.Repository{
 		Org:  "openshift",
@@ -63,40 +134,134 @@ func Main() error {
 		return err
 	}
 
-	if c.FromBranch != c.Branch {
-		outConfig := filepath.Join(openShiftRelease.Org, openShiftRelease.Repo, "ci-operator", "config")
-		if err := prowgen.DeleteExistingReleaseBuildConfigurationForBranch(&outConfig, prowgen.Repository{Org: c.Org, Repo: c.Repo}, c.Branch); err != nil {
-			return err
+	report := Report{}
+	failed := false
+	for _, r := range repositories {
+		rr := copyRepository(ctx, openShiftRelease, r, c, rules)
+		if rr.Error != "" {
+			log.Println("Failed to copy", rr.Repository, rr.Error)
+			failed = true
 		}
+		report.Repositories = append(report.Repositories, rr)
 	}
 
-	files, err := discoverJobConfigs(openShiftRelease, c)
-	if err != nil {
+	if err := writeReport(c.Report, report); err != nil {
 		return err
 	}
+
+	if c.Preview {
+		if failed {
+			return fmt.Errorf("failed to preview the configurations of some repositories, see the report")
+		}
+		return nil
+	}
+
+	if err := prowgen.RunOpenShiftReleaseGenerator(ctx, openShiftRelease); err != nil {
+		log.Fatalln("Failed to run openshift/release generator:", err)
+	}
+
+	for _, prowgenConfig := range prowgenConfigs {
+		if err := mirrorRepositories(ctx, prowgenConfig); err != nil {
+			log.Fatalln("Failed to mirror repositories", err)
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("failed to copy the configurations of some repositories, see the report")
+	}
+	return nil
+}
+
+// copyRepository copies the configurations of the repository and returns the report, errors
+// are reported.
+//
+// Unless c.Overwrite is set, the copied configurations are compared with the existing
+// configurations of the target branch first, conflicts fail the copy unless c.Merge is set.
+// Configurations ignored by the repository IgnoreConfigs are never overwritten.
+func copyRepository(ctx context.Context, openShiftRelease prowgen.Repository, r prowgen.Repository, c Config, rules *Rules) (report RepositoryReport) {
+	report.Repository = r.RepositoryDirectory()
+	c.Org, c.Repo = r.Org, r.Repo
+
+	outConfig := filepath.Join(openShiftRelease.LocalDirectory(ctx), "ci-operator", "config")
+	defer func() { relativeReports(outConfig, report.Files) }()
+
+	files, err := discoverJobConfigs(ctx, openShiftRelease, c, c.FromBranch)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
 	log.Println("Matching job configs for branch", c.FromBranch, "files", files)
 
-	jobs, err := getJobConfigs(files, c)
+	jobs, fileReports, err := copyJobConfigs(files, c, rules.forRepository(r))
+	report.Files = fileReports
 	if err != nil {
-		return err
+		report.Error = err.Error()
+		return report
 	}
 	log.Println("Got", len(jobs), "jobs config")
 
+	kept := jobs[:0]
 	for _, j := range jobs {
-		if err := prowgen.SaveReleaseBuildConfiguration(pointer.String(""), j); err != nil {
-			return err
+		ignored, err := isIgnored(r, j.Path)
+		if err != nil {
+			report.Error = err.Error()
+			return report
 		}
+		if ignored {
+			if fr := reportFor(report.Files, j.Path); fr != nil {
+				fr.To = ""
+				fr.Changes = append(fr.Changes, fmt.Sprintf("not copied, %s is ignored by ignoreConfigs", filepath.Base(j.Path)))
+			}
+			continue
+		}
+		kept = append(kept, j)
 	}
+	jobs = kept
 
-	if err := prowgen.RunOpenShiftReleaseGenerator(ctx, openShiftRelease); err != nil {
-		log.Fatalln("Failed to run openshift/release generator:", err)
+	if c.FromBranch != c.Branch && !c.Overwrite {
+		existing, err := existingJobConfigs(ctx, openShiftRelease, r, c)
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		extra, conflicts := reconcile(jobs, report.Files, existing, c.Merge)
+		report.Files = append(report.Files, extra...)
+		if conflicts > 0 && !c.Merge {
+			report.Error = fmt.Sprintf("%d conflicts with the existing configurations of branch %s, use --merge or --overwrite", conflicts, c.Branch)
+			return report
+		}
 	}
 
-	if err := mirrorRepositories(ctx, prowgenConfig); err != nil {
-		log.Fatalln("Failed to mirror repositories", err)
+	if c.Preview {
+		return report
 	}
 
-	return nil
+	if c.FromBranch != c.Branch && c.Overwrite {
+		if err := prowgen.DeleteExistingReleaseBuildConfigurationForBranch(&outConfig, r, c.Branch); err != nil {
+			report.Error = err.Error()
+			return report
+		}
+	}
+
+	for _, j := range jobs {
+		if err := prowgen.SaveReleaseBuildConfiguration(pointer.String(""), j); err != nil {
+			report.Error = err.Error()
+			return report
+		}
+	}
+	return report
+}
+
+func writeReport(path string, report Report) error {
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		log.Println("Report\n", string(out))
+		return nil
+	}
+	return os.WriteFile(path, out, os.ModePerm)
 }
 
 func mirrorRepositories(ctx context.Context, inConfig *prowgen.Config) error {
@@ -116,37 +281,56 @@ func mirrorRepositories(ctx context.Context, inConfig *prowgen.Config) error {
 	return nil
 }
 
-func getJobConfigs(files []string, c Config) ([]prowgen.ReleaseBuildConfiguration, error) {
+// copyJobConfigs returns the copied configurations with the rules applied and the report of
+// each file.
+func copyJobConfigs(files []string, c Config, rules []RepositoryRules) ([]prowgen.ReleaseBuildConfiguration, []FileReport, error) {
 	jobs := make([]prowgen.ReleaseBuildConfiguration, 0, len(files))
+	reports := make([]FileReport, 0, len(files))
 	for _, match := range files {
 		jc, err := getJobConfig(match, c)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get job config for %s: %w", match, err)
+			return nil, reports, fmt.Errorf("failed to get job config for %s: %w", match, err)
 		}
-		jobs = append(jobs, *jc)
+		report := FileReport{From: match}
+		if c.RemovePeriodic {
+			for _, t := range jc.Tests {
+				if t.Cron != nil && *t.Cron != "" {
+					report.Changes = append(report.Changes, fmt.Sprintf("dropped periodic test %s", t.As))
+				}
+			}
+		}
+		j := removePeriodicTests(*jc, c)
+
+		keep := true
+		for _, rr := range rules {
+			var changes []string
+			keep, changes, err = rr.apply(&j)
+			report.Changes = append(report.Changes, changes...)
+			if err != nil {
+				return nil, append(reports, report), fmt.Errorf("failed to apply rules to %s: %w", match, err)
+			}
+			if !keep {
+				break
+			}
+		}
+		if keep {
+			report.To = j.Path
+			jobs = append(jobs, j)
+		}
+		reports = append(reports, report)
 	}
 
-	return transform(jobs, c), nil
+	return jobs, reports, nil
 }
 
-func discoverJobConfigs(openShiftRelease prowgen.Repository, c Config) ([]string, error) {
-	ciConfigDir := filepath.Join(openShiftRelease.RepositoryDirectory(), "ci-operator", "config", c.Org, c.Repo)
+func discoverJobConfigs(ctx context.Context, openShiftRelease prowgen.Repository, c Config, branch string) ([]string, error) {
+	ciConfigDir := filepath.Join(openShiftRelease.LocalDirectory(ctx), "ci-operator", "config", c.Org, c.Repo)
 
-	glob := filepath.Join(ciConfigDir, fmt.Sprintf("%s-%s-%s__*.yaml", c.Org, c.Repo, c.FromBranch))
+	glob := filepath.Join(ciConfigDir, fmt.Sprintf("%s-%s-%s__*.yaml", c.Org, c.Repo, branch))
 	log.Println(glob)
 	return filepath.Glob(glob)
 }
 
-func transform(jobs []prowgen.ReleaseBuildConfiguration, c Config) []prowgen.ReleaseBuildConfiguration {
-	r := make([]prowgen.ReleaseBuildConfiguration, 0, len(jobs))
-	for _, j := range jobs {
-		j = removePeriodicTests(j, c)
-		r = append(r, j)
-	}
-
-	return r
-}
-
 func removePeriodicTests(job prowgen.ReleaseBuildConfiguration, c Config) prowgen.ReleaseBuildConfiguration {
 	if !c.RemovePeriodic {
 		return job
@@ -164,6 +348,7 @@ func removePeriodicTests(job prowgen.ReleaseBuildConfiguration, c Config) prowge
 		ReleaseBuildConfiguration: *job.DeepCopy(),
 		Path:                      job.Path,
 		Branch:                    job.Branch,
+		ReporterConfigs:           job.ReporterConfigs,
 	}
 	r.Tests = tests
 
@@ -171,21 +356,10 @@ func removePeriodicTests(job prowgen.ReleaseBuildConfiguration, c Config) prowge
 }
 
 func getJobConfig(match string, c Config) (*prowgen.ReleaseBuildConfiguration, error) {
-	// Going directly from YAML raw input produces unexpected configs (due to missing YAML tags),
-	// so we convert YAML to JSON and unmarshal the struct from the JSON object.
-	y, err := os.ReadFile(match)
+	jobConfig, err := readJobConfig(match)
 	if err != nil {
 		return nil, err
 	}
-	j, err := yaml.YAMLToJSON(y)
-	if err != nil {
-		return nil, err
-	}
-
-	jobConfig := &prowgen.ReleaseBuildConfiguration{}
-	if err := json.Unmarshal(j, jobConfig); err != nil {
-		return nil, err
-	}
 
 	initialConfig, _ := json.MarshalIndent(jobConfig, "", "  ")
 	log.Println("Initial configuration\n", string(initialConfig))
@@ -207,3 +381,56 @@ func getJobConfig(match string, c Config) (*prowgen.ReleaseBuildConfiguration, e
 
 	return jobConfig, nil
 }
+
+// readJobConfig reads the ci-operator configuration at path, including the reporter config of
+// the tests.
+func readJobConfig(path string) (*prowgen.ReleaseBuildConfiguration, error) {
+	// Going directly from YAML raw input produces unexpected configs (due to missing YAML tags),
+	// so we convert YAML to JSON and unmarshal the struct from the JSON object.
+	y, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return nil, err
+	}
+
+	jobConfig := &prowgen.ReleaseBuildConfiguration{}
+	if err := json.Unmarshal(j, &jobConfig.ReleaseBuildConfiguration); err != nil {
+		return nil, err
+	}
+
+	reported := struct {
+		Tests []struct {
+			As             string                  `json:"as"`
+			ReporterConfig *prowgen.ReporterConfig `json:"reporter_config,omitempty"`
+		} `json:"tests,omitempty"`
+	}{}
+	if err := json.Unmarshal(j, &reported); err != nil {
+		return nil, err
+	}
+	for _, t := range reported.Tests {
+		if t.ReporterConfig == nil {
+			continue
+		}
+		if jobConfig.ReporterConfigs == nil {
+			jobConfig.ReporterConfigs = make(map[string]prowgen.ReporterConfig)
+		}
+		jobConfig.ReporterConfigs[t.As] = *t.ReporterConfig
+	}
+	return jobConfig, nil
+}
+
+// configuredRepository returns the configuration of the repository, its IgnoreConfigs are
+// required to preserve the configurations maintained by hand.
+func configuredRepository(configs []*prowgen.Config, org string, repo string) (prowgen.Repository, error) {
+	for _, config := range configs {
+		for _, r := range config.Repositories {
+			if r.Org == org && r.Repo == repo {
+				return r, nil
+			}
+		}
+	}
+	return prowgen.Repository{}, fmt.Errorf("repository %s/%s isn't configured", org, repo)
+}
@@ -0,0 +1,33 @@
+package prowcopy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+)
+
+func TestConfiguredRepository(t *testing.T) {
+	eventing := prowgen.Repository{
+		Org:           "openshift-knative",
+		Repo:          "eventing",
+		IgnoreConfigs: prowgen.IgnoreConfigs{Matches: []string{".*__custom.yaml$"}},
+	}
+	configs := []*prowgen.Config{
+		{Repositories: []prowgen.Repository{{Org: "openshift-knative", Repo: "serving"}}},
+		{Repositories: []prowgen.Repository{eventing}},
+	}
+
+	got, err := configuredRepository(configs, "openshift-knative", "eventing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(eventing, got); diff != "" {
+		t.Error("repository (-want, +got):", diff)
+	}
+
+	if _, err := configuredRepository(configs, "openshift-knative", "client"); err == nil {
+		t.Error("expected error for a repository that isn't configured")
+	}
+}
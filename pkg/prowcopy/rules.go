@@ -0,0 +1,286 @@
+package prowcopy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+)
+
+// Rules are the transformations applied to the copied configurations.
+//
+// The common rules are applied to every repository, followed by the repository rules, if any.
+type Rules struct {
+	RepositoryRules `json:",inline"`
+
+	// Repositories are the rules of each repository, by <org>/<repo>.
+	Repositories map[string]RepositoryRules `json:"repositories,omitempty"`
+}
+
+// RepositoryRules are the transformations applied to the copied configurations of a repository,
+// in the order of the fields.
+type RepositoryRules struct {
+	// DropVariants removes the configurations whose variant matches any of the regular
+	// expressions, for example, ^414.
+	DropVariants []string `json:"dropVariants,omitempty"`
+	// OpenShiftVersions replaces OpenShift versions, for example, "4.16": "4.18", in releases,
+	// cluster claims and variants (for example, 416 and 416-arm64 become 418 and 418-arm64).
+	OpenShiftVersions map[string]string `json:"openShiftVersions,omitempty"`
+	// Variants renames variants, for example, 418-fips: 418-fips-140.
+	Variants map[string]string `json:"variants,omitempty"`
+	// DropTests removes the tests whose name matches any of the regular expressions.
+	DropTests []string `json:"dropTests,omitempty"`
+	// Releases replaces the releases with the given names.
+	Releases map[string]cioperatorapi.UnresolvedRelease `json:"releases,omitempty"`
+	// Promotion adds, rewrites or removes the promotion.
+	Promotion *PromotionRule `json:"promotion,omitempty"`
+	// Cron replaces the cron schedule of periodic tests.
+	Cron string `json:"cron,omitempty"`
+}
+
+// PromotionRule adds, rewrites or removes the promotion of the configurations whose variant
+// matches Variants.
+type PromotionRule struct {
+	// Variants are regular expressions matching the variants the rule applies to, every variant
+	// by default.
+	Variants []string `json:"variants,omitempty"`
+	// Remove removes the promotion.
+	Remove bool `json:"remove,omitempty"`
+	// Namespace, Name and Tag replace the corresponding non-empty fields of promotion targets,
+	// configurations without promotion get a target when Name or Tag is set.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+}
+
+// LoadRules loads the rules file at path.
+func LoadRules(path string) (*Rules, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules := &Rules{}
+	if err := yaml.UnmarshalStrict(content, rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rules %q: %w", path, err)
+	}
+	return rules, nil
+}
+
+// forRepository returns the rules applied to the repository, in order.
+func (r *Rules) forRepository(repository prowgen.Repository) []RepositoryRules {
+	if r == nil {
+		return nil
+	}
+	rules := []RepositoryRules{r.RepositoryRules}
+	if rr, ok := r.Repositories[repository.Org+"/"+repository.Repo]; ok {
+		rules = append(rules, rr)
+	}
+	return rules
+}
+
+// apply applies the rules to the configuration, it returns false when the configuration is
+// dropped and the changes made.
+func (rr RepositoryRules) apply(cfg *prowgen.ReleaseBuildConfiguration) (bool, []string, error) {
+	var changes []string
+	changef := func(format string, args ...interface{}) {
+		changes = append(changes, fmt.Sprintf(format, args...))
+	}
+
+	variant := cfg.Metadata.Variant
+	drop, err := matchesAny(rr.DropVariants, variant)
+	if err != nil {
+		return false, nil, fmt.Errorf("dropVariants: %w", err)
+	}
+	if drop {
+		changef("dropped variant %s", variant)
+		return false, changes, nil
+	}
+
+	// Versions are looked up by their value in the copied configuration so that chained rules,
+	// like 4.16 -> 4.17 and 4.17 -> 4.18, rewrite each version once.
+	for _, name := range sortedKeys(cfg.Releases) {
+		release := cfg.Releases[name]
+		if release.Release != nil {
+			if to, ok := rr.OpenShiftVersions[release.Release.Version]; ok {
+				changef("release %s: OpenShift %s -> %s", name, release.Release.Version, to)
+				release.Release.Version = to
+			}
+		}
+		if release.Candidate != nil {
+			if to, ok := rr.OpenShiftVersions[release.Candidate.Version]; ok {
+				changef("release %s: OpenShift %s -> %s", name, release.Candidate.Version, to)
+				release.Candidate.Version = to
+			}
+		}
+		cfg.Releases[name] = release
+	}
+	for i := range cfg.Tests {
+		if claim := cfg.Tests[i].ClusterClaim; claim != nil {
+			if to, ok := rr.OpenShiftVersions[claim.Version]; ok {
+				changef("test %s: cluster claim OpenShift %s -> %s", cfg.Tests[i].As, claim.Version, to)
+				claim.Version = to
+			}
+		}
+	}
+	for _, from := range sortedKeys(rr.OpenShiftVersions) {
+		fromVariant, toVariant := strings.ReplaceAll(from, ".", ""), strings.ReplaceAll(rr.OpenShiftVersions[from], ".", "")
+		if variant == fromVariant || strings.HasPrefix(variant, fromVariant+"-") {
+			renameVariant(cfg, toVariant+strings.TrimPrefix(variant, fromVariant))
+			break
+		}
+	}
+
+	if to, ok := rr.Variants[cfg.Metadata.Variant]; ok {
+		renameVariant(cfg, to)
+	}
+	if cfg.Metadata.Variant != variant {
+		changef("variant %s -> %s", variant, cfg.Metadata.Variant)
+	}
+
+	tests := cfg.Tests[:0]
+	for _, test := range cfg.Tests {
+		drop, err := matchesAny(rr.DropTests, test.As)
+		if err != nil {
+			return false, nil, fmt.Errorf("dropTests: %w", err)
+		}
+		if drop {
+			changef("dropped test %s", test.As)
+			continue
+		}
+		tests = append(tests, test)
+	}
+	cfg.Tests = tests
+
+	for _, name := range sortedKeys(rr.Releases) {
+		if cfg.Releases == nil {
+			cfg.Releases = make(map[string]cioperatorapi.UnresolvedRelease)
+		}
+		// Rules are shared by configurations, so each configuration gets its own copy.
+		release, err := deepCopy(rr.Releases[name])
+		if err != nil {
+			return false, nil, fmt.Errorf("could not copy release %s: %w", name, err)
+		}
+		cfg.Releases[name] = release
+		changef("release %s replaced", name)
+	}
+
+	if rr.Promotion != nil {
+		promotionChanges, err := rr.Promotion.apply(cfg)
+		if err != nil {
+			return false, nil, fmt.Errorf("promotion: %w", err)
+		}
+		changes = append(changes, promotionChanges...)
+	}
+
+	if rr.Cron != "" {
+		for i := range cfg.Tests {
+			if cfg.Tests[i].Cron != nil && *cfg.Tests[i].Cron != rr.Cron {
+				cron := rr.Cron
+				cfg.Tests[i].Cron = &cron
+				changef("test %s: cron %s", cfg.Tests[i].As, cron)
+			}
+		}
+	}
+	return true, changes, nil
+}
+
+func (p *PromotionRule) apply(cfg *prowgen.ReleaseBuildConfiguration) ([]string, error) {
+	if len(p.Variants) > 0 {
+		matches, err := matchesAny(p.Variants, cfg.Metadata.Variant)
+		if err != nil || !matches {
+			return nil, err
+		}
+	}
+
+	if p.Remove {
+		if cfg.PromotionConfiguration == nil {
+			return nil, nil
+		}
+		cfg.PromotionConfiguration = nil
+		return []string{"promotion removed"}, nil
+	}
+
+	if cfg.PromotionConfiguration == nil || len(cfg.PromotionConfiguration.Targets) == 0 {
+		if p.Name == "" && p.Tag == "" {
+			return nil, nil
+		}
+		if p.Name != "" && p.Tag != "" {
+			return nil, fmt.Errorf("adding a promotion requires exactly one of name or tag")
+		}
+		namespace := p.Namespace
+		if namespace == "" {
+			namespace = "openshift"
+		}
+		cfg.PromotionConfiguration = &cioperatorapi.PromotionConfiguration{
+			Targets: []cioperatorapi.PromotionTarget{{Namespace: namespace, Name: p.Name, Tag: p.Tag}},
+		}
+		return []string{fmt.Sprintf("promotion added to %s/%s%s", namespace, p.Name, p.Tag)}, nil
+	}
+
+	var changes []string
+	for i := range cfg.PromotionConfiguration.Targets {
+		target := &cfg.PromotionConfiguration.Targets[i]
+		if p.Namespace != "" && target.Namespace != "" && target.Namespace != p.Namespace {
+			changes = append(changes, fmt.Sprintf("promotion namespace %s -> %s", target.Namespace, p.Namespace))
+			target.Namespace = p.Namespace
+		}
+		if p.Name != "" && target.Name != "" && target.Name != p.Name {
+			changes = append(changes, fmt.Sprintf("promotion name %s -> %s", target.Name, p.Name))
+			target.Name = p.Name
+		}
+		if p.Tag != "" && target.Tag != "" && target.Tag != p.Tag {
+			changes = append(changes, fmt.Sprintf("promotion tag %s -> %s", target.Tag, p.Tag))
+			target.Tag = p.Tag
+		}
+	}
+	return changes, nil
+}
+
+// renameVariant renames the variant of the configuration and its path.
+func renameVariant(cfg *prowgen.ReleaseBuildConfiguration, variant string) {
+	dir, file := filepath.Split(cfg.Path)
+	if i := strings.LastIndex(file, "__"); i >= 0 {
+		cfg.Path = filepath.Join(dir, file[:i]+"__"+variant+".yaml")
+	}
+	cfg.Metadata.Variant = variant
+}
+
+func matchesAny(exprs []string, s string) (bool, error) {
+	for _, expr := range exprs {
+		matches, err := regexp.MatchString(expr, s)
+		if err != nil {
+			return false, err
+		}
+		if matches {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func deepCopy[T any](in T) (T, error) {
+	var out T
+	b, err := json.Marshal(in)
+	if err != nil {
+		return out, err
+	}
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,238 @@
+package prowcopy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+)
+
+func TestCopyJobConfigs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "openshift-knative", "eventing")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	config := func(variant string, ocpVersion string, promotion *cioperatorapi.PromotionConfiguration) string {
+		cfg := cioperatorapi.ReleaseBuildConfiguration{
+			Metadata: cioperatorapi.Metadata{Org: "openshift-knative", Repo: "eventing", Branch: "release-next", Variant: variant},
+			InputConfiguration: cioperatorapi.InputConfiguration{
+				Releases: map[string]cioperatorapi.UnresolvedRelease{
+					"latest": {Release: &cioperatorapi.Release{Version: ocpVersion, Channel: cioperatorapi.ReleaseChannelFast}},
+				},
+			},
+			PromotionConfiguration: promotion,
+			Tests: []cioperatorapi.TestStepConfiguration{
+				{As: "test-e2e", ClusterClaim: &cioperatorapi.ClusterClaim{Version: ocpVersion}},
+				{As: "test-e2e-c", Cron: pointer.String("1 2 * * 2,6"), ClusterClaim: &cioperatorapi.ClusterClaim{Version: ocpVersion}},
+				{As: "test-kafka-e2e", ClusterClaim: &cioperatorapi.ClusterClaim{Version: ocpVersion}},
+			},
+		}
+		out, err := yaml.Marshal(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "openshift-knative-eventing-release-next__"+variant+".yaml")
+		if err := os.WriteFile(path, out, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	files := []string{
+		config("414", "4.14", nil),
+		config("416", "4.16", &cioperatorapi.PromotionConfiguration{Targets: []cioperatorapi.PromotionTarget{{Namespace: "openshift", Name: "knative-nightly"}}}),
+		config("416-arm64", "4.16", nil),
+	}
+
+	rules := &Rules{
+		RepositoryRules: RepositoryRules{
+			DropVariants:      []string{"^414"},
+			OpenShiftVersions: map[string]string{"4.16": "4.18"},
+			Promotion:         &PromotionRule{Variants: []string{"^418$"}, Name: "knative-v1.17"},
+		},
+		Repositories: map[string]RepositoryRules{
+			"openshift-knative/eventing": {
+				Variants:  map[string]string{"418-arm64": "418-multi"},
+				DropTests: []string{"kafka"},
+			},
+			"openshift-knative/serving": {
+				DropTests: []string{".*"},
+			},
+		},
+	}
+
+	c := Config{FromBranch: "release-next", Branch: "release-v1.17", RemovePeriodic: true}
+	jobs, reports, err := copyJobConfigs(files, c, rules.forRepository(prowgen.Repository{Org: "openshift-knative", Repo: "eventing"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantReports := []FileReport{
+		{
+			From:    files[0],
+			Changes: []string{"dropped periodic test test-e2e-c", "dropped variant 414"},
+		},
+		{
+			From: files[1],
+			To:   filepath.Join(dir, "openshift-knative-eventing-release-v1.17__418.yaml"),
+			Changes: []string{
+				"dropped periodic test test-e2e-c",
+				"release latest: OpenShift 4.16 -> 4.18",
+				"test test-e2e: cluster claim OpenShift 4.16 -> 4.18",
+				"test test-kafka-e2e: cluster claim OpenShift 4.16 -> 4.18",
+				"variant 416 -> 418",
+				"promotion name knative-nightly -> knative-v1.17",
+				"dropped test test-kafka-e2e",
+			},
+		},
+		{
+			From: files[2],
+			To:   filepath.Join(dir, "openshift-knative-eventing-release-v1.17__418-multi.yaml"),
+			Changes: []string{
+				"dropped periodic test test-e2e-c",
+				"release latest: OpenShift 4.16 -> 4.18",
+				"test test-e2e: cluster claim OpenShift 4.16 -> 4.18",
+				"test test-kafka-e2e: cluster claim OpenShift 4.16 -> 4.18",
+				"variant 416-arm64 -> 418-arm64",
+				"variant 418-arm64 -> 418-multi",
+				"dropped test test-kafka-e2e",
+			},
+		},
+	}
+	if diff := cmp.Diff(wantReports, reports); diff != "" {
+		t.Error("reports (-want, +got):", diff)
+	}
+
+	if len(jobs) != 2 {
+		t.Fatalf("want 2 configurations, got %d", len(jobs))
+	}
+	for _, j := range jobs {
+		if diff := cmp.Diff("4.18", j.Releases["latest"].Release.Version); diff != "" {
+			t.Errorf("%s: release version (-want, +got): %s", j.Path, diff)
+		}
+		if diff := cmp.Diff("release-v1.17", j.Metadata.Branch); diff != "" {
+			t.Errorf("%s: branch (-want, +got): %s", j.Path, diff)
+		}
+		var tests []string
+		for _, test := range j.Tests {
+			tests = append(tests, test.As)
+		}
+		if diff := cmp.Diff([]string{"test-e2e"}, tests); diff != "" {
+			t.Errorf("%s: tests (-want, +got): %s", j.Path, diff)
+		}
+	}
+	if diff := cmp.Diff("418-multi", jobs[1].Metadata.Variant); diff != "" {
+		t.Error("variant (-want, +got):", diff)
+	}
+	if jobs[1].PromotionConfiguration != nil {
+		t.Errorf("unexpected promotion %+v", jobs[1].PromotionConfiguration)
+	}
+}
+
+func TestChainedOpenShiftVersions(t *testing.T) {
+	rr := RepositoryRules{OpenShiftVersions: map[string]string{"4.16": "4.17", "4.17": "4.18"}}
+
+	for _, tc := range []struct {
+		variant string
+		from    string
+		want    string
+	}{
+		{variant: "416", from: "4.16", want: "4.17"},
+		{variant: "417", from: "4.17", want: "4.18"},
+	} {
+		t.Run(tc.variant, func(t *testing.T) {
+			cfg := &prowgen.ReleaseBuildConfiguration{
+				ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+					Metadata: cioperatorapi.Metadata{Org: "openshift-knative", Repo: "eventing", Branch: "release-next", Variant: tc.variant},
+					InputConfiguration: cioperatorapi.InputConfiguration{
+						Releases: map[string]cioperatorapi.UnresolvedRelease{
+							"latest": {Release: &cioperatorapi.Release{Version: tc.from}},
+						},
+					},
+					Tests: []cioperatorapi.TestStepConfiguration{
+						{As: "test-e2e", ClusterClaim: &cioperatorapi.ClusterClaim{Version: tc.from}},
+					},
+				},
+			}
+			keep, changes, err := rr.apply(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !keep {
+				t.Fatal("configuration dropped")
+			}
+			wantVariant := strings.ReplaceAll(tc.want, ".", "")
+			wantChanges := []string{
+				"release latest: OpenShift " + tc.from + " -> " + tc.want,
+				"test test-e2e: cluster claim OpenShift " + tc.from + " -> " + tc.want,
+				"variant " + tc.variant + " -> " + wantVariant,
+			}
+			if diff := cmp.Diff(wantChanges, changes); diff != "" {
+				t.Error("changes (-want, +got):", diff)
+			}
+			if diff := cmp.Diff(tc.want, cfg.Releases["latest"].Release.Version); diff != "" {
+				t.Error("release version (-want, +got):", diff)
+			}
+			if diff := cmp.Diff(tc.want, cfg.Tests[0].ClusterClaim.Version); diff != "" {
+				t.Error("cluster claim version (-want, +got):", diff)
+			}
+			if diff := cmp.Diff(wantVariant, cfg.Metadata.Variant); diff != "" {
+				t.Error("variant (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestPromotionRule(t *testing.T) {
+	tests := []struct {
+		name string
+		rule PromotionRule
+		in   *cioperatorapi.PromotionConfiguration
+		want *cioperatorapi.PromotionConfiguration
+	}{
+		{
+			name: "add",
+			rule: PromotionRule{Tag: "knative-v1.17"},
+			want: &cioperatorapi.PromotionConfiguration{Targets: []cioperatorapi.PromotionTarget{{Namespace: "openshift", Tag: "knative-v1.17"}}},
+		},
+		{
+			name: "remove",
+			rule: PromotionRule{Remove: true},
+			in:   &cioperatorapi.PromotionConfiguration{Targets: []cioperatorapi.PromotionTarget{{Namespace: "openshift", Name: "knative-nightly"}}},
+		},
+		{
+			name: "rewrite",
+			rule: PromotionRule{Namespace: "serverless", Name: "knative-v1.17", Tag: "knative-v1.17"},
+			in:   &cioperatorapi.PromotionConfiguration{Targets: []cioperatorapi.PromotionTarget{{Namespace: "openshift", Tag: "knative-nightly"}}},
+			want: &cioperatorapi.PromotionConfiguration{Targets: []cioperatorapi.PromotionTarget{{Namespace: "serverless", Tag: "knative-v1.17"}}},
+		},
+		{
+			name: "other variant",
+			rule: PromotionRule{Variants: []string{"^418$"}, Remove: true},
+			in:   &cioperatorapi.PromotionConfiguration{Targets: []cioperatorapi.PromotionTarget{{Namespace: "openshift", Name: "knative-nightly"}}},
+			want: &cioperatorapi.PromotionConfiguration{Targets: []cioperatorapi.PromotionTarget{{Namespace: "openshift", Name: "knative-nightly"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &prowgen.ReleaseBuildConfiguration{
+				ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+					Metadata:               cioperatorapi.Metadata{Variant: "416"},
+					PromotionConfiguration: tt.in,
+				},
+			}
+			if _, err := tt.rule.apply(cfg); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, cfg.PromotionConfiguration); diff != "" {
+				t.Error("(-want, +got):", diff)
+			}
+		})
+	}
+}
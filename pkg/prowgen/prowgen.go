@@ -13,6 +13,7 @@ package prowgen
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io/fs"
@@ -21,13 +22,14 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/openshift-knative/hack/pkg/util"
+	"github.com/openshift-knative/hack/pkg/verify"
 	"github.com/openshift/ci-tools/pkg/api/shardprowconfig"
 	"sigs.k8s.io/yaml"
 
 	"github.com/coreos/go-semver/semver"
-	"golang.org/x/sync/errgroup"
 )
 
 // Config is the prowgen configuration file struct.
@@ -35,9 +37,24 @@ type Config struct {
 	Repositories []Repository `json:"repositories,omitempty" yaml:"repositories,omitempty"`
 
 	Config CommonConfig `json:"config,omitempty" yaml:"config,omitempty"`
+
+	// Schedule enables the global scheduler of periodic jobs for every loaded configuration,
+	// at most one configuration can define it.
+	Schedule *Schedule `json:"schedule,omitempty" yaml:"schedule,omitempty"`
 }
 
 func Main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			ValidateMain(os.Args[2:])
+			return
+		case "resolve":
+			ResolveMain(os.Args[2:])
+			return
+		}
+	}
+
 	ctx := context.TODO()
 
 	openShiftRelease := Repository{
@@ -46,25 +63,110 @@ func Main() {
 	}
 
 	inputConfig := flag.String("config", filepath.Join("config", "repositories.yaml"), "Specify repositories config")
-	outConfig := flag.String("output", filepath.Join(openShiftRelease.Org, openShiftRelease.Repo, "ci-operator", "config"), "Specify repositories config")
+	outConfig := flag.String("output", "", "Specify repositories config (default <workspace>/openshift/release/ci-operator/config)")
 	remote := flag.String("remote", "", "openshift/release remote fork (example: git@github.com:pierDipi/release.git)")
 	branch := flag.String("branch", "sync-serverless-ci", "Branch for remote fork")
 	build := flag.Bool("build", true, "Run the openshift/release generator")
 	push := flag.Bool("push", true, "Whether to commit and push the changes")
 	konflux := flag.Bool("konflux", true, "Whether to generate Konflux config")
 	owners := flag.Bool("owners", true, "Whether to generate OWNERS files")
+	workspace := RegisterWorkspaceFlags(flag.CommandLine)
+	plan := flag.Bool("plan", false, "Print the changes to the openshift/release configurations, jobs and prow configuration without modifying it (implies -build=false -push=false -konflux=false -owners=false)")
+	planFormat := flag.String("plan-format", PlanFormatHuman, "Format of the plan output: 'human' or 'json'")
+	scheduleReport := flag.String("schedule-report", "", "Write the JSON report of the periodic jobs schedule to the given file ('-' for stdout), requires a configured schedule")
+	force := flag.Bool("force", false, "Regenerate every repository, ignoring the cache of unchanged repositories")
+	cacheManifest := flag.String("cache-manifest", "", "Cache manifest of the generated repositories, it must be outside of the openshift/release clone (default <workspace>/"+CacheManifestName+")")
+	reportPath := flag.String("report", "", "Write the JSON report of the generation to the given file ('-' for stdout)")
+	verifyOnly := flag.Bool("verify", false, "Print the unified diff between the generated files and the existing ones and fail when they differ, without modifying them; OWNERS files are verified with -owners and dependabot configurations with -konflux, Konflux .tekton and .konflux files of the repositories aren't verified, see konflux-gen --verify (implies -build=false -push=false)")
 	flag.Parse()
 
+	ctx = WithWorkspace(ctx, workspace)
+	if *planFormat != PlanFormatHuman && *planFormat != PlanFormatJSON {
+		log.Fatalln("Unknown plan format", *planFormat)
+	}
+	if *outConfig == "" {
+		*outConfig = filepath.Join(openShiftRelease.LocalDirectory(ctx), "ci-operator", "config")
+	}
+
 	log.Println(*inputConfig, *outConfig)
 
+	inConfigs, err := LoadConfigs(*inputConfig)
+	if err != nil {
+		log.Fatalln("Failed to load configs", *inputConfig, err)
+	}
+
+	if *plan {
+		p, err := NewPlan(ctx, openShiftRelease, inConfigs, *outConfig)
+		if err != nil {
+			log.Fatalln("Failed to compute plan", err)
+		}
+		if err := p.Write(os.Stdout, *planFormat); err != nil {
+			log.Fatalln("Failed to write plan", err)
+		}
+		return
+	}
+
+	if *verifyOnly {
+		diff, err := Verify(ctx, openShiftRelease, inConfigs, *outConfig)
+		if err != nil {
+			log.Fatalln("Failed to verify configurations", err)
+		}
+		if *owners {
+			ownersDiff, err := VerifyOwners(ctx, inConfigs)
+			if err != nil {
+				log.Fatalln("Failed to verify OWNERS files", err)
+			}
+			diff += ownersDiff
+		}
+		if *konflux {
+			dependabotDiff, err := VerifyDependabot(ctx, inConfigs)
+			if err != nil {
+				log.Fatalln("Failed to verify dependabot configurations", err)
+			}
+			diff += dependabotDiff
+		}
+		if err := verify.Check(os.Stdout, diff); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	opts := []GeneratorOption{
+		WithOpenShiftRelease(openShiftRelease),
+		WithOutputConfig(*outConfig),
+		WithBuild(*build),
+		WithKonflux(*konflux),
+		WithOwners(*owners),
+		WithForce(*force),
+		WithCacheManifest(*cacheManifest),
+	}
+	if *push {
+		opts = append(opts, WithPush(*remote, *branch, "Sync Serverless CI "+*inputConfig))
+	}
+	report, err := NewGenerator(inConfigs, opts...).Run(ctx)
+	if reportErr := writeReport(*reportPath, report); reportErr != nil {
+		log.Println("Failed to write report", reportErr)
+		err = errors.Join(err, reportErr)
+	}
+	if err != nil {
+		log.Fatalln("Failed to generate configurations", err)
+	}
+	if err := writeScheduleReport(*scheduleReport, report.Schedule); err != nil {
+		log.Fatalln("Failed to write schedule report", err)
+	}
+}
+
+// LoadConfigs loads the configuration file at path or, when path is a directory, every YAML
+// configuration file in it.
+func LoadConfigs(path string) ([]*Config, error) {
 	var inConfigs []*Config
 
-	fi, err := os.Lstat(*inputConfig)
+	fi, err := os.Lstat(path)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
 	if fi.IsDir() {
-		err := filepath.WalkDir(*inputConfig, func(path string, d fs.DirEntry, err error) error {
+		err := filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
 			if d.IsDir() {
 				return nil
 			}
@@ -81,12 +183,12 @@ func Main() {
 			return nil
 		})
 		if err != nil {
-			log.Fatalln("Failed to load configs", *inputConfig, err)
+			return nil, err
 		}
 	} else {
-		inConfig, err := LoadConfig(*inputConfig)
+		inConfig, err := LoadConfig(path)
 		if err != nil {
-			log.Fatalln("Failed to load config", err)
+			return nil, fmt.Errorf("failed to load config: %w", err)
 		}
 		inConfigs = append(inConfigs, inConfig)
 	}
@@ -98,87 +200,148 @@ func Main() {
 			})
 		}
 	}
+	return inConfigs, nil
+}
+
+// generatedRepository holds the configurations generated for a repository.
+type generatedRepository struct {
+	Repository Repository
+	Config     *Config
+	Configs    []ReleaseBuildConfiguration
+	// Err is the error generating configurations for the repository.
+	Err error
+	// Cached is true when the configurations are reused from a previous run.
+	Cached bool
+}
 
-	// Clone openshift/release and clean up existing jobs for the configured branches
-	openshiftReleaseInitialization, openshiftReleaseInitCtx := errgroup.WithContext(ctx)
-	openshiftReleaseInitialization.Go(func() error {
-		return InitializeOpenShiftReleaseRepository(openshiftReleaseInitCtx, openShiftRelease, inConfigs, outConfig)
-	})
+// generateRepositories generates configurations for every repository in inConfigs and, when
+// a schedule is configured, it schedules periodic jobs across all of them.
+// Errors of a single repository, including inconsistencies of the generated configurations
+// (see checkConsistency), are returned in generatedRepository.Err.
+// Repositories in cached aren't generated, their cached configurations are used instead.
+func generateRepositories(ctx context.Context, inConfigs []*Config, cached map[cacheRepositoryKey][]ReleaseBuildConfiguration) ([]generatedRepository, *ScheduleReport, error) {
+	schedule, err := scheduleForConfigs(inConfigs)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// For each repository and branch generate openshift/release configuration, and write it to the output file.
-	repositoriesGenerateConfigs, generatorsCtx := errgroup.WithContext(ctx)
+	var generated []generatedRepository
 	for _, inConfig := range inConfigs {
-		inConfig := inConfig
-
 		for _, repository := range inConfig.Repositories {
-			repository := repository
-
-			repositoriesGenerateConfigs.Go(func() error {
-
-				cfgs, err := NewGenerateConfigs(generatorsCtx, repository, inConfig.Config)
-				if err != nil {
-					return err
-				}
-
-				// Wait for the openshift/release initialization goroutine.
-				if err := openshiftReleaseInitialization.Wait(); err != nil {
-					return fmt.Errorf("failed waiting for %s initialization: %w", openShiftRelease.RepositoryDirectory(), err)
-				}
-
-				// Delete existing configuration for each configured branch.
-				for branch, b := range inConfig.Config.Branches {
-					if b.Prowgen != nil && b.Prowgen.Disabled {
-						continue
-					}
-					if err := DeleteExistingReleaseBuildConfigurationForBranch(outConfig, repository, branch); err != nil {
-						return err
-					}
-				}
-
-				// Write generated configurations.
-				for _, cfg := range cfgs {
-					if err := SaveReleaseBuildConfiguration(outConfig, cfg); err != nil {
-						return err
-					}
-				}
-
-				branchProtectionAndTideConfig := NewProwConfig(repository)
-				if err := SaveProwConfig(openShiftRelease, repository, branchProtectionAndTideConfig); err != nil {
-					return err
-				}
-
-				return nil
-			})
+			g := generatedRepository{Repository: repository, Config: inConfig}
+			g.Configs, g.Cached = cached[cacheRepositoryKey{config: inConfig, repository: repository.RepositoryDirectory()}]
+			generated = append(generated, g)
 		}
 	}
-	// Wait for the openshift/release initialization goroutine and repositories generators goroutines.
-	if err := openshiftReleaseInitialization.Wait(); err != nil {
-		log.Fatalln("Failed waiting for", openShiftRelease.RepositoryDirectory(), "initialization", err)
+
+	var repositoriesGenerateConfigs sync.WaitGroup
+	for i := range generated {
+		g := &generated[i]
+		if g.Cached {
+			continue
+		}
+		repositoriesGenerateConfigs.Go(func() {
+			g.Configs, g.Err = NewGenerateConfigs(ctx, g.Repository, g.Config.Config)
+		})
 	}
+	repositoriesGenerateConfigs.Wait()
+	checkConsistency(generated)
 
-	if err := repositoriesGenerateConfigs.Wait(); err != nil {
-		log.Fatalln("Failed waiting for repositories generator", err)
+	if schedule == nil {
+		return generated, nil, nil
 	}
-	if *build {
-		if err := RunOpenShiftReleaseGenerator(ctx, openShiftRelease); err != nil {
-			log.Fatalln("Failed to run openshift/release generator:", err)
+	var cfgs []*ReleaseBuildConfiguration
+	for i := range generated {
+		if generated[i].Err != nil {
+			continue
 		}
-	}
-	if *push {
-		if err := PushBranch(ctx, openShiftRelease, remote, *branch, "Sync Serverless CI "+*inputConfig); err != nil {
-			log.Fatalln("Failed to push branch to openshift/release fork", *remote, err)
+		for j := range generated[i].Configs {
+			cfgs = append(cfgs, &generated[i].Configs[j])
 		}
 	}
-	if *konflux {
-		if err := GenerateKonflux(ctx, openShiftRelease, inConfigs); err != nil {
-			log.Fatalln("Failed to generate Konflux configurations: %w", err)
+	report, err := ScheduleConfigs(*schedule, cfgs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return generated, report, nil
+}
+
+// savedRepository are the files written and deleted by saveGeneratedRepository.
+type savedRepository struct {
+	written    []string
+	deleted    []string
+	prowConfig string
+}
+
+// saveGeneratedRepository replaces existing configurations for the configured branches of the
+// repository with the generated ones.
+func saveGeneratedRepository(ctx context.Context, openShiftRelease Repository, outConfig string, g generatedRepository) (savedRepository, error) {
+	s := savedRepository{}
+
+	// Delete existing configuration for each configured branch.
+	for branch, b := range g.Config.Config.Branches {
+		if b.Prowgen != nil && b.Prowgen.Disabled {
+			continue
+		}
+		deleted, err := deleteExistingReleaseBuildConfigurationForBranch(outConfig, g.Repository, branch)
+		s.deleted = append(s.deleted, deleted...)
+		if err != nil {
+			return s, err
 		}
 	}
-	if *owners {
-		if err := GenerateOwners(ctx, inConfigs); err != nil {
-			log.Fatalln("Failed to generate OWNERS files: %w", err)
+
+	// Write generated configurations.
+	for _, cfg := range g.Configs {
+		if err := SaveReleaseBuildConfiguration(&outConfig, cfg); err != nil {
+			return s, err
 		}
+		s.written = append(s.written, filepath.Join(outConfig, cfg.Path))
+	}
+
+	branchProtectionAndTideConfig, err := NewProwConfig(g.Repository, g.Configs...)
+	if err != nil {
+		return s, err
+	}
+	if err := SaveProwConfig(ctx, openShiftRelease, g.Repository, branchProtectionAndTideConfig); err != nil {
+		return s, err
+	}
+	s.prowConfig = prowConfigPath(ctx, openShiftRelease, g.Repository)
+	return s, nil
+}
+
+// writeReport writes the report to the given path, '-' is stdout.
+func writeReport(path string, report *Report) error {
+	if path == "" {
+		return nil
+	}
+	if path == "-" {
+		return report.Write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+	return report.Write(f)
+}
+
+// writeScheduleReport writes the report to the given path, '-' is stdout.
+func writeScheduleReport(path string, report *ScheduleReport) error {
+	if path == "" {
+		return nil
+	}
+	if report == nil {
+		return fmt.Errorf("no schedule configured")
+	}
+	if path == "-" {
+		return report.Write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return report.Write(f)
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -192,7 +355,27 @@ func LoadConfig(path string) (*Config, error) {
 	return UnmarshalConfig(y)
 }
 
+// UnmarshalConfig unmarshals the configuration and resolves branch profiles and OpenShift
+// version sets, see CommonConfig.Resolve.
 func UnmarshalConfig(rawYaml []byte) (*Config, error) {
+	inConfig, err := unmarshalConfig(rawYaml)
+	if err != nil {
+		return nil, err
+	}
+	// Resolve the configuration as written, so that branches can override profile values with
+	// zero values.
+	raw, err := unmarshalRawCommonConfig(rawYaml)
+	if err != nil {
+		return nil, err
+	}
+	inConfig.Config, err = raw.resolve(inConfig.Config)
+	if err != nil {
+		return nil, err
+	}
+	return inConfig, nil
+}
+
+func unmarshalConfig(rawYaml []byte) (*Config, error) {
 	j, err := yaml.YAMLToJSON(rawYaml)
 	if err != nil {
 		return nil, err
@@ -233,23 +416,50 @@ func PushBranch(ctx context.Context, release Repository, remote *string, branch
 }
 
 func DeleteExistingReleaseBuildConfigurationForBranch(outConfig *string, r Repository, branch string) error {
-	dir := filepath.Join(*outConfig, r.RepositoryDirectory())
+	_, err := deleteExistingReleaseBuildConfigurationForBranch(*outConfig, r, branch)
+	return err
+}
+
+// deleteExistingReleaseBuildConfigurationForBranch returns the deleted files.
+func deleteExistingReleaseBuildConfigurationForBranch(outConfig string, r Repository, branch string) ([]string, error) {
+	dir := filepath.Join(outConfig, r.RepositoryDirectory())
 	configPaths, err := filepath.Glob(filepath.Join(dir, "*"+branch+"*"))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if err := deleteConfigsIfNeeded(r.IgnoreConfigs.Matches, configPaths, branch); err != nil {
-		return err
+	return deleteConfigsIfNeeded(r.IgnoreConfigs.Matches, configPaths, branch)
+}
+
+func deleteConfigsIfNeeded(ignoreConfigs []string, paths []string, branch string) ([]string, error) {
+	matches, err := configsForDeletion(ignoreConfigs, paths)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+
+	deleted := make([]string, 0, len(matches))
+	for _, path := range matches {
+		if branch != "" {
+			log.Println("Detected a config for branch", branch, "removing file", path)
+		} else {
+			log.Println("Detected a config, removing file", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, path)
+	}
+	return deleted, nil
 }
 
-func deleteConfigsIfNeeded(ignoreConfigs []string, paths []string, branch string) error {
+// configsForDeletion returns the paths that don't match any of the ignoreConfigs patterns.
+func configsForDeletion(ignoreConfigs []string, paths []string) ([]string, error) {
 	excludeFilePattern, err := util.ToRegexp(ignoreConfigs)
 	if err != nil {
-		return fmt.Errorf("failed to parse ignore configs regex: %w", err)
+		return nil, fmt.Errorf("failed to parse ignore configs regex: %w", err)
 	}
 
+	var matches []string
 	for _, path := range paths {
 		include := true
 		for _, r := range excludeFilePattern {
@@ -259,22 +469,14 @@ func deleteConfigsIfNeeded(ignoreConfigs []string, paths []string, branch string
 			}
 		}
 		if include {
-			if branch != "" {
-				log.Println("Detected a config for branch", branch, "removing file", path)
-			} else {
-				log.Println("Detected a config, removing file", path)
-			}
-
-			if err := os.Remove(path); err != nil {
-				return err
-			}
+			matches = append(matches, path)
 		}
 	}
-	return nil
+	return matches, nil
 }
 
-func SaveProwConfig(openShiftRelease Repository, repository Repository, config shardprowconfig.ProwConfigWithPointers) error {
-	outPath := filepath.Join(openShiftRelease.Org, openShiftRelease.Repo, "core-services", "prow", "02_config", repository.Org, repository.Repo, "_prowconfig.yaml")
+func SaveProwConfig(ctx context.Context, openShiftRelease Repository, repository Repository, config shardprowconfig.ProwConfigWithPointers) error {
+	outPath := prowConfigPath(ctx, openShiftRelease, repository)
 
 	dir := filepath.Dir(outPath)
 
@@ -290,7 +492,16 @@ func SaveProwConfig(openShiftRelease Repository, repository Repository, config s
 	return os.WriteFile(outPath, out, os.ModePerm)
 }
 
-const slackReportTemplate = `{{if eq .Status.State "success"}} :rainbow: Job *{{.Spec.Job}}* ended with *{{.Status.State}}*. <{{.Status.URL}}|View logs> :rainbow: {{else}} :volcano: Job *{{.Spec.Job}}* ended with *{{.Status.State}}*. <{{.Status.URL}}|View logs> :volcano: {{end}}`
+// prowConfigPath returns the path of the repository _prowconfig.yaml shard in the openshift/release clone.
+func prowConfigPath(ctx context.Context, openShiftRelease Repository, repository Repository) string {
+	return filepath.Join(openShiftRelease.LocalDirectory(ctx), prowConfigShard(repository))
+}
+
+// prowConfigShard returns the path of the repository _prowconfig.yaml shard relative to the
+// openshift/release root.
+func prowConfigShard(repository Repository) string {
+	return filepath.Join("core-services", "prow", "02_config", repository.Org, repository.Repo, "_prowconfig.yaml")
+}
 
 func SaveReleaseBuildConfiguration(outConfig *string, cfg ReleaseBuildConfiguration) error {
 	dir := filepath.Join(*outConfig, filepath.Dir(cfg.Path))
@@ -299,18 +510,11 @@ func SaveReleaseBuildConfiguration(outConfig *string, cfg ReleaseBuildConfigurat
 		return err
 	}
 
-	out, err := yaml.Marshal(cfg.ReleaseBuildConfiguration)
+	out, err := marshalReleaseBuildConfiguration(cfg)
 	if err != nil {
 		return err
 	}
 
-	if cfg.SlackChannel != "" {
-		out, err = addReporterConfigToTests(out, cfg.SlackChannel)
-		if err != nil {
-			return err
-		}
-	}
-
 	if err := os.WriteFile(filepath.Join(*outConfig, cfg.Path), out, os.ModePerm); err != nil {
 		return err
 	}
@@ -318,55 +522,12 @@ func SaveReleaseBuildConfiguration(outConfig *string, cfg ReleaseBuildConfigurat
 	return copyOwnersFileIfNotPresent(dir)
 }
 
-// addReporterConfigToTests injects a reporter_config stanza into every test that
-// has a cron field, so Slack notifications are configured directly in the
-// ci-operator config yaml instead of via a separate .config.prowgen file.
-func addReporterConfigToTests(out []byte, slackChannel string) ([]byte, error) {
-	var rawConfig interface{}
-	if err := yaml.Unmarshal(out, &rawConfig); err != nil {
-		return nil, err
-	}
-
-	configMap, ok := rawConfig.(map[string]interface{})
-	if !ok {
-		return out, nil
+// marshalReleaseBuildConfiguration returns the YAML content of the ci-operator config file.
+func marshalReleaseBuildConfiguration(cfg ReleaseBuildConfiguration) ([]byte, error) {
+	if len(cfg.ReporterConfigs) == 0 {
+		return yaml.Marshal(cfg.ReleaseBuildConfiguration)
 	}
-
-	testsRaw, ok := configMap["tests"]
-	if !ok {
-		return out, nil
-	}
-
-	tests, ok := testsRaw.([]interface{})
-	if !ok {
-		return out, nil
-	}
-
-	reporterConfig := map[string]interface{}{
-		"channel":              slackChannel,
-		"job_states_to_report": []interface{}{"success", "failure", "error"},
-		"report_template":      slackReportTemplate,
-	}
-
-	modified := false
-	for i, testRaw := range tests {
-		test, ok := testRaw.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		if _, hasCron := test["cron"]; hasCron {
-			test["reporter_config"] = reporterConfig
-			tests[i] = test
-			modified = true
-		}
-	}
-
-	if !modified {
-		return out, nil
-	}
-
-	configMap["tests"] = tests
-	return yaml.Marshal(rawConfig)
+	return yaml.Marshal(withReporterConfigs(cfg.ReleaseBuildConfiguration, cfg.ReporterConfigs))
 }
 
 func copyOwnersFileIfNotPresent(dir string) error {
@@ -402,42 +563,92 @@ func InitializeOpenShiftReleaseRepository(ctx context.Context, openShiftRelease
 	if err := GitCheckout(ctx, openShiftRelease, "main"); err != nil {
 		return err
 	}
+	_, err := deleteExistingConfigs(inConfigs, *outputConfig)
+	return err
+}
 
+// deleteExistingConfigs deletes the existing configurations replaced by the generated ones and
+// returns the deleted files.
+func deleteExistingConfigs(inConfigs []*Config, outputConfig string) ([]string, error) {
 	// Remove all config files except the ones explicitly excluded
+	paths, err := existingConfigsForDeletion(inConfigs, outputConfig)
+	if err != nil {
+		return nil, err
+	}
+	return deleteConfigFiles(paths)
+}
+
+// deleteExistingRepositoryConfigs deletes the existing configurations of the repository replaced
+// by the generated ones and returns the deleted files.
+func deleteExistingRepositoryConfigs(inConfig *Config, r Repository, outputConfig string) ([]string, error) {
+	paths, err := existingRepositoryConfigsForDeletion(inConfig, r, outputConfig)
+	if err != nil {
+		return nil, err
+	}
+	return deleteConfigFiles(paths)
+}
+
+func deleteConfigFiles(paths []string) ([]string, error) {
+	deleted := make([]string, 0, len(paths))
+	for _, path := range paths {
+		log.Println("Detected a config, removing file", path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return deleted, err
+		}
+		deleted = append(deleted, path)
+	}
+	return deleted, nil
+}
+
+// existingConfigsForDeletion returns the existing config files in outputConfig that are
+// replaced by the generated configurations.
+func existingConfigsForDeletion(inConfigs []*Config, outputConfig string) ([]string, error) {
+	var paths []string
 	for _, inConfig := range inConfigs {
 		for _, r := range inConfig.Repositories {
-			// TODO: skip automatic deletion for S-O for now
-			if strings.Contains(r.RepositoryDirectory(), "serverless-operator") {
-				// Delete .config.prowgen if it exists; the branch-based glob below won't catch it.
-				prowgenConfigPath := filepath.Join(*outputConfig, r.RepositoryDirectory(), ".config.prowgen")
-				if err := os.Remove(prowgenConfigPath); err != nil && !os.IsNotExist(err) {
-					return err
-				}
-
-				for branch, branchConfig := range inConfig.Config.Branches {
-					if branchConfig.Prowgen != nil && branchConfig.Prowgen.Disabled {
-						continue
-					}
-
-					matches, err := filepath.Glob(filepath.Join(*outputConfig, r.RepositoryDirectory(), "*"+branch+"*"))
-					if err != nil {
-						return err
-					}
-					if err := deleteConfigsIfNeeded(r.IgnoreConfigs.Matches, matches, branch); err != nil {
-						return err
-					}
-				}
+			matches, err := existingRepositoryConfigsForDeletion(inConfig, r, outputConfig)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, matches...)
+		}
+	}
+	return paths, nil
+}
+
+// existingRepositoryConfigsForDeletion returns the existing config files of the repository in
+// outputConfig that are replaced by the generated configurations.
+func existingRepositoryConfigsForDeletion(inConfig *Config, r Repository, outputConfig string) ([]string, error) {
+	// TODO: skip automatic deletion for S-O for now
+	if strings.Contains(r.RepositoryDirectory(), "serverless-operator") {
+		var paths []string
+		// Delete .config.prowgen if it exists; the branch-based glob below won't catch it.
+		prowgenConfigPath := filepath.Join(outputConfig, r.RepositoryDirectory(), ".config.prowgen")
+		if _, err := os.Stat(prowgenConfigPath); err == nil {
+			paths = append(paths, prowgenConfigPath)
+		}
+
+		for branch, branchConfig := range inConfig.Config.Branches {
+			if branchConfig.Prowgen != nil && branchConfig.Prowgen.Disabled {
 				continue
 			}
-			// Remove all config files except the ones explicitly excluded
-			matchesForDeletion, err := filepath.Glob(filepath.Join(*outputConfig, r.RepositoryDirectory(), "*.*"))
+
+			matches, err := filepath.Glob(filepath.Join(outputConfig, r.RepositoryDirectory(), "*"+branch+"*"))
 			if err != nil {
-				return err
+				return nil, err
 			}
-			if err := deleteConfigsIfNeeded(r.IgnoreConfigs.Matches, matchesForDeletion, ""); err != nil {
-				return err
+			matches, err = configsForDeletion(r.IgnoreConfigs.Matches, matches)
+			if err != nil {
+				return nil, err
 			}
+			paths = append(paths, matches...)
 		}
+		return paths, nil
 	}
-	return nil
+	// Remove all config files except the ones explicitly excluded
+	matchesForDeletion, err := filepath.Glob(filepath.Join(outputConfig, r.RepositoryDirectory(), "*.*"))
+	if err != nil {
+		return nil, err
+	}
+	return configsForDeletion(r.IgnoreConfigs.Matches, matchesForDeletion)
 }
@@ -0,0 +1,115 @@
+package prowgen
+
+import (
+	"fmt"
+	"slices"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+)
+
+var (
+	// defaultArchitecture is the architecture of the main variant of each OpenShift version.
+	defaultArchitecture = Architecture{Name: string(cioperatorapi.ReleaseArchitectureAMD64)}
+
+	// architectureImages are the architectures images are built for, in addition to amd64,
+	// for tests running on clusters with the given architecture.
+	architectureImages = map[string][]string{
+		"arm64": {"arm64"},
+		"multi": {"arm64"},
+	}
+
+	// architectureEnv is the default environment for tests starting clusters from scratch
+	// with the given architecture.
+	architectureEnv = map[string]cioperatorapi.TestEnvironment{
+		"arm64": {
+			"OCP_ARCH": "arm64",
+		},
+	}
+)
+
+// architectures returns the default architecture followed by the additional architectures.
+func (ov OpenShift) architectures() ([]Architecture, error) {
+	archs := make([]Architecture, 0, len(ov.Architectures)+1)
+	archs = append(archs, defaultArchitecture)
+	for _, a := range ov.Architectures {
+		if _, ok := architectureImages[a.Name]; !ok {
+			return nil, fmt.Errorf("OpenShift %s: unsupported architecture %q, supported architectures: %v", ov.Version, a.Name, supportedArchitectures())
+		}
+		if slices.ContainsFunc(archs, func(other Architecture) bool { return other.Name == a.Name }) {
+			return nil, fmt.Errorf("OpenShift %s: duplicate architecture %q", ov.Version, a.Name)
+		}
+		archs = append(archs, a)
+	}
+	return archs, nil
+}
+
+func (a Architecture) isDefault() bool {
+	return a.Name == defaultArchitecture.Name
+}
+
+// variant returns the ci-operator variant for the given OpenShift version, for example,
+// 414 for the default architecture and 414-arm64 for arm64.
+func (a Architecture) variant(ov OpenShift) string {
+	variant := openShiftVariant(ov)
+	if a.isDefault() {
+		return variant
+	}
+	return variant + "-" + a.Name
+}
+
+func (a Architecture) useClusterPool(ov OpenShift) bool {
+	if a.UseClusterPool != nil {
+		return *a.UseClusterPool
+	}
+	return ov.UseClusterPool
+}
+
+// env returns the environment for tests starting clusters from scratch.
+func (a Architecture) env() cioperatorapi.TestEnvironment {
+	env := cioperatorapi.TestEnvironment{}
+	for k, v := range architectureEnv[a.Name] {
+		env[k] = v
+	}
+	for k, v := range a.Env {
+		env[k] = v
+	}
+	return env
+}
+
+func supportedArchitectures() []string {
+	names := make([]string, 0, len(architectureImages))
+	for name := range architectureImages {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// WithArchitecture configures releases and images for tests running on clusters with
+// the given architecture.
+func WithArchitecture(a Architecture) ReleaseBuildConfigurationOption {
+	return func(cfg *cioperatorapi.ReleaseBuildConfiguration) error {
+		if a.isDefault() {
+			return nil
+		}
+		arch := cioperatorapi.ReleaseArchitecture(a.Name)
+		for name, release := range cfg.Releases {
+			if release.Release != nil {
+				release.Release.Architecture = arch
+			}
+			if release.Candidate != nil {
+				release.Candidate.Architecture = arch
+			}
+			cfg.Releases[name] = release
+		}
+		for i := range cfg.Images.Items {
+			img := &cfg.Images.Items[i]
+			for _, imgArch := range architectureImages[a.Name] {
+				if !slices.Contains(img.AdditionalArchitectures, imgArch) {
+					img.AdditionalArchitectures = append(img.AdditionalArchitectures, imgArch)
+				}
+			}
+		}
+		return nil
+	}
+}
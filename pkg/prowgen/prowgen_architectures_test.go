@@ -0,0 +1,172 @@
+package prowgen
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/ptr"
+)
+
+func TestNewGenerateConfigsArchitectures(t *testing.T) {
+	r := Repository{
+		Org:         "testorg",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests:    []E2ETest{{Match: "test-e2e$"}},
+	}
+
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, r.Org, r.Repo+".git"), "release-next")
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	cc := CommonConfig{
+		Branches: map[string]Branch{
+			"release-next": {
+				OpenShiftVersions: []OpenShift{{
+					Version:        "4.14",
+					UseClusterPool: true,
+					SkipCron:       true,
+					Architectures: []Architecture{
+						{Name: "arm64", UseClusterPool: ptr.To(false)},
+						{Name: "multi"},
+					},
+				}},
+			},
+		},
+	}
+	cfgs, err := NewGenerateConfigs(ctx, r, cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, cfg := range cfgs {
+		paths = append(paths, cfg.Path)
+	}
+	if diff := cmp.Diff([]string{
+		filepath.Join("testorg", "serving", "testorg-serving-release-next__414.yaml"),
+		filepath.Join("testorg", "serving", "testorg-serving-release-next__414-arm64.yaml"),
+		filepath.Join("testorg", "serving", "testorg-serving-release-next__414-multi.yaml"),
+	}, paths); diff != "" {
+		t.Fatal("paths (-want, +got):", diff)
+	}
+
+	amd64, arm64, multi := cfgs[0], cfgs[1], cfgs[2]
+
+	if amd64.PromotionConfiguration == nil {
+		t.Error("expected promotion for the default architecture")
+	}
+	for _, cfg := range []ReleaseBuildConfiguration{arm64, multi} {
+		if cfg.PromotionConfiguration != nil {
+			t.Errorf("%s: unexpected promotion %+v", cfg.Path, cfg.PromotionConfiguration)
+		}
+		for _, img := range cfg.Images.Items {
+			if diff := cmp.Diff([]string{"arm64"}, img.AdditionalArchitectures); diff != "" {
+				t.Errorf("%s: image %s additional architectures (-want, +got): %s", cfg.Path, img.To, diff)
+			}
+		}
+		for _, test := range cfg.Tests {
+			if len(test.As) > maxNameLength {
+				t.Errorf("%s: test name %q is longer than %d", cfg.Path, test.As, maxNameLength)
+			}
+		}
+	}
+	for _, img := range amd64.Images.Items {
+		if len(img.AdditionalArchitectures) != 0 {
+			t.Errorf("unexpected additional architectures for image %s: %v", img.To, img.AdditionalArchitectures)
+		}
+	}
+
+	if got := arm64.Releases["latest"].Release.Architecture; got != "arm64" {
+		t.Errorf("want arm64 release, got %q", got)
+	}
+	if got := amd64.Releases["latest"].Release.Architecture; got != "" {
+		t.Errorf("want default release architecture, got %q", got)
+	}
+
+	// amd64 and multi use cluster pools, arm64 starts a cluster from scratch.
+	if diff := cmp.Diff(cioperatorapi.ReleaseArchitecture("multi"), multi.Tests[0].ClusterClaim.Architecture); diff != "" {
+		t.Error("multi cluster claim architecture (-want, +got):", diff)
+	}
+	if diff := cmp.Diff(cioperatorapi.ReleaseArchitecture("amd64"), amd64.Tests[0].ClusterClaim.Architecture); diff != "" {
+		t.Error("amd64 cluster claim architecture (-want, +got):", diff)
+	}
+	arm64Test := arm64.Tests[0]
+	if arm64Test.ClusterClaim != nil {
+		t.Errorf("unexpected cluster claim for arm64 %+v", arm64Test.ClusterClaim)
+	}
+	if got := arm64Test.MultiStageTestConfiguration.Environment["OCP_ARCH"]; got != "arm64" {
+		t.Errorf("want OCP_ARCH=arm64, got %q", got)
+	}
+	if got := *arm64Test.MultiStageTestConfiguration.Workflow; got != "ipi-aws" {
+		t.Errorf("want ipi-aws workflow, got %q", got)
+	}
+	if amd64.Tests[0].As != arm64Test.As {
+		t.Errorf("expected the same test names across architectures, got %q and %q", amd64.Tests[0].As, arm64Test.As)
+	}
+}
+
+func TestOpenShiftArchitecturesErrors(t *testing.T) {
+	for _, ov := range []OpenShift{
+		{Version: "4.14", Architectures: []Architecture{{Name: "amd64"}}},
+		{Version: "4.14", Architectures: []Architecture{{Name: "s390x"}}},
+		{Version: "4.14", Architectures: []Architecture{{Name: "arm64"}, {Name: "arm64"}}},
+	} {
+		if _, err := ov.architectures(); err == nil {
+			t.Errorf("expected error for %+v", ov.Architectures)
+		}
+	}
+}
+
+func TestNewGenerateConfigsArchitecturesCron(t *testing.T) {
+	r := Repository{
+		Org:         "testorg",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests:    []E2ETest{{Match: "test-e2e$"}},
+	}
+
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, r.Org, r.Repo+".git"), "release-next")
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	crons := func(architectures ...Architecture) map[string]string {
+		cc := CommonConfig{
+			Branches: map[string]Branch{
+				"release-next": {
+					OpenShiftVersions: []OpenShift{{Version: "4.14", Architectures: architectures}, {Version: "4.15"}},
+				},
+			},
+		}
+		cfgs, err := NewGenerateConfigs(ctx, r, cc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, cfg := range cfgs {
+			for _, test := range cfg.Tests {
+				if test.Cron != nil {
+					got[cfg.Metadata.Variant+"/"+test.As] = *test.Cron
+				}
+			}
+		}
+		return got
+	}
+
+	want := crons()
+	if len(want) == 0 {
+		t.Fatal("expected periodic tests")
+	}
+	// Adding an architecture only adds jobs, the schedules of the existing ones don't change.
+	got := crons(Architecture{Name: "arm64"})
+	if len(got) <= len(want) {
+		t.Errorf("expected periodic tests for arm64, got %v", got)
+	}
+	for k, cron := range want {
+		if diff := cmp.Diff(cron, got[k]); diff != "" {
+			t.Errorf("%s: cron (-want, +got): %s", k, diff)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package prowgen
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+// CacheManifestName is the name of the cache manifest, stored in the workspace root by default.
+const CacheManifestName = ".prowgen-cache.json"
+
+// CacheManifest records the inputs and outputs of the last generation of each repository
+// branch, unchanged repositories are not generated again in subsequent runs.
+type CacheManifest struct {
+	// Generator identifies the prowgen build, the cache is invalidated when prowgen changes.
+	Generator string `json:"generator"`
+	// Branches are indexed by <org>/<repo>@<branch>.
+	Branches map[string]CacheEntry `json:"branches"`
+}
+
+type CacheEntry struct {
+	// Commit is the commit SHA of the branch.
+	Commit string `json:"commit"`
+	// ConfigHash is the hash of the effective repository and branch configuration.
+	ConfigHash string      `json:"configHash"`
+	Files      []CacheFile `json:"files,omitempty"`
+}
+
+type CacheFile struct {
+	// Path is the generated ci-operator configuration, relative to the output directory.
+	Path           string          `json:"path"`
+	SHA256         string          `json:"sha256"`
+	ScheduledTests []string        `json:"scheduledTests,omitempty"`
+	UnmappedImages []UnmappedImage `json:"unmappedImages,omitempty"`
+	// ReporterConfigs are the Slack reporter configs of the reported tests, by test name.
+	ReporterConfigs map[string]ReporterConfig `json:"reporterConfigs,omitempty"`
+}
+
+// LoadCacheManifest loads the cache manifest at path, a missing manifest is empty.
+func LoadCacheManifest(path string) (*CacheManifest, error) {
+	m := &CacheManifest{Branches: map[string]CacheEntry{}}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache manifest %q: %w", path, err)
+	}
+	if err := json.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cache manifest %q: %w", path, err)
+	}
+	if m.Branches == nil {
+		m.Branches = map[string]CacheEntry{}
+	}
+	return m, nil
+}
+
+// Save writes the cache manifest to path.
+func (m *CacheManifest) Save(path string) error {
+	out, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), os.ModePerm)
+}
+
+func cacheKey(r Repository, branch string) string {
+	return r.RepositoryDirectory() + "@" + branch
+}
+
+// branchConfigHash returns the hash of the configuration affecting the generation of the branch.
+func branchConfigHash(r Repository, cc CommonConfig, branch Branch) (string, error) {
+	out, err := json.Marshal(struct {
+		Repository      Repository                `json:"repository"`
+		Branch          Branch                    `json:"branch"`
+		ClusterProfiles map[string]ClusterProfile `json:"clusterProfiles,omitempty"`
+	}{Repository: r, Branch: branch, ClusterProfiles: cc.ClusterProfiles})
+	if err != nil {
+		return "", err
+	}
+	return sha256Hex(out), nil
+}
+
+// generatorFingerprint returns the hash of the running executable.
+func generatorFingerprint() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// generatedBranches returns the branches generated for the configuration, in generation order.
+func generatedBranches(cc CommonConfig) []string {
+	var branches []string
+	for _, name := range sortedKeys(cc.Branches) {
+		if b := cc.Branches[name]; b.Prowgen != nil && b.Prowgen.Disabled {
+			continue
+		}
+		branches = append(branches, name)
+	}
+	return branches
+}
+
+// cacheRepositoryKey identifies a repository of a configuration file.
+type cacheRepositoryKey struct {
+	config     *Config
+	repository string
+}
+
+// generationCache reuses configurations of unchanged repositories and records the
+// generated ones.
+type generationCache struct {
+	path         string
+	outputConfig string
+	force        bool
+	manifest     *CacheManifest
+
+	// heads are the remote branches commit SHAs, by repository.
+	heads map[cacheRepositoryKey]map[string]string
+}
+
+func newGenerationCache(path string, outputConfig string, force bool) (*generationCache, error) {
+	generator, err := generatorFingerprint()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute generator fingerprint: %w", err)
+	}
+	manifest, err := LoadCacheManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Generator != generator {
+		manifest = &CacheManifest{Generator: generator, Branches: map[string]CacheEntry{}}
+	}
+	return &generationCache{
+		path:         path,
+		outputConfig: outputConfig,
+		force:        force,
+		manifest:     manifest,
+		heads:        map[cacheRepositoryKey]map[string]string{},
+	}, nil
+}
+
+// resolve returns the cached configurations of the repositories whose branches are all
+// unchanged, they must be resolved before existing configurations are deleted.
+//
+// A repository is reused only as a whole since cron schedules of a branch depend on the
+// branches generated before it.
+func (c *generationCache) resolve(ctx context.Context, inConfigs []*Config) map[cacheRepositoryKey][]ReleaseBuildConfiguration {
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, inConfig := range inConfigs {
+		for _, r := range inConfig.Repositories {
+			key := cacheRepositoryKey{config: inConfig, repository: r.RepositoryDirectory()}
+			wg.Go(func() {
+				heads, err := GitRemoteHeads(ctx, r)
+				if err != nil {
+					// The repository is generated and the error reported, if any, by the generation.
+					log.Println("Skipping cache for", r.RepositoryDirectory(), err)
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				c.heads[key] = heads
+			})
+		}
+	}
+	wg.Wait()
+
+	cached := make(map[cacheRepositoryKey][]ReleaseBuildConfiguration)
+	if c.force {
+		return cached
+	}
+	for _, inConfig := range inConfigs {
+		for _, r := range inConfig.Repositories {
+			key := cacheRepositoryKey{config: inConfig, repository: r.RepositoryDirectory()}
+			cfgs, ok := c.lookup(r, inConfig.Config, c.heads[key])
+			if !ok {
+				continue
+			}
+			log.Println("Repository", r.RepositoryDirectory(), "is unchanged, reusing cached configurations")
+			cached[key] = cfgs
+		}
+	}
+	return cached
+}
+
+func (c *generationCache) lookup(r Repository, cc CommonConfig, heads map[string]string) ([]ReleaseBuildConfiguration, bool) {
+	if heads == nil {
+		return nil, false
+	}
+	var cfgs []ReleaseBuildConfiguration
+	for _, branch := range generatedBranches(cc) {
+		entry, ok := c.manifest.Branches[cacheKey(r, branch)]
+		if !ok || heads[branch] == "" || entry.Commit != heads[branch] {
+			return nil, false
+		}
+		hash, err := branchConfigHash(r, cc, cc.Branches[branch])
+		if err != nil || hash != entry.ConfigHash {
+			return nil, false
+		}
+		for _, f := range entry.Files {
+			content, err := os.ReadFile(filepath.Join(c.outputConfig, f.Path))
+			if err != nil || sha256Hex(content) != f.SHA256 {
+				return nil, false
+			}
+			cfg := ReleaseBuildConfiguration{
+				Path:            f.Path,
+				Branch:          branch,
+				ScheduledTests:  f.ScheduledTests,
+				UnmappedImages:  f.UnmappedImages,
+				ReporterConfigs: f.ReporterConfigs,
+			}
+			if err := yaml.Unmarshal(content, &cfg.ReleaseBuildConfiguration); err != nil {
+				return nil, false
+			}
+			cfgs = append(cfgs, cfg)
+		}
+	}
+	return cfgs, true
+}
+
+// update records the generated configurations of the repositories without errors and saves
+// the manifest.
+func (c *generationCache) update(generated []generatedRepository) error {
+	for _, g := range generated {
+		heads, ok := c.heads[cacheRepositoryKey{config: g.Config, repository: g.Repository.RepositoryDirectory()}]
+		if g.Err != nil || !ok {
+			continue
+		}
+		files := make(map[string][]CacheFile)
+		for _, cfg := range g.Configs {
+			out, err := marshalReleaseBuildConfiguration(cfg)
+			if err != nil {
+				return err
+			}
+			files[cfg.Branch] = append(files[cfg.Branch], CacheFile{
+				Path:            cfg.Path,
+				SHA256:          sha256Hex(out),
+				ScheduledTests:  cfg.ScheduledTests,
+				UnmappedImages:  cfg.UnmappedImages,
+				ReporterConfigs: cfg.ReporterConfigs,
+			})
+		}
+		for _, branch := range generatedBranches(g.Config.Config) {
+			if heads[branch] == "" {
+				continue
+			}
+			hash, err := branchConfigHash(g.Repository, g.Config.Config, g.Config.Config.Branches[branch])
+			if err != nil {
+				return err
+			}
+			c.manifest.Branches[cacheKey(g.Repository, branch)] = CacheEntry{
+				Commit:     heads[branch],
+				ConfigHash: hash,
+				Files:      files[branch],
+			}
+		}
+	}
+	return c.manifest.Save(c.path)
+}
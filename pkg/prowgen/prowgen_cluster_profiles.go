@@ -0,0 +1,111 @@
+package prowgen
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+// ClusterProfile configures how clusters for tests are provisioned, either by claiming
+// a cluster from an existing cluster pool (see OpenShift.UseClusterPool) or by starting
+// a new cluster from scratch.
+type ClusterProfile struct {
+	// Cloud is the cloud of the cluster pool, for example, aws, gcp or azure.
+	Cloud string `json:"cloud,omitempty" yaml:"cloud,omitempty"`
+	// ClaimOwner is the owner of the cluster pool.
+	ClaimOwner string `json:"claimOwner,omitempty" yaml:"claimOwner,omitempty"`
+	// ClaimTimeout is the maximum time to wait for a cluster from the pool.
+	ClaimTimeout *prowapi.Duration `json:"claimTimeout,omitempty" yaml:"claimTimeout,omitempty"`
+
+	// Workflow is the step registry workflow starting new clusters, for example, ipi-aws.
+	Workflow string `json:"workflow,omitempty" yaml:"workflow,omitempty"`
+	// ClusterProfile is the openshift/release cluster profile holding the cloud credentials.
+	ClusterProfile string `json:"clusterProfile,omitempty" yaml:"clusterProfile,omitempty"`
+	// Env is the environment of tests starting new clusters.
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	// Deprovision is the post step destroying new clusters, it defaults to ipi-deprovision-deprovision
+	// for ipi-* workflows and it's required for any other workflow.
+	Deprovision *StepReference `json:"deprovision,omitempty" yaml:"deprovision,omitempty"`
+}
+
+// StepReference references a step or a chain in the openshift/release step registry.
+type StepReference struct {
+	Ref   string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Chain string `json:"chain,omitempty" yaml:"chain,omitempty"`
+}
+
+const (
+	// defaultClusterProfileName is the cluster profile used when OpenShift versions and tests
+	// don't select one, it can be overridden in CommonConfig.ClusterProfiles.
+	defaultClusterProfileName = "aws"
+	// ipiDeprovisionStep is the default deprovision step for ipi-* workflows.
+	ipiDeprovisionStep = "ipi-deprovision-deprovision"
+)
+
+// defaultClusterProfile provisions clusters in the rh-serverless AWS account.
+var defaultClusterProfile = ClusterProfile{
+	Cloud:        string(cioperatorapi.CloudAWS),
+	ClaimOwner:   clusterPoolOwner,
+	ClaimTimeout: &prowapi.Duration{Duration: 2 * time.Hour},
+	Workflow:     "ipi-aws",
+	// References the existing cluster profile in CI.
+	ClusterProfile: serverlessClusterProfile,
+	Env: map[string]string{
+		"BASE_DOMAIN": devclusterBaseDomain,
+		// Use single zone to save costs. See https://red.ht/3Y8g7Ar
+		"ZONES_COUNT":    "1",
+		"SPOT_INSTANCES": "true",
+	},
+}
+
+// selectClusterProfile returns the first selected cluster profile in the given names, by precedence,
+// or the default one.
+func selectClusterProfile(profiles map[string]ClusterProfile, names ...string) (string, ClusterProfile, error) {
+	name := defaultClusterProfileName
+	for _, n := range names {
+		if n != "" {
+			name = n
+			break
+		}
+	}
+	if p, ok := profiles[name]; ok {
+		return name, p, nil
+	}
+	if name == defaultClusterProfileName {
+		return name, defaultClusterProfile, nil
+	}
+	return name, ClusterProfile{}, fmt.Errorf("cluster profile %q not found", name)
+}
+
+// validate returns an error if the profile can't be used to start new clusters.
+func (p ClusterProfile) validate(name string) error {
+	if _, err := p.deprovisionStep(); err != nil {
+		return fmt.Errorf("cluster profile %q: %w", name, err)
+	}
+	if p.Deprovision != nil && (p.Deprovision.Ref == "") == (p.Deprovision.Chain == "") {
+		return fmt.Errorf("cluster profile %q: deprovision must have exactly one of ref or chain", name)
+	}
+	return nil
+}
+
+// deprovisionStep returns the post step destroying new clusters.
+func (p ClusterProfile) deprovisionStep() (cioperatorapi.TestStep, error) {
+	if p.Deprovision != nil {
+		return p.Deprovision.testStep(), nil
+	}
+	if strings.HasPrefix(p.Workflow, "ipi-") {
+		return cioperatorapi.TestStep{Reference: pointer.String(ipiDeprovisionStep)}, nil
+	}
+	return cioperatorapi.TestStep{}, fmt.Errorf("deprovision is required for workflow %q", p.Workflow)
+}
+
+func (s StepReference) testStep() cioperatorapi.TestStep {
+	if s.Chain != "" {
+		return cioperatorapi.TestStep{Chain: pointer.String(s.Chain)}
+	}
+	return cioperatorapi.TestStep{Reference: pointer.String(s.Ref)}
+}
@@ -0,0 +1,209 @@
+package prowgen
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+func TestDiscoverTestsClusterProfiles(t *testing.T) {
+	ctx := context.Background()
+
+	r := Repository{
+		Org:         "testdata",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+	}
+
+	profiles := map[string]ClusterProfile{
+		"gcp": {
+			Cloud:          "gcp",
+			ClaimOwner:     "serverless-ci-gcp",
+			ClaimTimeout:   &prowapi.Duration{Duration: time.Hour},
+			Workflow:       "ipi-gcp",
+			ClusterProfile: "gcp-serverless",
+			Env:            map[string]string{"COMPUTE_NODE_TYPE": "n2-standard-8"},
+		},
+		"hypershift": {
+			Workflow:       "hypershift-hostedcluster-workflow",
+			ClusterProfile: "aws-serverless",
+			Deprovision:    &StepReference{Chain: "hypershift-dump"},
+		},
+	}
+
+	tcs := []struct {
+		name      string
+		e2e       []E2ETest
+		openShift OpenShift
+		want      map[string]cioperatorapi.TestStepConfiguration
+	}{
+		{
+			name: "fresh clusters",
+			e2e: []E2ETest{
+				{Match: "perf-tests$", SkipCron: true},
+				{Match: "test-e2e-tls$", SkipCron: true, ClusterProfile: "hypershift"},
+			},
+			openShift: OpenShift{Version: "4.16", ClusterProfile: "gcp"},
+			want: map[string]cioperatorapi.TestStepConfiguration{
+				"perf-tests": {
+					MultiStageTestConfiguration: &cioperatorapi.MultiStageTestConfiguration{
+						ClusterProfile: "gcp-serverless",
+						Environment:    cioperatorapi.TestEnvironment{"COMPUTE_NODE_TYPE": "n2-standard-8"},
+						Workflow:       pointer.String("ipi-gcp"),
+						Post:           []cioperatorapi.TestStep{{Reference: pointer.String("ipi-deprovision-deprovision")}},
+					},
+				},
+				"test-e2e-tls": {
+					MultiStageTestConfiguration: &cioperatorapi.MultiStageTestConfiguration{
+						ClusterProfile: "aws-serverless",
+						Workflow:       pointer.String("hypershift-hostedcluster-workflow"),
+						Post:           []cioperatorapi.TestStep{{Chain: pointer.String("hypershift-dump")}},
+					},
+				},
+			},
+		},
+		{
+			name:      "cluster pool",
+			e2e:       []E2ETest{{Match: "perf-tests$", SkipCron: true}},
+			openShift: OpenShift{Version: "4.16", ClusterProfile: "gcp", UseClusterPool: true},
+			want: map[string]cioperatorapi.TestStepConfiguration{
+				"perf-tests": {
+					ClusterClaim: &cioperatorapi.ClusterClaim{
+						Product:      cioperatorapi.ReleaseProductOCP,
+						Version:      "4.16",
+						Architecture: cioperatorapi.ReleaseArchitectureAMD64,
+						Cloud:        "gcp",
+						Owner:        "serverless-ci-gcp",
+						Timeout:      &prowapi.Duration{Duration: time.Hour},
+					},
+					MultiStageTestConfiguration: &cioperatorapi.MultiStageTestConfiguration{
+						Workflow: pointer.String("generic-claim"),
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := r
+			r.E2ETests = tc.e2e
+			random := rand.New(rand.NewSource(seed))
+			option := DiscoverTestsForArchitecture(ctx, r, tc.openShift, defaultArchitecture, profiles, nil, "knative-serving-source-image", nil, random)
+
+			cfg := cioperatorapi.ReleaseBuildConfiguration{}
+			if err := applyOptions(&cfg, option); err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]cioperatorapi.TestStepConfiguration, len(cfg.Tests))
+			for _, test := range cfg.Tests {
+				var post []cioperatorapi.TestStep
+				for _, step := range test.MultiStageTestConfiguration.Post {
+					// Ignore must-gather steps.
+					if step.LiteralTestStep == nil {
+						post = append(post, step)
+					}
+				}
+				got[test.As] = cioperatorapi.TestStepConfiguration{
+					ClusterClaim: test.ClusterClaim,
+					MultiStageTestConfiguration: &cioperatorapi.MultiStageTestConfiguration{
+						ClusterProfile: test.MultiStageTestConfiguration.ClusterProfile,
+						Environment:    test.MultiStageTestConfiguration.Environment,
+						Workflow:       test.MultiStageTestConfiguration.Workflow,
+						Post:           post,
+					},
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error("tests (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestDiscoverTestsClusterProfilesErrors(t *testing.T) {
+	ctx := context.Background()
+
+	r := Repository{
+		Org:         "testdata",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests:    []E2ETest{{Match: "perf-tests$"}},
+	}
+	profiles := map[string]ClusterProfile{
+		"hypershift": {
+			Workflow:    "hypershift-hostedcluster-workflow",
+			Deprovision: &StepReference{Chain: "hypershift-dump"},
+		},
+	}
+
+	tcs := []struct {
+		name      string
+		openShift OpenShift
+	}{
+		{
+			name:      "unknown cluster profile",
+			openShift: OpenShift{Version: "4.16", ClusterProfile: "azure"},
+		},
+		{
+			name:      "cluster pool without cloud and owner",
+			openShift: OpenShift{Version: "4.16", ClusterProfile: "hypershift", UseClusterPool: true},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			option := DiscoverTestsForArchitecture(ctx, r, tc.openShift, defaultArchitecture, profiles, nil, "knative-serving-source-image", nil, rand.New(rand.NewSource(seed)))
+
+			cfg := cioperatorapi.ReleaseBuildConfiguration{}
+			if err := applyOptions(&cfg, option); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestClusterProfileValidate(t *testing.T) {
+	tcs := []struct {
+		name    string
+		profile ClusterProfile
+		wantErr bool
+	}{
+		{
+			name:    "default",
+			profile: defaultClusterProfile,
+		},
+		{
+			name:    "ipi workflow",
+			profile: ClusterProfile{Workflow: "ipi-azure"},
+		},
+		{
+			name:    "custom workflow without deprovision",
+			profile: ClusterProfile{Workflow: "hypershift-hostedcluster-workflow"},
+			wantErr: true,
+		},
+		{
+			name:    "custom workflow with deprovision",
+			profile: ClusterProfile{Workflow: "hypershift-hostedcluster-workflow", Deprovision: &StepReference{Ref: "hypershift-destroy"}},
+		},
+		{
+			name:    "deprovision with ref and chain",
+			profile: ClusterProfile{Workflow: "ipi-aws", Deprovision: &StepReference{Ref: "a", Chain: "b"}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.profile.validate(tc.name)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("wantErr %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
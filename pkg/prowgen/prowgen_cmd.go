@@ -3,50 +3,105 @@ package prowgen
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"strings"
+	"time"
 )
 
-func runNoRepo(ctx context.Context, name string, args ...string) ([]byte, error) {
-	var buf bytes.Buffer
+// Command is a command executed by an Executor.
+type Command struct {
+	Name string
+	Args []string
+	// Dir is the working directory, the current working directory when empty.
+	Dir string
+}
 
-	select {
-	case <-ctx.Done():
-		return buf.Bytes(), ctx.Err()
-	default:
-	}
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
 
-	cmd := exec.Command(name, args...)
+// Executor executes commands, every command run by Run (git, make, kubectl, ...) goes through
+// the configured executor, see SetExecutor.
+type Executor interface {
+	// Execute runs the command and returns its standard output.
+	Execute(ctx context.Context, cmd Command) ([]byte, error)
+}
 
-	cmd.Stdout = io.MultiWriter(os.Stdout, &buf)
-	cmd.Stderr = os.Stderr
+// processWaitDelay bounds the wait for the output of a killed process.
+const processWaitDelay = 5 * time.Second
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to run %s %v: %w", name, args, err)
+// OSExecutor executes commands as processes, their output is copied to Stdout and Stderr.
+// When the context is done the process is killed together with its children.
+type OSExecutor struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewOSExecutor returns an OSExecutor copying the output of commands to os.Stdout and os.Stderr.
+func NewOSExecutor() *OSExecutor {
+	return &OSExecutor{Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+func (e *OSExecutor) Execute(ctx context.Context, c Command) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
+	var buf bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Stdout = &buf
+	if e.Stdout != nil {
+		cmd.Stdout = io.MultiWriter(e.Stdout, &buf)
+	}
+	cmd.Stderr = e.Stderr
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = processWaitDelay
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+			err = errors.Join(err, ctxErr)
+		}
+		return nil, err
+	}
 	return buf.Bytes(), nil
 }
 
-func Run(ctx context.Context, r Repository, name string, args ...string) ([]byte, error) {
-	var buf bytes.Buffer
+var executor Executor = NewOSExecutor()
 
-	select {
-	case <-ctx.Done():
-		return buf.Bytes(), ctx.Err()
-	default:
+// SetExecutor configures the executor of commands, nil restores the default OSExecutor.
+// It is not safe to call it concurrently with any command.
+func SetExecutor(e Executor) {
+	if e == nil {
+		e = NewOSExecutor()
 	}
+	executor = e
+}
 
-	cmd := exec.Command(name, args...)
+// Execute executes the command with the configured executor, see SetExecutor.
+func Execute(ctx context.Context, cmd Command) ([]byte, error) {
+	return executor.Execute(ctx, cmd)
+}
 
-	cmd.Dir = r.RepositoryDirectory()
-	cmd.Stdout = io.MultiWriter(os.Stdout, &buf)
-	cmd.Stderr = os.Stderr
+func runNoRepo(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := executor.Execute(ctx, Command{Name: name, Args: args})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s %v: %w", name, args, err)
+	}
+	return out, nil
+}
 
-	if err := cmd.Run(); err != nil {
+func Run(ctx context.Context, r Repository, name string, args ...string) ([]byte, error) {
+	out, err := executor.Execute(ctx, Command{Name: name, Args: args, Dir: r.LocalDirectory(ctx)})
+	if err != nil {
 		return nil, fmt.Errorf("[%s] failed to run %s %v: %w", r.RepositoryDirectory(), name, args, err)
 	}
-	return buf.Bytes(), nil
+	return out, nil
 }
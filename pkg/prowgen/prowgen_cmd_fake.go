@@ -0,0 +1,98 @@
+package prowgen
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// FakeExecutor is an Executor recording the executed commands and replaying canned outputs,
+// it allows testing the orchestration of commands without network and real binaries.
+type FakeExecutor struct {
+	mu          sync.Mutex
+	invocations []Command
+	responses   []FakeResponse
+}
+
+// FakeResponse is the canned result of the commands it matches.
+type FakeResponse struct {
+	// Name and Args match commands with the same name whose arguments start with Args.
+	Name string
+	Args []string
+	// Dir, when not empty, matches commands running in the same directory.
+	Dir string
+
+	Output []byte
+	Err    error
+	// Run, when not nil, is called with the command before returning, for example, to create
+	// the files a command would create.
+	Run func(cmd Command) error
+}
+
+// NewFakeExecutor returns a FakeExecutor replaying the given responses, commands that don't
+// match any response succeed without output.
+func NewFakeExecutor(responses ...FakeResponse) *FakeExecutor {
+	return &FakeExecutor{responses: responses}
+}
+
+// On adds a response for the commands with the given name whose arguments start with args,
+// responses added earlier take precedence.
+func (f *FakeExecutor) On(output string, err error, name string, args ...string) *FakeExecutor {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, FakeResponse{Name: name, Args: args, Output: []byte(output), Err: err})
+	return f
+}
+
+func (f *FakeExecutor) Execute(ctx context.Context, cmd Command) ([]byte, error) {
+	f.mu.Lock()
+	f.invocations = append(f.invocations, Command{Name: cmd.Name, Args: slices.Clone(cmd.Args), Dir: cmd.Dir})
+	response, ok := f.match(cmd)
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	if response.Run != nil {
+		if err := response.Run(cmd); err != nil {
+			return nil, err
+		}
+	}
+	if response.Err != nil {
+		return nil, response.Err
+	}
+	return slices.Clone(response.Output), nil
+}
+
+func (f *FakeExecutor) match(cmd Command) (FakeResponse, bool) {
+	for _, r := range f.responses {
+		if r.Name != cmd.Name || (r.Dir != "" && r.Dir != cmd.Dir) {
+			continue
+		}
+		if len(r.Args) > len(cmd.Args) || !slices.Equal(r.Args, cmd.Args[:len(r.Args)]) {
+			continue
+		}
+		return r, true
+	}
+	return FakeResponse{}, false
+}
+
+// Invocations returns the executed commands, in order.
+func (f *FakeExecutor) Invocations() []Command {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.invocations)
+}
+
+// Commands returns the executed commands, in order, as strings (see Command.String).
+func (f *FakeExecutor) Commands() []string {
+	invocations := f.Invocations()
+	commands := make([]string, 0, len(invocations))
+	for _, c := range invocations {
+		commands = append(commands, c.String())
+	}
+	return commands
+}
@@ -0,0 +1,145 @@
+package prowgen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func withExecutor(t *testing.T, e Executor) {
+	t.Helper()
+	previous := executor
+	SetExecutor(e)
+	t.Cleanup(func() {
+		SetExecutor(previous)
+	})
+}
+
+func TestOSExecutor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	dir := t.TempDir()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	e := &OSExecutor{Stdout: stdout, Stderr: stderr}
+
+	out, err := e.Execute(context.Background(), Command{Name: "sh", Args: []string{"-c", "pwd; echo err >&2"}, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := filepath.EvalSymlinks(dir)
+	if got := strings.TrimSpace(string(out)); got != wd {
+		t.Errorf("expected output %q, got %q", wd, got)
+	}
+	if stdout.String() != string(out) {
+		t.Errorf("expected stdout to be copied, got %q", stdout.String())
+	}
+	if stderr.String() != "err\n" {
+		t.Errorf("expected stderr %q, got %q", "err\n", stderr.String())
+	}
+
+	if _, err := e.Execute(context.Background(), Command{Name: "sh", Args: []string{"-c", "exit 3"}}); err == nil {
+		t.Error("expected error for a failing command")
+	}
+}
+
+func TestOSExecutorCancelKillsProcessGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	// The child keeps the output open, the command returns before the wait delay only when the
+	// whole process group is killed.
+	_, err := (&OSExecutor{}).Execute(ctx, Command{Name: "sh", Args: []string{"-c", "sleep 30 & wait"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= processWaitDelay {
+		t.Errorf("expected the process group to be killed, command returned after %v", elapsed)
+	}
+
+	if _, err := (&OSExecutor{}).Execute(ctx, Command{Name: "true"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected commands not to start once the context is done, got %v", err)
+	}
+}
+
+func TestFakeExecutorPushBranch(t *testing.T) {
+	fake := NewFakeExecutor().
+		On("", errors.New("a branch named 'sync' already exists"), "git", "checkout", "-b").
+		On("", errors.New("nothing to commit"), "git", "commit").
+		On("", errors.New("rejected"), "git", "push")
+	withExecutor(t, fake)
+	ctx := withWorkspace(t, GitHubSource{}, "/workspace")
+
+	release := Repository{Org: "openshift", Repo: "release"}
+	remote := "git@github.com:fork/release.git"
+	err := PushBranch(ctx, release, &remote, "sync", "Sync")
+	if err == nil || !strings.Contains(err.Error(), "[openshift/release] failed to run git [push fork sync -f]: rejected") {
+		t.Errorf("expected push error, got %v", err)
+	}
+
+	want := []string{
+		"git checkout -b sync",
+		"git checkout sync",
+		"git add .",
+		"git commit -m Sync",
+		"git remote add fork " + remote,
+		"git push fork sync -f",
+	}
+	if diff := cmp.Diff(want, fake.Commands()); diff != "" {
+		t.Error("commands (-want, +got):", diff)
+	}
+	for _, c := range fake.Invocations() {
+		if c.Dir != release.LocalDirectory(ctx) {
+			t.Errorf("expected %q to run in %q, got %q", c, release.LocalDirectory(ctx), c.Dir)
+		}
+	}
+}
+
+func TestFakeExecutorGitMirror(t *testing.T) {
+	root := t.TempDir()
+	r := Repository{Org: "testorg", Repo: "serving"}
+	fake := NewFakeExecutor(FakeResponse{
+		Name: "git",
+		Args: []string{"clone", "--mirror"},
+		Run: func(cmd Command) error {
+			// Create the clone, the next mirror is a no-op.
+			return os.MkdirAll(cmd.Args[len(cmd.Args)-1], os.ModePerm)
+		},
+	}).On("* main\n  release-v1.15\n", nil, "git", "--no-pager", "branch")
+	withExecutor(t, fake)
+	ctx := withWorkspace(t, LocalSource{Root: "/mirrors"}, root)
+
+	branches, err := Branches(ctx, r, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := GitMirror(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"main", "release-v1.15"}, branches); diff != "" {
+		t.Error("branches (-want, +got):", diff)
+	}
+
+	want := []Command{
+		{Name: "git", Args: []string{"clone", "--mirror", "/mirrors/testorg/serving", filepath.Join(root, "testorg", "serving", ".git")}},
+		{Name: "git", Args: []string{"config", "--bool", "core.bare", "false"}, Dir: filepath.Join(root, "testorg", "serving")},
+		{Name: "git", Args: []string{"--no-pager", "branch", "--list", "*"}, Dir: filepath.Join(root, "testorg", "serving")},
+	}
+	if diff := cmp.Diff(want, fake.Invocations()); diff != "" {
+		t.Error("invocations (-want, +got):", diff)
+	}
+}
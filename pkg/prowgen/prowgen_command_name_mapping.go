@@ -15,17 +15,18 @@ const (
 func ToName(r Repository, test *Test) string {
 	continuousSuffix := "-c"
 	maxCommandLength := maxNameLength - len(continuousSuffix)
-	if len(test.Command) > maxCommandLength {
+	name := test.name()
+	if len(name) > maxCommandLength {
 		sha := test.HexSha() // guarantees uniqueness
-		prefix := test.Command[:maxCommandLength-len(sha)-1]
+		prefix := name[:maxCommandLength-len(sha)-1]
 		// OpenShift CI doesnt' like double dashes, such as `stable-latest-test-kafka--7465737-aws-ocp-412`.
 		// So, if the prefix of the command ends with a dash, we remove it.
 		prefix = strings.TrimSuffix(prefix, "-")
 		newTarget := prefix + "-" + sha
 		log.Println(r.RepositoryDirectory(), "command as test name is too long",
-			test.Command, "truncating it to", newTarget)
+			name, "truncating it to", newTarget)
 		return newTarget
 	}
 
-	return test.Command
+	return name
 }
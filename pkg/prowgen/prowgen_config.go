@@ -3,19 +3,17 @@ package prowgen
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"log"
 	"math/rand"
-	"os"
 	"path/filepath"
 	"slices"
 	"strings"
 
 	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/api/shardprowconfig"
-	"k8s.io/utils/ptr"
 	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
 	"sigs.k8s.io/prow/pkg/config"
-	"sigs.k8s.io/prow/pkg/git/types"
 
 	"github.com/openshift-knative/hack/pkg/util"
 )
@@ -36,6 +34,16 @@ type Repository struct {
 	Tests                 []cioperatorapi.TestStepConfiguration                       `json:"tests,omitempty" yaml:"tests,omitempty"`
 	Resources             cioperatorapi.ResourceConfiguration                         `json:"resources,omitempty" yaml:"resources,omitempty"`
 	Owners                Owners                                                      `json:"owners,omitempty" yaml:"owners,omitempty"`
+	// Steps are the pre and post steps of generated e2e tests, the default steps gather
+	// test logs and cluster state.
+	Steps *Steps `json:"steps,omitempty" yaml:"steps,omitempty"`
+	// Prow configures Tide and branch protection.
+	Prow *ProwConfig `json:"prow,omitempty" yaml:"prow,omitempty"`
+	// ImageMappings map external images referenced by Dockerfiles to image stream tags, they
+	// take precedence over the default mappings.
+	ImageMappings []ImageMapping `json:"imageMappings,omitempty" yaml:"imageMappings,omitempty"`
+	// SlackReporter configures the Slack notifications of the generated jobs, see SlackReporter.
+	SlackReporter *SlackReporter `json:"slackReporter,omitempty" yaml:"slackReporter,omitempty"`
 }
 
 type E2ETest struct {
@@ -48,6 +56,25 @@ type E2ETest struct {
 	SkipImages []string          `json:"skipImages,omitempty" yaml:"skipImages,omitempty"`
 	Timeout    *prowapi.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
 	JobTimeout *prowapi.Duration `json:"jobTimeout,omitempty" yaml:"jobTimeout,omitempty"`
+	// ClusterProfile selects the cluster profile (see CommonConfig.ClusterProfiles) for the test,
+	// it takes precedence over the OpenShift version one.
+	ClusterProfile string `json:"clusterProfile,omitempty" yaml:"clusterProfile,omitempty"`
+	// Steps overrides the repository and branch pre and post steps for the test.
+	Steps *Steps `json:"steps,omitempty" yaml:"steps,omitempty"`
+
+	// Env are additional environment variables for the test step.
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	// CommandTemplate is a Go template for the test step command, see TestCommandData for the
+	// available fields. It defaults to running the make target.
+	CommandTemplate string `json:"commandTemplate,omitempty" yaml:"commandTemplate,omitempty"`
+	// Resources overrides the test step resources, it defaults to 100m CPU request.
+	Resources *cioperatorapi.ResourceRequirements `json:"resources,omitempty" yaml:"resources,omitempty"`
+	// From overrides the image the test step runs in, it defaults to the source image.
+	From string `json:"from,omitempty" yaml:"from,omitempty"`
+	// ClaimTimeout overrides the cluster profile timeout for claiming a cluster from a pool.
+	ClaimTimeout *prowapi.Duration `json:"claimTimeout,omitempty" yaml:"claimTimeout,omitempty"`
+	// SlackReporter overrides the repository and branch Slack notifications for the test.
+	SlackReporter *SlackReporter `json:"slackReporter,omitempty" yaml:"slackReporter,omitempty"`
 }
 
 type Dockerfiles struct {
@@ -61,7 +88,9 @@ type IgnoreConfigs struct {
 
 type Promotion struct {
 	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
-	Template  string `json:"template,omitempty" yaml:"template,omitempty"`
+	// Template is the name or tag of the default promotion targets, knative-${version} by
+	// default, see OpenShiftPromotion for the available variables.
+	Template string `json:"template,omitempty" yaml:"template,omitempty"`
 }
 
 type CustomConfigs struct {
@@ -85,6 +114,10 @@ func (r Repository) RepositoryDirectory() string {
 }
 
 type Branch struct {
+	// Extends is the name of the profile (see CommonConfig.Profiles) this branch inherits from,
+	// fields set in the branch override the profile ones.
+	Extends string `json:"extends,omitempty" yaml:"extends,omitempty"`
+
 	Prowgen                *Prowgen    `json:"prowgen,omitempty" yaml:"prowgen,omitempty"`
 	Promotion              Promotion   `json:"promotion,omitempty" yaml:"promotion,omitempty"`
 	OpenShiftVersions      []OpenShift `json:"openShiftVersions,omitempty" yaml:"openShiftVersions,omitempty"`
@@ -95,6 +128,16 @@ type Branch struct {
 
 	// DependabotEnabled enabled if `nil`.
 	DependabotEnabled *bool `json:"dependabotEnabled,omitempty" yaml:"dependabotEnabled,omitempty"`
+
+	// Steps overrides the repository pre and post steps for the branch.
+	Steps *Steps `json:"steps,omitempty" yaml:"steps,omitempty"`
+
+	// UpgradeMatrix generates Serverless Operator upgrade tests, see UpgradeMatrix.
+	UpgradeMatrix *UpgradeMatrix `json:"upgradeMatrix,omitempty" yaml:"upgradeMatrix,omitempty"`
+
+	// SlackReporter overrides the repository Slack notifications for the branch, for example,
+	// to report presubmits.
+	SlackReporter *SlackReporter `json:"slackReporter,omitempty" yaml:"slackReporter,omitempty"`
 }
 
 type Konflux struct {
@@ -115,6 +158,10 @@ type Prowgen struct {
 }
 
 type OpenShift struct {
+	// VersionSet references a list of OpenShift versions in CommonConfig.OpenShiftVersionSets,
+	// the entry is replaced by the versions in the set and other fields set in the entry are
+	// applied to each version.
+	VersionSet     string `json:"versionSet,omitempty" yaml:"versionSet,omitempty"`
 	Version        string `json:"version,omitempty" yaml:"version,omitempty"`
 	UseClusterPool bool   `json:"useClusterPool,omitempty" yaml:"useClusterPool,omitempty"`
 	Cron           string `json:"cron,omitempty" yaml:"cron,omitempty"`
@@ -125,10 +172,59 @@ type OpenShift struct {
 	CandidateRelease bool                     `json:"candidateRelease,omitempty" yaml:"candidateRelease,omitempty"`
 	// SkipPromotion excludes this OpenShift version from promotion indexing.
 	SkipPromotion bool `json:"skipPromotion,omitempty" yaml:"skipPromotion,omitempty"`
+	// Promotion configures the promotion of the images built for this OpenShift version, see
+	// OpenShiftPromotion.
+	Promotion *OpenShiftPromotion `json:"promotion,omitempty" yaml:"promotion,omitempty"`
 	// SkipE2EMatches excludes e2e tests (by exact match on E2ETest.Match) from this OpenShift version.
 	SkipE2EMatches []string `json:"skipE2EMatches,omitempty" yaml:"skipE2EMatches,omitempty"`
 	// IncludeE2EMatches, if non-empty, limits this OpenShift version to only the listed e2e tests (by exact match on E2ETest.Match).
 	IncludeE2EMatches []string `json:"includeE2EMatches,omitempty" yaml:"includeE2EMatches,omitempty"`
+	// ClusterProfile selects the cluster profile (see CommonConfig.ClusterProfiles) for tests
+	// running on this OpenShift version.
+	ClusterProfile string `json:"clusterProfile,omitempty" yaml:"clusterProfile,omitempty"`
+	// Architectures generates an additional variant, named <version>-<architecture>, for each
+	// architecture on top of the default amd64 one.
+	Architectures []Architecture `json:"architectures,omitempty" yaml:"architectures,omitempty"`
+	// Variants generates an additional variant, named <version>-<name>, for each variant on top
+	// of the default one.
+	Variants []Variant `json:"variants,omitempty" yaml:"variants,omitempty"`
+}
+
+// Variant configures tests running on clusters with a different configuration, for example,
+// FIPS, proxy or disconnected clusters, tests are discovered like for the default variant.
+// Variants don't participate in promotion and don't generate custom configs.
+type Variant struct {
+	// Name is appended to the OpenShift version variant, for example, fips for 417-fips.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Env is added to the environment of tests starting clusters from scratch.
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	// UseClusterPool overrides OpenShift.UseClusterPool.
+	UseClusterPool *bool `json:"useClusterPool,omitempty" yaml:"useClusterPool,omitempty"`
+	// ClusterProfile selects the cluster profile (see CommonConfig.ClusterProfiles) for tests
+	// running on this variant, it takes precedence over the OpenShift version one.
+	ClusterProfile string `json:"clusterProfile,omitempty" yaml:"clusterProfile,omitempty"`
+	// SkipE2EMatches excludes e2e tests on top of OpenShift.SkipE2EMatches.
+	SkipE2EMatches []string `json:"skipE2EMatches,omitempty" yaml:"skipE2EMatches,omitempty"`
+	// IncludeE2EMatches, if non-empty, replaces OpenShift.IncludeE2EMatches.
+	IncludeE2EMatches []string `json:"includeE2EMatches,omitempty" yaml:"includeE2EMatches,omitempty"`
+	// Cron overrides OpenShift.Cron.
+	Cron string `json:"cron,omitempty" yaml:"cron,omitempty"`
+	// SkipCron ensures that no periodic jobs are generated for tests running on this variant.
+	SkipCron bool `json:"skipCron,omitempty" yaml:"skipCron,omitempty"`
+}
+
+// Architecture configures the tests running on clusters with a non-default architecture.
+// Architecture variants don't participate in promotion and don't generate custom configs.
+type Architecture struct {
+	// Name is the cluster architecture, either arm64 or multi.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// UseClusterPool overrides OpenShift.UseClusterPool, a cluster pool for the architecture must exist.
+	UseClusterPool *bool `json:"useClusterPool,omitempty" yaml:"useClusterPool,omitempty"`
+	// ClusterProfile selects the cluster profile (see CommonConfig.ClusterProfiles) for tests
+	// running on this architecture, it takes precedence over the OpenShift version one.
+	ClusterProfile string `json:"clusterProfile,omitempty" yaml:"clusterProfile,omitempty"`
+	// Env is added to the environment of tests starting clusters from scratch.
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
 }
 
 type CustomConfigsEnablement struct {
@@ -142,8 +238,23 @@ type Image struct {
 	PullSpec string `json:"pullSpec" yaml:"pullSpec"`
 }
 
+// openShiftVariant returns the ci-operator variant for the given OpenShift version, for example, 414.
+func openShiftVariant(ov OpenShift) string {
+	return strings.ReplaceAll(ov.Version, ".", "")
+}
+
 type CommonConfig struct {
 	Branches map[string]Branch `json:"branches,omitempty" yaml:"branches,omitempty"`
+
+	// Profiles are reusable branch configurations, see Branch.Extends.
+	Profiles map[string]Branch `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+	// OpenShiftVersionSets are reusable lists of OpenShift versions, see OpenShift.VersionSet.
+	OpenShiftVersionSets map[string][]OpenShift `json:"openShiftVersionSets,omitempty" yaml:"openShiftVersionSets,omitempty"`
+
+	// ClusterProfiles are the available cluster profiles by name, the "aws" profile is
+	// used by default and, unless it's overridden, it provisions clusters in the
+	// rh-serverless AWS account.
+	ClusterProfiles map[string]ClusterProfile `json:"clusterProfiles,omitempty" yaml:"clusterProfiles,omitempty"`
 }
 
 type ReleaseBuildConfigurationOption func(cfg *cioperatorapi.ReleaseBuildConfiguration) error
@@ -153,17 +264,30 @@ type ProjectDirectoryImageBuildStepConfigurationFunc func() (cioperatorapi.Proje
 type ReleaseBuildConfiguration struct {
 	cioperatorapi.ReleaseBuildConfiguration
 
-	Path         string
-	Branch       string
-	SlackChannel string
+	Path   string
+	Branch string
+	// ReporterConfigs are the Slack reporter configs of the reported tests, by test name.
+	ReporterConfigs map[string]ReporterConfig
+	// ScheduledTests are the periodic tests using the default cron schedule, which can be
+	// rescheduled by ScheduleConfigs.
+	ScheduledTests []string
+	// UnmappedImages are the external images referenced by Dockerfiles that ci-operator can't
+	// resolve.
+	UnmappedImages []UnmappedImage
+}
+
+// configRandom returns the random numbers of the cron schedules of the tests with the given key
+// (branch, variant and, optionally, the group of tests).
+// Use the same seed to always get the same sequence of random numbers for the same key, every
+// key has its own source so that adding a branch, a variant or tests doesn't change the cron
+// schedules of the existing jobs.
+func configRandom(r Repository, key ...string) *rand.Rand {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strings.Join(append([]string{r.RepositoryDirectory()}, key...), "/")))
+	return rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
 }
 
 func NewGenerateConfigs(ctx context.Context, r Repository, cc CommonConfig, opts ...ReleaseBuildConfigurationOption) ([]ReleaseBuildConfiguration, error) {
-	// Use the same seed to always get the same sequence of random
-	// numbers for tests within the given repository. It means the cron schedules
-	// for jobs will change less often when generating jobs.
-	random := rand.New(rand.NewSource(seed))
-
 	cfgs := make([]ReleaseBuildConfiguration, 0, len(cc.Branches)*2)
 
 	if err := GitMirror(ctx, r); err != nil {
@@ -192,210 +316,255 @@ func NewGenerateConfigs(ctx context.Context, r Repository, cc CommonConfig, opts
 			return nil, fmt.Errorf("[%s] failed to checkout branch %s", r.RepositoryDirectory(), branchName)
 		}
 
-		openshiftVersions := branch.OpenShiftVersions
-
-		promotionIndex := 0
-		for _, ov := range openshiftVersions {
-			log.Println(r.RepositoryDirectory(), "Generating config", branchName, "OpenShiftVersion", ov)
-
-			variant := strings.ReplaceAll(ov.Version, ".", "")
-
-			images := make([]cioperatorapi.ProjectDirectoryImageBuildStepConfiguration, 0, len(r.Images))
-			for _, img := range r.Images {
-				images = append(images, *img.DeepCopy())
-			}
-
-			tests := make([]cioperatorapi.TestStepConfiguration, 0, len(r.Tests))
-			for _, test := range r.Tests {
-				tests = append(tests, *test.DeepCopy())
-			}
-
-			resources := make(cioperatorapi.ResourceConfiguration, 1)
-			resources["*"] = cioperatorapi.ResourceRequirements{
-				Requests: map[string]string{
-					"cpu":    "500m",
-					"memory": "1Gi",
-				},
-			}
-			for k, v := range r.Resources {
-				resources[k] = v
-			}
+		unmappedImages, err := discoverUnmappedImages(ctx, r, branch.SkipDockerFilesMatches)
+		if err != nil {
+			return nil, err
+		}
 
-			metadata := cioperatorapi.Metadata{
-				Org:     r.Org,
-				Repo:    r.Repo,
-				Branch:  branchName,
-				Variant: variant,
-			}
-			buildRootImage := &cioperatorapi.BuildRootImageConfiguration{
-				ProjectImageBuild: &cioperatorapi.ProjectDirectoryImageBuildInputs{
-					DockerfilePath: "openshift/ci-operator/build-image/Dockerfile",
-				},
-			}
-			// Include releases as it's required by clusters that start from scratch (vs. cluster-pools).
-			releases := map[string]cioperatorapi.UnresolvedRelease{
-				"latest": {
-					Release: &cioperatorapi.Release{
-						Version: ov.Version,
-						Channel: cioperatorapi.ReleaseChannelFast},
-				},
-			}
-			if ov.CandidateRelease {
-				releases = map[string]cioperatorapi.UnresolvedRelease{
-					"latest": {
-						Candidate: &cioperatorapi.Candidate{
-							Version: ov.Version,
-							Stream:  "nightly",
-							ReleaseDescriptor: cioperatorapi.ReleaseDescriptor{
-								Product: "ocp",
-							},
-						}},
-				}
-			}
+		openshiftVersions := branch.OpenShiftVersions
 
-			cfg := cioperatorapi.ReleaseBuildConfiguration{
-				Metadata: metadata,
-				InputConfiguration: cioperatorapi.InputConfiguration{
-					BuildRootImage: buildRootImage,
-					Releases:       releases,
-				},
-				CanonicalGoRepository: r.CanonicalGoRepository,
-				Images: cioperatorapi.ImageConfiguration{
-					Items: images,
-				},
-				Tests:     tests,
-				Resources: resources,
-			}
+		promotions, err := branchPromotionTargets(r, branchName, branch)
+		if err != nil {
+			return nil, err
+		}
 
-			options := make([]ReleaseBuildConfigurationOption, 0, len(opts))
-			copy(options, opts)
-			if !ov.SkipPromotion {
-				if promotionIndex == 0 {
-					options = append(options, withNamePromotion(r, branch, branchName))
-				} else if promotionIndex == 1 {
-					options = append(options, withTagPromotion(r, branch, branchName))
-				}
-				promotionIndex++
+		reporter := slackReporter(r, branch)
+		var e2eReporters map[string]*SlackReporter
+		if reporter != nil {
+			e2eReporters, err = e2eSlackReporters(ctx, r)
+			if err != nil {
+				return nil, err
 			}
+		}
 
-			fromImage := srcImage
-			srcImageDockerfile, err := discoverSourceImageDockerfile(r)
+		var upgrades *upgradeMatrix
+		if branch.UpgradeMatrix != nil {
+			upgrades, err = loadUpgradeMatrix(ctx, r, branchName, *branch.UpgradeMatrix)
 			if err != nil {
 				return nil, err
 			}
-			if srcImageDockerfile != "" {
-				fromImage = toImage(r, ImageInput{
-					Context:        discoverImageContext(srcImageDockerfile),
-					DockerfilePath: strings.Join(strings.Split(srcImageDockerfile, string(os.PathSeparator))[2:], string(os.PathSeparator)),
-				})
-			}
-
-			options = append(
-				options,
-				DiscoverImages(r, branch.SkipDockerFilesMatches),
-				DiscoverTests(r, ov, fromImage, branch.SkipE2EMatches, random),
-			)
+		}
 
-			if !ov.OnDemand {
-				options = append(options,
-					SkipIfOnlyChanged(),
-					ImagesSkipIfOnlyChanged(),
-				)
-			} else {
-				options = append(options,
-					// onDemand jobs, should only run tests when needed / triggered manually
-					DisableAlwaysRunForTests(),
-					ImagesRunIfChangedHack(),
-				)
+		for i, ov := range openshiftVersions {
+			cvs, err := ov.configVariants()
+			if err != nil {
+				return nil, fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
 			}
+			for _, cv := range cvs {
+				ov, arch, variant := cv.openShift, cv.arch, cv.name
+				log.Println(r.RepositoryDirectory(), "Generating config", branchName, "OpenShiftVersion", ov, "variant", variant)
 
-			log.Println(r.RepositoryDirectory(), "Apply input options", len(options))
+				images := make([]cioperatorapi.ProjectDirectoryImageBuildStepConfiguration, 0, len(r.Images))
+				for _, img := range r.Images {
+					images = append(images, *img.DeepCopy())
+				}
 
-			if err := applyOptions(&cfg, options...); err != nil {
-				return nil, fmt.Errorf("[%s] failed to apply option: %w", r.RepositoryDirectory(), err)
-			}
+				tests := make([]cioperatorapi.TestStepConfiguration, 0, len(r.Tests))
+				for _, test := range r.Tests {
+					tests = append(tests, *test.DeepCopy())
+				}
 
-			log.Println("numTests", len(cfg.Tests), "numImages", len(cfg.Images.Items))
+				resources := make(cioperatorapi.ResourceConfiguration, 1)
+				resources["*"] = cioperatorapi.ResourceRequirements{
+					Requests: map[string]string{
+						"cpu":    "500m",
+						"memory": "1Gi",
+					},
+				}
+				for k, v := range r.Resources {
+					resources[k] = v
+				}
 
-			// openshift-knative/eventing-kafka-broker/openshift-knative-eventing-kafka-broker-release-next__411.yaml
-			buildConfigPath := filepath.Join(
-				r.RepositoryDirectory(),
-				r.Org+"-"+r.Repo+"-"+branchName+"__"+variant+".yaml",
-			)
+				metadata := cioperatorapi.Metadata{
+					Org:     r.Org,
+					Repo:    r.Repo,
+					Branch:  branchName,
+					Variant: variant,
+				}
+				buildRootImage := &cioperatorapi.BuildRootImageConfiguration{
+					ProjectImageBuild: &cioperatorapi.ProjectDirectoryImageBuildInputs{
+						DockerfilePath: "openshift/ci-operator/build-image/Dockerfile",
+					},
+				}
+				// Include releases as it's required by clusters that start from scratch (vs. cluster-pools).
+				releases := map[string]cioperatorapi.UnresolvedRelease{
+					"latest": {
+						Release: &cioperatorapi.Release{
+							Version: ov.Version,
+							Channel: cioperatorapi.ReleaseChannelFast},
+					},
+				}
+				if ov.CandidateRelease {
+					releases = map[string]cioperatorapi.UnresolvedRelease{
+						"latest": {
+							Candidate: &cioperatorapi.Candidate{
+								Version: ov.Version,
+								Stream:  "nightly",
+								ReleaseDescriptor: cioperatorapi.ReleaseDescriptor{
+									Product: "ocp",
+								},
+							}},
+					}
+				}
 
-			cfgs = append(cfgs, ReleaseBuildConfiguration{
-				ReleaseBuildConfiguration: cfg,
-				Path:                      buildConfigPath,
-				Branch:                    branchName,
-				SlackChannel:              r.SlackChannel,
-			})
+				cfg := cioperatorapi.ReleaseBuildConfiguration{
+					Metadata: metadata,
+					InputConfiguration: cioperatorapi.InputConfiguration{
+						BuildRootImage: buildRootImage,
+						Releases:       releases,
+					},
+					CanonicalGoRepository: r.CanonicalGoRepository,
+					Images: cioperatorapi.ImageConfiguration{
+						Items: images,
+					},
+					Tests:     tests,
+					Resources: resources,
+				}
 
-			if ov.CustomConfigs == nil || !ov.CustomConfigs.Enabled {
-				continue
-			}
+				options := make([]ReleaseBuildConfigurationOption, 0, len(opts))
+				copy(options, opts)
+				// Images are promoted only from the default variants.
+				if promotions[i] != nil && cv.primary {
+					options = append(options, withPromotion(*promotions[i]))
+				}
 
-			// Generate custom configs.
-			for _, customCfg := range r.CustomConfigs {
-				shouldInclude, err := shouldIncludeCustomConfig(ov, customCfg.Name)
+				fromImage := srcImage
+				srcImageDockerfile, err := discoverSourceImageDockerfile(ctx, r)
 				if err != nil {
 					return nil, err
 				}
-				if !shouldInclude {
-					continue
-				}
-				customBuildCfg := customCfg.ReleaseBuildConfiguration.DeepCopy()
-				customBuildCfg.Metadata = metadata
-				if customBuildCfg.BuildRootImage == nil {
-					customBuildCfg.BuildRootImage = buildRootImage
-				}
-				if customBuildCfg.CanonicalGoRepository == nil {
-					customBuildCfg.CanonicalGoRepository = r.CanonicalGoRepository
-				}
-				if len(customBuildCfg.Resources) == 0 {
-					customBuildCfg.Resources = resources
-				}
-				if len(customBuildCfg.Releases) == 0 {
-					customBuildCfg.Releases = releases
+				if srcImageDockerfile != "" {
+					fromImage = toImage(r, ImageInput{
+						Context:        discoverImageContext(srcImageDockerfile),
+						DockerfilePath: relativeToRepository(ctx, r, srcImageDockerfile),
+					})
 				}
 
-				customBuildOptions := append(
-					opts,
-					DiscoverImages(r, branch.SkipDockerFilesMatches),
-					DependenciesForTestSteps(),
-					// Custom build definitions are always on-demand only, that's also applied to image builds
-					ImagesRunIfChangedHack(),
+				options = append(
+					options,
+					DiscoverImages(ctx, r, branch.SkipDockerFilesMatches),
+					DiscoverTestsForArchitecture(ctx, r, ov, arch, cc.ClusterProfiles, branch.Steps, fromImage, branch.SkipE2EMatches, configRandom(r, branchName, variant)),
+					WithArchitecture(arch),
 				)
+				// Upgrade tests run only on the default variants.
+				if upgrades != nil && cv.primary {
+					options = append(options, WithTests(r, ov, arch, cc.ClusterProfiles, branch.Steps, fromImage, upgrades.tests(ov.Version), configRandom(r, branchName, variant, "upgrades")))
+				}
 
 				if !ov.OnDemand {
-					customBuildOptions = append(customBuildOptions,
+					options = append(options,
 						SkipIfOnlyChanged(),
+						ImagesSkipIfOnlyChanged(),
 					)
 				} else {
-					customBuildOptions = append(customBuildOptions,
+					options = append(options,
 						// onDemand jobs, should only run tests when needed / triggered manually
 						DisableAlwaysRunForTests(),
+						ImagesRunIfChangedHack(),
 					)
 				}
 
-				log.Println(r.RepositoryDirectory(), "Apply input options", len(customBuildOptions))
+				log.Println(r.RepositoryDirectory(), "Apply input options", len(options))
 
-				if err := applyOptions(customBuildCfg, customBuildOptions...); err != nil {
+				if err := applyOptions(&cfg, options...); err != nil {
 					return nil, fmt.Errorf("[%s] failed to apply option: %w", r.RepositoryDirectory(), err)
 				}
 
-				log.Println("numTests", len(customBuildCfg.Tests), "numImages", len(customBuildCfg.Images.Items))
+				log.Println("numTests", len(cfg.Tests), "numImages", len(cfg.Images.Items))
 
-				buildConfigPath = filepath.Join(
+				// openshift-knative/eventing-kafka-broker/openshift-knative-eventing-kafka-broker-release-next__411.yaml
+				buildConfigPath := filepath.Join(
 					r.RepositoryDirectory(),
-					r.Org+"-"+r.Repo+"-"+branchName+"__"+customCfg.Name+".yaml",
+					r.Org+"-"+r.Repo+"-"+branchName+"__"+variant+".yaml",
 				)
 
+				reporterCfgs, err := reporterConfigs(reporter, cfg.Tests, e2eReporters)
+				if err != nil {
+					return nil, fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
+				}
+
 				cfgs = append(cfgs, ReleaseBuildConfiguration{
-					ReleaseBuildConfiguration: *customBuildCfg,
+					ReleaseBuildConfiguration: cfg,
 					Path:                      buildConfigPath,
 					Branch:                    branchName,
-					SlackChannel:              r.SlackChannel,
+					ReporterConfigs:           reporterCfgs,
+					ScheduledTests:            scheduledTests(r, ov, cfg.Tests),
+					UnmappedImages:            unmappedImages,
 				})
+
+				if ov.CustomConfigs == nil || !ov.CustomConfigs.Enabled || !cv.primary {
+					continue
+				}
+
+				// Generate custom configs.
+				for _, customCfg := range r.CustomConfigs {
+					shouldInclude, err := shouldIncludeCustomConfig(ov, customCfg.Name)
+					if err != nil {
+						return nil, err
+					}
+					if !shouldInclude {
+						continue
+					}
+					customBuildCfg := customCfg.ReleaseBuildConfiguration.DeepCopy()
+					customBuildCfg.Metadata = metadata
+					if customBuildCfg.BuildRootImage == nil {
+						customBuildCfg.BuildRootImage = buildRootImage
+					}
+					if customBuildCfg.CanonicalGoRepository == nil {
+						customBuildCfg.CanonicalGoRepository = r.CanonicalGoRepository
+					}
+					if len(customBuildCfg.Resources) == 0 {
+						customBuildCfg.Resources = resources
+					}
+					if len(customBuildCfg.Releases) == 0 {
+						customBuildCfg.Releases = releases
+					}
+
+					customBuildOptions := append(
+						opts,
+						DiscoverImages(ctx, r, branch.SkipDockerFilesMatches),
+						DependenciesForTestSteps(),
+						// Custom build definitions are always on-demand only, that's also applied to image builds
+						ImagesRunIfChangedHack(),
+					)
+
+					if !ov.OnDemand {
+						customBuildOptions = append(customBuildOptions,
+							SkipIfOnlyChanged(),
+						)
+					} else {
+						customBuildOptions = append(customBuildOptions,
+							// onDemand jobs, should only run tests when needed / triggered manually
+							DisableAlwaysRunForTests(),
+						)
+					}
+
+					log.Println(r.RepositoryDirectory(), "Apply input options", len(customBuildOptions))
+
+					if err := applyOptions(customBuildCfg, customBuildOptions...); err != nil {
+						return nil, fmt.Errorf("[%s] failed to apply option: %w", r.RepositoryDirectory(), err)
+					}
+
+					log.Println("numTests", len(customBuildCfg.Tests), "numImages", len(customBuildCfg.Images.Items))
+
+					buildConfigPath = filepath.Join(
+						r.RepositoryDirectory(),
+						r.Org+"-"+r.Repo+"-"+branchName+"__"+customCfg.Name+".yaml",
+					)
+
+					reporterCfgs, err := reporterConfigs(reporter, customBuildCfg.Tests, nil)
+					if err != nil {
+						return nil, fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
+					}
+
+					cfgs = append(cfgs, ReleaseBuildConfiguration{
+						ReleaseBuildConfiguration: *customBuildCfg,
+						Path:                      buildConfigPath,
+						Branch:                    branchName,
+						ReporterConfigs:           reporterCfgs,
+						UnmappedImages:            unmappedImages,
+					})
+				}
 			}
 		}
 
@@ -406,87 +575,53 @@ func NewGenerateConfigs(ctx context.Context, r Repository, cc CommonConfig, opts
 	return cfgs, nil
 }
 
+// scheduledTests returns the generated periodic tests using the default cron schedule.
+func scheduledTests(r Repository, ov OpenShift, tests []cioperatorapi.TestStepConfiguration) []string {
+	if ov.Cron != "" {
+		return nil
+	}
+	var scheduled []string
+	for _, t := range tests {
+		isRepositoryTest := slices.ContainsFunc(r.Tests, func(rt cioperatorapi.TestStepConfiguration) bool {
+			return rt.As == t.As
+		})
+		if t.Cron != nil && !isRepositoryTest {
+			scheduled = append(scheduled, t.As)
+		}
+	}
+	return scheduled
+}
+
 // NewProwConfig returns a prow config for branch protection and tide config like https://github.com/openshift/release/blob/363307d181d1cf4734191bb794be20df54431b7d/core-services/prow/02_config/openshift-knative/eventing-integrations/_prowconfig.yaml
-func NewProwConfig(r Repository) shardprowconfig.ProwConfigWithPointers {
-	tideMissingLabels := []string{
-		"backports/unvalidated-commits",
-		"do-not-merge/hold",
-		"do-not-merge/invalid-owners-file",
-		"do-not-merge/work-in-progress",
-		"jira/invalid-bug",
-		"needs-rebase",
+// using the repository ProwConfig, cfgs are the generated configurations used to derive the
+// required contexts of release branches.
+func NewProwConfig(r Repository, cfgs ...ReleaseBuildConfiguration) (shardprowconfig.ProwConfigWithPointers, error) {
+	p := r.prowConfig()
+
+	branches, err := p.branches(cfgs)
+	if err != nil {
+		return shardprowconfig.ProwConfigWithPointers{}, fmt.Errorf("[%s] failed to generate branch protection: %w", r.RepositoryDirectory(), err)
 	}
 
-	branchProtection := &config.BranchProtection{
-		Orgs: map[string]config.Org{
-			r.Org: config.Org{
-				Repos: map[string]config.Repo{
-					r.Repo: config.Repo{
-						Branches: map[string]config.Branch{
-							"release-next": config.Branch{
-								Policy: config.Policy{
-									Protect: ptr.To(false),
-								},
-							},
-							"release-next-ci": config.Branch{
-								Policy: config.Policy{
-									Protect: ptr.To(false),
-								},
-							},
+	var branchProtection *config.BranchProtection
+	if len(branches) > 0 {
+		branchProtection = &config.BranchProtection{
+			Orgs: map[string]config.Org{
+				r.Org: {
+					Repos: map[string]config.Repo{
+						r.Repo: {
+							Branches: branches,
 						},
 					},
 				},
 			},
-		},
-	}
-
-	if r.IsServerlessOperator() {
-		// SO does not have release-next nor release-next-ci branches
-		branchProtection = nil
+		}
 	}
 
 	return shardprowconfig.ProwConfigWithPointers{
 		BranchProtection: branchProtection,
-		Tide: &shardprowconfig.TideConfig{
-			MergeType: map[string]types.PullRequestMergeType{
-				fmt.Sprintf("%s/%s", r.Org, r.Repo): types.MergeSquash,
-			},
-			Queries: config.TideQueries{
-				config.TideQuery{
-					Labels: []string{
-						"approved",
-						"lgtm",
-					},
-					MissingLabels: tideMissingLabels,
-					Repos: []string{
-						fmt.Sprintf("%s/%s", r.Org, r.Repo),
-					},
-				},
-				config.TideQuery{
-					Labels: []string{
-						"skip-review",
-					},
-					MissingLabels: tideMissingLabels,
-					Repos: []string{
-						fmt.Sprintf("%s/%s", r.Org, r.Repo),
-					},
-					Author:                 "red-hat-konflux-kflux-prd-rh02[bot]",
-					ReviewApprovedRequired: false,
-				},
-				config.TideQuery{
-					Labels: []string{
-						"skip-review",
-					},
-					MissingLabels: tideMissingLabels,
-					Repos: []string{
-						fmt.Sprintf("%s/%s", r.Org, r.Repo),
-					},
-					Author:                 "serverless-qe",
-					ReviewApprovedRequired: false,
-				},
-			},
-		},
-	}
+		Tide:             p.tide(fmt.Sprintf("%s/%s", r.Org, r.Repo)),
+	}, nil
 }
 
 func shouldIncludeCustomConfig(ov OpenShift, customCfgName string) (bool, error) {
@@ -527,79 +662,6 @@ func transformLegacyKnativeSourceImageName(r Repository) string {
 	return srcImage
 }
 
-func withNamePromotion(r Repository, branch Branch, branchName string) ReleaseBuildConfigurationOption {
-	return func(cfg *cioperatorapi.ReleaseBuildConfiguration) error {
-		p := r.Promotion
-		if branch.Promotion.Namespace != "" {
-			p.Namespace = branch.Promotion.Namespace
-		}
-		if branch.Promotion.Template != "" {
-			p.Template = branch.Promotion.Template
-		}
-
-		ns := "openshift"
-		if p.Namespace != "" {
-			ns = p.Namespace
-		}
-		cfg.PromotionConfiguration = &cioperatorapi.PromotionConfiguration{
-			Targets: []cioperatorapi.PromotionTarget{
-				{
-					Namespace: ns,
-					Name:      createPromotionName(p, branchName),
-					AdditionalImages: map[string]string{
-						// Add source image
-						transformLegacyKnativeSourceImageName(r): "src",
-					},
-				},
-			},
-		}
-		return nil
-	}
-}
-
-func withTagPromotion(r Repository, branch Branch, branchName string) ReleaseBuildConfigurationOption {
-	return func(cfg *cioperatorapi.ReleaseBuildConfiguration) error {
-		p := r.Promotion
-		if branch.Promotion.Namespace != "" {
-			p.Namespace = branch.Promotion.Namespace
-		}
-		if branch.Promotion.Template != "" {
-			p.Template = branch.Promotion.Template
-		}
-
-		ns := "openshift"
-		if p.Namespace != "" {
-			ns = p.Namespace
-		}
-		cfg.PromotionConfiguration = &cioperatorapi.PromotionConfiguration{
-			Targets: []cioperatorapi.PromotionTarget{
-				{
-					Namespace:   ns,
-					Tag:         createPromotionName(p, branchName),
-					TagByCommit: false, // TODO: revisit this later
-					AdditionalImages: map[string]string{
-						// Add source image
-						transformLegacyKnativeSourceImageName(r): "src",
-					},
-				},
-			},
-		}
-		return nil
-	}
-}
-
-func createPromotionName(p Promotion, branchName string) string {
-	tpl := "knative-${version}"
-	if p.Template != "" {
-		tpl = p.Template
-	}
-	version := strings.Replace(branchName, "release-", "", 1)
-	if version == "next" {
-		version = "nightly"
-	}
-	return strings.ReplaceAll(tpl, "${version}", version)
-}
-
 func applyOptions(cfg *cioperatorapi.ReleaseBuildConfiguration, opts ...ReleaseBuildConfigurationOption) error {
 	for _, opt := range opts {
 		if err := opt(cfg); err != nil {
@@ -0,0 +1,165 @@
+package prowgen
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var (
+	// imageNameRegex matches valid image stream tag names.
+	imageNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+	envVarRegex    = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+	// reservedImageNames are the images of the pipeline image stream built by ci-operator.
+	reservedImageNames = sets.NewString("root", "src", "bin", "test-bin", "rpms")
+)
+
+// checkConsistency checks the generated configurations of each repository and the promotion
+// targets across repositories, inconsistencies are errors of the affected repositories.
+//
+// Repositories that failed to generate are skipped.
+func checkConsistency(generated []generatedRepository) {
+	errs := make([][]error, len(generated))
+	for i, g := range generated {
+		if g.Err != nil {
+			continue
+		}
+		for _, cfg := range g.Configs {
+			errs[i] = append(errs[i], checkConfigConsistency(cfg)...)
+		}
+	}
+
+	// Images promoted by different configurations to the same destination overwrite each other.
+	type promoted struct {
+		repository int
+		path       string
+	}
+	destinations := make(map[string]promoted)
+	for i, g := range generated {
+		if g.Err != nil {
+			continue
+		}
+		for _, cfg := range g.Configs {
+			for _, dst := range promotionDestinations(cfg.ReleaseBuildConfiguration) {
+				other, ok := destinations[dst]
+				if !ok {
+					destinations[dst] = promoted{repository: i, path: cfg.Path}
+					continue
+				}
+				if other.path == cfg.Path {
+					continue
+				}
+				err := fmt.Errorf("%s: image promoted to %s is also promoted by %s", cfg.Path, dst, other.path)
+				errs[i] = append(errs[i], err)
+				if other.repository != i {
+					errs[other.repository] = append(errs[other.repository], err)
+				}
+			}
+		}
+	}
+
+	for i := range generated {
+		if len(errs[i]) > 0 {
+			generated[i].Err = errors.Join(errs[i]...)
+		}
+	}
+}
+
+// checkConfigConsistency checks that images built by the configuration have unique names valid
+// for ci-operator and that the environment variables of test steps don't collide.
+func checkConfigConsistency(cfg ReleaseBuildConfiguration) []error {
+	var errs []error
+	errorf := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", cfg.Path, fmt.Sprintf(format, args...)))
+	}
+
+	dockerfiles := make(map[string]string, len(cfg.Images.Items))
+	envs := make(map[string]string, len(cfg.Images.Items))
+	for _, img := range cfg.Images.Items {
+		name := string(img.To)
+		switch {
+		case !imageNameRegex.MatchString(name):
+			errorf("invalid image name %q built from %s, expected at most 128 alphanumeric characters, '-', '_' or '.'", name, img.DockerfilePath)
+		case reservedImageNames.Has(name):
+			errorf("image name %q built from %s is reserved by ci-operator", name, img.DockerfilePath)
+		}
+
+		if other, ok := dockerfiles[name]; ok {
+			errorf("image %q is built from both %s and %s", name, other, img.DockerfilePath)
+			continue
+		}
+		dockerfiles[name] = img.DockerfilePath
+
+		// See dependenciesFromImages.
+		env := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		if !envVarRegex.MatchString(env) {
+			errorf("image %q built from %s results in the invalid environment variable %q", name, img.DockerfilePath, env)
+		}
+		if other, ok := envs[env]; ok {
+			errorf("images %q and %q result in the same environment variable %q", other, name, env)
+			continue
+		}
+		envs[env] = name
+	}
+
+	for _, test := range cfg.Tests {
+		if test.MultiStageTestConfiguration == nil {
+			continue
+		}
+		for _, step := range test.MultiStageTestConfiguration.Test {
+			if step.LiteralTestStep == nil {
+				continue
+			}
+			stepEnv := make(map[string]string)
+			for _, dep := range step.Dependencies {
+				stepEnv[dep.Env] = fmt.Sprintf("dependency on image %q", dep.Name)
+			}
+			for _, param := range step.Environment {
+				if other, ok := stepEnv[param.Name]; ok {
+					errorf("test %q step %q: environment variable %s collides with the %s", test.As, step.As, param.Name, other)
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// promotionDestinations returns the image stream tags, as <namespace>/<name>:<tag>, the
+// configuration promotes images to.
+func promotionDestinations(cfg cioperatorapi.ReleaseBuildConfiguration) []string {
+	if cfg.PromotionConfiguration == nil {
+		return nil
+	}
+	var destinations []string
+	for _, target := range cfg.PromotionConfiguration.Targets {
+		excluded := sets.NewString(target.ExcludedImages...)
+		if excluded.Has("*") {
+			excluded = sets.NewString()
+			for _, img := range cfg.Images.Items {
+				excluded.Insert(string(img.To))
+			}
+		}
+		images := sets.NewString()
+		for _, img := range cfg.Images.Items {
+			if !excluded.Has(string(img.To)) {
+				images.Insert(string(img.To))
+			}
+		}
+		for name := range target.AdditionalImages {
+			images.Insert(name)
+		}
+		for _, img := range images.List() {
+			if target.Name != "" {
+				destinations = append(destinations, fmt.Sprintf("%s/%s:%s", target.Namespace, target.Name, img))
+			} else {
+				destinations = append(destinations, fmt.Sprintf("%s/%s:%s", target.Namespace, img, target.Tag))
+			}
+		}
+	}
+	return destinations
+}
@@ -0,0 +1,147 @@
+package prowgen
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+)
+
+func TestCheckConfigConsistency(t *testing.T) {
+	image := func(to string, dockerfile string) cioperatorapi.ProjectDirectoryImageBuildStepConfiguration {
+		return cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{
+			To: cioperatorapi.PipelineImageStreamTagReference(to),
+			ProjectDirectoryImageBuildInputs: cioperatorapi.ProjectDirectoryImageBuildInputs{
+				DockerfilePath: dockerfile,
+			},
+		}
+	}
+
+	cfg := ReleaseBuildConfiguration{
+		Path: "openshift-knative/serving/openshift-knative-serving-release-next__414.yaml",
+		ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+			Images: cioperatorapi.ImageConfiguration{
+				Items: []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{
+					image("knative-serving-controller", "openshift/ci-operator/knative-images/controller/Dockerfile"),
+					image("knative-serving-controller", "openshift/ci-operator/knative-images/v2/controller/Dockerfile"),
+					image("knative-serving-queue", "openshift/ci-operator/knative-images/queue/Dockerfile"),
+					image("knative_serving_queue", "openshift/ci-operator/other/queue/Dockerfile"),
+					image("knative-serving-1.0", "openshift/ci-operator/knative-images/1.0/Dockerfile"),
+					image("src", "openshift/ci-operator/knative-images/src/Dockerfile"),
+					image("knative serving", "openshift/ci-operator/knative-images/knative serving/Dockerfile"),
+				},
+			},
+			Tests: []cioperatorapi.TestStepConfiguration{
+				{
+					As: "test-e2e",
+					MultiStageTestConfiguration: &cioperatorapi.MultiStageTestConfiguration{
+						Test: []cioperatorapi.TestStep{
+							{
+								LiteralTestStep: &cioperatorapi.LiteralTestStep{
+									As: "test",
+									Environment: []cioperatorapi.StepParameter{
+										{Name: "KNATIVE_SERVING_CONTROLLER", Default: pointer.String("quay.io/openshift-knative/controller")},
+										{Name: "SYSTEM_NAMESPACE", Default: pointer.String("knative-serving")},
+									},
+									Dependencies: []cioperatorapi.StepDependency{
+										{Name: "knative-serving-controller", Env: "KNATIVE_SERVING_CONTROLLER"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var got []string
+	for _, err := range checkConfigConsistency(cfg) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		`openshift-knative/serving/openshift-knative-serving-release-next__414.yaml: image "knative-serving-controller" is built from both openshift/ci-operator/knative-images/controller/Dockerfile and openshift/ci-operator/knative-images/v2/controller/Dockerfile`,
+		`openshift-knative/serving/openshift-knative-serving-release-next__414.yaml: images "knative-serving-queue" and "knative_serving_queue" result in the same environment variable "KNATIVE_SERVING_QUEUE"`,
+		`openshift-knative/serving/openshift-knative-serving-release-next__414.yaml: image "knative-serving-1.0" built from openshift/ci-operator/knative-images/1.0/Dockerfile results in the invalid environment variable "KNATIVE_SERVING_1.0"`,
+		`openshift-knative/serving/openshift-knative-serving-release-next__414.yaml: image name "src" built from openshift/ci-operator/knative-images/src/Dockerfile is reserved by ci-operator`,
+		`openshift-knative/serving/openshift-knative-serving-release-next__414.yaml: invalid image name "knative serving" built from openshift/ci-operator/knative-images/knative serving/Dockerfile, expected at most 128 alphanumeric characters, '-', '_' or '.'`,
+		`openshift-knative/serving/openshift-knative-serving-release-next__414.yaml: image "knative serving" built from openshift/ci-operator/knative-images/knative serving/Dockerfile results in the invalid environment variable "KNATIVE SERVING"`,
+		`openshift-knative/serving/openshift-knative-serving-release-next__414.yaml: test "test-e2e" step "test": environment variable KNATIVE_SERVING_CONTROLLER collides with the dependency on image "knative-serving-controller"`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+}
+
+func TestCheckConsistencyPromotion(t *testing.T) {
+	config := func(path string, target cioperatorapi.PromotionTarget, images ...string) ReleaseBuildConfiguration {
+		cfg := ReleaseBuildConfiguration{
+			Path: path,
+			ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+				PromotionConfiguration: &cioperatorapi.PromotionConfiguration{
+					Targets: []cioperatorapi.PromotionTarget{target},
+				},
+			},
+		}
+		for _, img := range images {
+			cfg.Images.Items = append(cfg.Images.Items, cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{
+				To: cioperatorapi.PipelineImageStreamTagReference(img),
+			})
+		}
+		return cfg
+	}
+
+	failed := errors.New("failed to clone")
+	generated := []generatedRepository{
+		{
+			Repository: Repository{Org: "openshift-knative", Repo: "serving"},
+			Configs: []ReleaseBuildConfiguration{
+				config("serving-release-v1.15__414.yaml", cioperatorapi.PromotionTarget{Namespace: "openshift", Name: "knative-v1.15"}, "knative-serving-controller", "knative-webhook"),
+				config("serving-release-v1.15__415.yaml", cioperatorapi.PromotionTarget{Namespace: "openshift", Tag: "knative-v1.15"}, "knative-serving-controller", "knative-webhook"),
+				// Promotes to the same name as release-v1.15.
+				config("serving-release-v1.16__414.yaml", cioperatorapi.PromotionTarget{Namespace: "openshift", Name: "knative-v1.15"}, "knative-serving-controller"),
+			},
+		},
+		{
+			Repository: Repository{Org: "openshift-knative", Repo: "eventing"},
+			Configs: []ReleaseBuildConfiguration{
+				config("eventing-release-v1.15__414.yaml", cioperatorapi.PromotionTarget{Namespace: "openshift", Name: "knative-v1.15", ExcludedImages: []string{"knative-eventing-test"}}, "knative-webhook", "knative-eventing-test"),
+			},
+		},
+		{
+			Repository: Repository{Org: "openshift-knative", Repo: "eventing-istio"},
+			Configs: []ReleaseBuildConfiguration{
+				config("eventing-istio-release-v1.15__414.yaml", cioperatorapi.PromotionTarget{Namespace: "openshift", Name: "knative-v1.15", ExcludedImages: []string{"*"}, AdditionalImages: map[string]string{"eventing-istio-src": "src"}}, "knative-webhook"),
+			},
+		},
+		{
+			Repository: Repository{Org: "openshift-knative", Repo: "client"},
+			Configs: []ReleaseBuildConfiguration{
+				config("client-release-v1.15__414.yaml", cioperatorapi.PromotionTarget{Namespace: "openshift", Name: "knative-v1.15"}, "knative-webhook"),
+			},
+			Err: failed,
+		},
+	}
+
+	checkConsistency(generated)
+
+	want := map[string]string{
+		"openshift-knative/serving": "serving-release-v1.16__414.yaml: image promoted to openshift/knative-v1.15:knative-serving-controller is also promoted by serving-release-v1.15__414.yaml\n" +
+			"eventing-release-v1.15__414.yaml: image promoted to openshift/knative-v1.15:knative-webhook is also promoted by serving-release-v1.15__414.yaml",
+		"openshift-knative/eventing":       "eventing-release-v1.15__414.yaml: image promoted to openshift/knative-v1.15:knative-webhook is also promoted by serving-release-v1.15__414.yaml",
+		"openshift-knative/eventing-istio": "",
+		"openshift-knative/client":         "failed to clone",
+	}
+	got := make(map[string]string)
+	for _, g := range generated {
+		got[g.Repository.RepositoryDirectory()] = ""
+		if g.Err != nil {
+			got[g.Repository.RepositoryDirectory()] = g.Err.Error()
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,452 @@
+package prowgen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dockerfileInstruction is a Dockerfile instruction, continuation lines are joined.
+type dockerfileInstruction struct {
+	// Command is the lowercase instruction, for example, from.
+	Command string
+	// Flags are the leading --name[=value] flags.
+	Flags []string
+	// Args are the words following the flags, quotes are removed and variables aren't expanded.
+	Args []string
+	// Line is the line of the instruction in the Dockerfile, starting from 1.
+	Line int
+}
+
+// flag returns the value of the flag with the given name.
+func (i dockerfileInstruction) flag(name string) (string, bool) {
+	for _, f := range i.Flags {
+		if v, ok := strings.CutPrefix(f, "--"+name+"="); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+var (
+	dockerfileDirectiveRegex = regexp.MustCompile(`^#\s*([a-zA-Z][a-zA-Z0-9]*)\s*=\s*(.+?)\s*$`)
+	dockerfileHeredocRegex   = regexp.MustCompile(`^<<-?([a-zA-Z_][a-zA-Z0-9_]*)`)
+)
+
+// parseDockerfile parses the instructions of a Dockerfile, it supports the escape parser
+// directive, comments, line continuations and heredocs.
+func parseDockerfile(reader io.Reader) ([]dockerfileInstruction, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var (
+		instructions []dockerfileInstruction
+		escape       = '\\'
+		directives   = true
+		lineNumber   = 0
+		current      strings.Builder
+		start        = 0
+		heredocs     []string
+	)
+
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+
+		// Heredoc bodies are part of the previous instruction.
+		if len(heredocs) > 0 {
+			if strings.TrimLeft(line, "\t") == heredocs[0] {
+				heredocs = heredocs[1:]
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if directives {
+			if m := dockerfileDirectiveRegex.FindStringSubmatch(trimmed); m != nil {
+				if strings.EqualFold(m[1], "escape") {
+					switch m[2] {
+					case "\\":
+						escape = '\\'
+					case "`":
+						escape = '`'
+					default:
+						return nil, fmt.Errorf("line %d: invalid escape directive %q, expected \\ or `", lineNumber, m[2])
+					}
+				}
+				continue
+			}
+			directives = false
+		}
+
+		if strings.HasPrefix(trimmed, "#") || (trimmed == "" && current.Len() > 0) {
+			// Comments and empty lines are skipped, also within continuation lines.
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+		if current.Len() == 0 {
+			start = lineNumber
+		}
+
+		if strings.HasSuffix(trimmed, string(escape)) {
+			current.WriteString(strings.TrimSuffix(trimmed, string(escape)))
+			current.WriteString(" ")
+			continue
+		}
+		current.WriteString(trimmed)
+
+		instruction, err := parseDockerfileInstruction(current.String(), escape)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		instruction.Line = start
+		instructions = append(instructions, instruction)
+		current.Reset()
+
+		heredocs = instruction.heredocs()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current.Len() > 0 {
+		instruction, err := parseDockerfileInstruction(current.String(), escape)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		instruction.Line = start
+		instructions = append(instructions, instruction)
+	}
+	if len(heredocs) > 0 {
+		return nil, fmt.Errorf("unterminated heredoc %q", heredocs[0])
+	}
+	return instructions, nil
+}
+
+// heredocs returns the delimiters of the heredocs of RUN, COPY and ADD instructions, in order.
+func (i dockerfileInstruction) heredocs() []string {
+	if i.Command != "run" && i.Command != "copy" && i.Command != "add" {
+		return nil
+	}
+	var delimiters []string
+	for _, arg := range i.Args {
+		if m := dockerfileHeredocRegex.FindStringSubmatch(arg); m != nil {
+			delimiters = append(delimiters, m[1])
+		}
+	}
+	return delimiters
+}
+
+func parseDockerfileInstruction(line string, escape rune) (dockerfileInstruction, error) {
+	command, rest, _ := strings.Cut(line, " ")
+	instruction := dockerfileInstruction{Command: strings.ToLower(command)}
+
+	words, err := splitDockerfileWords(strings.TrimSpace(rest), escape)
+	if err != nil {
+		return instruction, fmt.Errorf("%s: %w", strings.ToUpper(instruction.Command), err)
+	}
+	for len(words) > 0 && strings.HasPrefix(words[0], "--") {
+		instruction.Flags = append(instruction.Flags, words[0])
+		words = words[1:]
+	}
+	instruction.Args = words
+	return instruction, nil
+}
+
+// splitDockerfileWords splits s in words separated by whitespace, quotes are removed and
+// escaped characters are unescaped, except $, which is kept escaped as \$ for variable expansion.
+func splitDockerfileWords(s string, escape rune) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, c := range s {
+		switch {
+		case escaped:
+			if c == '$' {
+				// Kept escaped, see expandDockerfileVariables.
+				word.WriteRune('\\')
+			}
+			word.WriteRune(c)
+			escaped = false
+		case c == escape && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// expandDockerfileVariables expands $NAME, ${NAME}, ${NAME:-word} and ${NAME:+word} references,
+// \$ is a literal $, referencing a variable without a value is an error.
+func expandDockerfileVariables(s string, vars map[string]string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case s[i] != '$' || i+1 == len(s):
+			b.WriteByte(s[i])
+			continue
+		}
+
+		var expr string
+		if s[i+1] == '{' {
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("missing '}' in %q", s)
+			}
+			expr = s[i+2 : i+2+end]
+			i += 2 + end
+		} else {
+			name := dockerfileVariablePrefixRegex.FindString(s[i+1:])
+			if name == "" {
+				b.WriteByte(s[i])
+				continue
+			}
+			expr = name
+			i += len(name)
+		}
+
+		value, err := expandDockerfileVariable(expr, vars)
+		if err != nil {
+			return "", fmt.Errorf("%w in %q", err, s)
+		}
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+func expandDockerfileVariable(expr string, vars map[string]string) (string, error) {
+	name, modifier, word := expr, "", ""
+	for _, m := range []string{":-", ":+"} {
+		if before, after, ok := strings.Cut(expr, m); ok {
+			name, modifier, word = before, m, after
+			break
+		}
+	}
+	if !isDockerfileVariableName(name) {
+		return "", fmt.Errorf("invalid variable reference ${%s}", expr)
+	}
+
+	value, ok := vars[name]
+	switch modifier {
+	case ":-":
+		if value == "" {
+			return word, nil
+		}
+	case ":+":
+		if value != "" {
+			return word, nil
+		}
+		return "", nil
+	}
+	if !ok {
+		return "", fmt.Errorf("variable %s has no value", name)
+	}
+	return value, nil
+}
+
+var (
+	dockerfileVariableNameRegex   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	dockerfileVariablePrefixRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
+)
+
+func isDockerfileVariableName(s string) bool {
+	return dockerfileVariableNameRegex.MatchString(s)
+}
+
+// dockerfileImage is an external image referenced by FROM, COPY --from or RUN --mount=from.
+type dockerfileImage struct {
+	// Reference is the reference as written in the Dockerfile, for example, $GO_BUILDER.
+	Reference string
+	// Image is the reference with ARGs substituted.
+	Image string
+	Line  int
+}
+
+// dockerfileArg is an ARG declared with a default value.
+type dockerfileArg struct {
+	Name string
+	// Value is the default value with ARGs substituted.
+	Value string
+	Line  int
+}
+
+// dockerfileImages are the images referenced by a Dockerfile.
+type dockerfileImages struct {
+	// External are the referenced external images, in order, build stages aren't included.
+	External []dockerfileImage
+	// Args are the ARG defaults, global and of each stage, in order, an ARG declared in
+	// several stages has a default for each of them.
+	Args []dockerfileArg
+}
+
+// loadDockerfileImages parses the Dockerfile and resolves the images it references.
+func loadDockerfileImages(path string) (*dockerfileImages, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Dockerfile %s: %w", path, err)
+	}
+	defer f.Close()
+
+	instructions, err := parseDockerfile(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Dockerfile %s: %w", path, err)
+	}
+	images, err := resolveDockerfileImages(instructions)
+	if err != nil {
+		return nil, fmt.Errorf("Dockerfile %s: %w", path, err)
+	}
+	return images, nil
+}
+
+// resolveDockerfileImages substitutes ARGs in image references and separates build stages from
+// external images, references that can't be resolved are errors.
+func resolveDockerfileImages(instructions []dockerfileInstruction) (*dockerfileImages, error) {
+	images := &dockerfileImages{}
+
+	// Global ARGs are declared before the first FROM and are only in scope of FROM instructions,
+	// unless they are declared again without a value in a stage.
+	globalArgs := map[string]string{}
+	var stageArgs map[string]string
+	var stages []string
+
+	isStage := func(ref string) bool {
+		if n, err := strconv.Atoi(ref); err == nil {
+			return n >= 0 && n < len(stages)
+		}
+		for _, s := range stages {
+			if strings.EqualFold(s, ref) {
+				return true
+			}
+		}
+		return false
+	}
+	addImage := func(reference string, vars map[string]string, line int) error {
+		image, err := expandDockerfileVariables(reference, vars)
+		if err != nil {
+			return fmt.Errorf("line %d: unresolvable image reference %q: %w", line, reference, err)
+		}
+		if image == "" {
+			return fmt.Errorf("line %d: unresolvable image reference %q: empty image", line, reference)
+		}
+		if isStage(image) || strings.EqualFold(image, "scratch") {
+			return nil
+		}
+		images.External = append(images.External, dockerfileImage{Reference: reference, Image: image, Line: line})
+		return nil
+	}
+
+	for _, i := range instructions {
+		vars := globalArgs
+		if stageArgs != nil {
+			vars = stageArgs
+		}
+
+		switch i.Command {
+		case "arg":
+			for _, arg := range i.Args {
+				name, value, hasValue := strings.Cut(arg, "=")
+				if !isDockerfileVariableName(name) {
+					return nil, fmt.Errorf("line %d: invalid ARG name %q", i.Line, name)
+				}
+				if !hasValue {
+					if stageArgs != nil {
+						if v, ok := globalArgs[name]; ok {
+							stageArgs[name] = v
+						}
+					}
+					continue
+				}
+				expanded, err := expandDockerfileVariables(value, vars)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: ARG %s: %w", i.Line, name, err)
+				}
+				vars[name] = expanded
+				images.Args = append(images.Args, dockerfileArg{Name: name, Value: expanded, Line: i.Line})
+			}
+
+		case "from":
+			if len(i.Args) != 1 && !(len(i.Args) == 3 && strings.EqualFold(i.Args[1], "as")) {
+				return nil, fmt.Errorf("line %d: FROM requires an image and an optional AS <name>, got %q", i.Line, strings.Join(i.Args, " "))
+			}
+			if err := addImage(i.Args[0], globalArgs, i.Line); err != nil {
+				return nil, err
+			}
+			name := ""
+			if len(i.Args) == 3 {
+				name = i.Args[2]
+			}
+			stages = append(stages, name)
+			stageArgs = map[string]string{}
+
+		case "copy", "add":
+			if from, ok := i.flag("from"); ok {
+				if stageArgs == nil {
+					return nil, fmt.Errorf("line %d: %s before FROM", i.Line, strings.ToUpper(i.Command))
+				}
+				if err := addImage(from, vars, i.Line); err != nil {
+					return nil, err
+				}
+			}
+
+		case "run":
+			for _, f := range i.Flags {
+				mount, ok := strings.CutPrefix(f, "--mount=")
+				if !ok {
+					continue
+				}
+				for _, opt := range strings.Split(mount, ",") {
+					if from, ok := strings.CutPrefix(opt, "from="); ok {
+						if err := addImage(from, vars, i.Line); err != nil {
+							return nil, err
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if len(stages) == 0 {
+		if len(instructions) == 0 {
+			return images, nil
+		}
+		return nil, fmt.Errorf("no FROM instruction")
+	}
+	return images, nil
+}
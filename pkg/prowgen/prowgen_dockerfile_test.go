@@ -0,0 +1,322 @@
+package prowgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+)
+
+func TestResolveDockerfileImages(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerfile string
+		want       *dockerfileImages
+		wantErr    string
+	}{
+		{
+			name:       "empty",
+			dockerfile: "# only a comment\n",
+			want:       &dockerfileImages{},
+		},
+		{
+			name:       "lowercase from and platform",
+			dockerfile: "from --platform=$BUILDPLATFORM registry.ci.openshift.org/openshift/release:golang-1.22\n",
+			want: &dockerfileImages{
+				External: []dockerfileImage{
+					{Reference: "registry.ci.openshift.org/openshift/release:golang-1.22", Image: "registry.ci.openshift.org/openshift/release:golang-1.22", Line: 1},
+				},
+			},
+		},
+		{
+			name: "continuation and quoted ARG",
+			dockerfile: `ARG GO_BUILDER="registry.ci.openshift.org/openshift/release:rhel-8-release-golang-1.22-openshift-4.17"
+ARG GO_RUNTIME='registry.access.redhat.com/ubi8/ubi-minimal'
+
+FROM \
+    $GO_BUILDER \
+    AS builder
+RUN make \
+  # a comment in a continuation
+  build
+
+FROM ${GO_RUNTIME}
+COPY --from=builder /bin/app /bin/app
+`,
+			want: &dockerfileImages{
+				External: []dockerfileImage{
+					{Reference: "$GO_BUILDER", Image: "registry.ci.openshift.org/openshift/release:rhel-8-release-golang-1.22-openshift-4.17", Line: 4},
+					{Reference: "${GO_RUNTIME}", Image: "registry.access.redhat.com/ubi8/ubi-minimal", Line: 11},
+				},
+				Args: []dockerfileArg{
+					{Name: "GO_BUILDER", Value: "registry.ci.openshift.org/openshift/release:rhel-8-release-golang-1.22-openshift-4.17", Line: 1},
+					{Name: "GO_RUNTIME", Value: "registry.access.redhat.com/ubi8/ubi-minimal", Line: 2},
+				},
+			},
+		},
+		{
+			name: "stages and external images",
+			dockerfile: `FROM registry.ci.openshift.org/ocp/4.17:cli-artifacts AS Tools
+FROM scratch
+COPY --from=tools /usr/bin/oc /usr/bin/oc
+COPY --from=0 /usr/bin/kubectl /usr/bin/kubectl
+COPY --from=quay.io/openshift-knative/helper:latest /helper /helper
+RUN --mount=type=cache,from=registry.ci.openshift.org/openshift/release:golang-1.22,target=/go true
+`,
+			want: &dockerfileImages{
+				External: []dockerfileImage{
+					{Reference: "registry.ci.openshift.org/ocp/4.17:cli-artifacts", Image: "registry.ci.openshift.org/ocp/4.17:cli-artifacts", Line: 1},
+					{Reference: "quay.io/openshift-knative/helper:latest", Image: "quay.io/openshift-knative/helper:latest", Line: 5},
+					{Reference: "registry.ci.openshift.org/openshift/release:golang-1.22", Image: "registry.ci.openshift.org/openshift/release:golang-1.22", Line: 6},
+				},
+			},
+		},
+		{
+			name: "stage ARGs",
+			dockerfile: `ARG VERSION=1.22
+ARG HELPER=quay.io/openshift-knative/helper
+FROM registry.ci.openshift.org/openshift/release:golang-${VERSION}
+ARG HELPER
+ARG TAG=${VERSION:-latest}
+COPY --from=${HELPER}:$TAG /helper /helper
+`,
+			want: &dockerfileImages{
+				External: []dockerfileImage{
+					{Reference: "registry.ci.openshift.org/openshift/release:golang-${VERSION}", Image: "registry.ci.openshift.org/openshift/release:golang-1.22", Line: 3},
+					{Reference: "${HELPER}:$TAG", Image: "quay.io/openshift-knative/helper:latest", Line: 6},
+				},
+				Args: []dockerfileArg{
+					{Name: "VERSION", Value: "1.22", Line: 1},
+					{Name: "HELPER", Value: "quay.io/openshift-knative/helper", Line: 2},
+					{Name: "TAG", Value: "latest", Line: 5},
+				},
+			},
+		},
+		{
+			name: "ARG in several stages",
+			dockerfile: `FROM registry.access.redhat.com/ubi8/ubi-minimal
+ARG BASE=registry.access.redhat.com/ubi8/ubi-minimal
+FROM registry.access.redhat.com/ubi9/ubi-minimal
+ARG BASE=registry.access.redhat.com/ubi9/ubi-minimal
+`,
+			want: &dockerfileImages{
+				External: []dockerfileImage{
+					{Reference: "registry.access.redhat.com/ubi8/ubi-minimal", Image: "registry.access.redhat.com/ubi8/ubi-minimal", Line: 1},
+					{Reference: "registry.access.redhat.com/ubi9/ubi-minimal", Image: "registry.access.redhat.com/ubi9/ubi-minimal", Line: 3},
+				},
+				Args: []dockerfileArg{
+					{Name: "BASE", Value: "registry.access.redhat.com/ubi8/ubi-minimal", Line: 2},
+					{Name: "BASE", Value: "registry.access.redhat.com/ubi9/ubi-minimal", Line: 4},
+				},
+			},
+		},
+		{
+			name: "heredoc and escape directive",
+			dockerfile: "# escape=`\n" +
+				"FROM registry.access.redhat.com/ubi8/ubi-minimal\n" +
+				"RUN <<EOF\n" +
+				"FROM not-an-image\n" +
+				"EOF\n" +
+				"COPY `\n" +
+				"  --from=quay.io/openshift-knative/helper:latest /helper /helper\n",
+			want: &dockerfileImages{
+				External: []dockerfileImage{
+					{Reference: "registry.access.redhat.com/ubi8/ubi-minimal", Image: "registry.access.redhat.com/ubi8/ubi-minimal", Line: 2},
+					{Reference: "quay.io/openshift-knative/helper:latest", Image: "quay.io/openshift-knative/helper:latest", Line: 6},
+				},
+			},
+		},
+		{
+			name:       "unresolvable ARG",
+			dockerfile: "ARG GO_BUILDER\nFROM $GO_BUILDER\n",
+			wantErr:    `line 2: unresolvable image reference "$GO_BUILDER": variable GO_BUILDER has no value`,
+		},
+		{
+			name:       "stage ARG isn't in scope of FROM",
+			dockerfile: "FROM scratch\nARG BASE=quay.io/base\nFROM $BASE\n",
+			wantErr:    `line 3: unresolvable image reference "$BASE"`,
+		},
+		{
+			name:       "invalid FROM",
+			dockerfile: "FROM a b\n",
+			wantErr:    "line 1: FROM requires an image",
+		},
+		{
+			name:       "missing FROM",
+			dockerfile: "ARG A=b\n",
+			wantErr:    "no FROM instruction",
+		},
+		{
+			name:       "unterminated quote",
+			dockerfile: "FROM scratch\nARG A=\"b\n",
+			wantErr:    "line 2: ARG: unterminated quote",
+		},
+		{
+			name:       "unterminated heredoc",
+			dockerfile: "FROM scratch\nRUN <<EOF\ntrue\n",
+			wantErr:    `unterminated heredoc "EOF"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDockerfile(strings.NewReader(tt.dockerfile))
+			var images *dockerfileImages
+			if err == nil {
+				images, err = resolveDockerfileImages(got)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, images); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiscoverInputImagesArgs(t *testing.T) {
+	dockerfile := filepath.Join(t.TempDir(), "Dockerfile")
+	content := `ARG GO_BUILDER=registry.ci.openshift.org/openshift/release:rhel-8-release-golang-1.22-openshift-4.17
+ARG GO_RUNTIME=registry.access.redhat.com/ubi8/ubi-minimal
+
+FROM $GO_BUILDER as builder
+FROM registry.ci.openshift.org/ocp/4.17:cli-artifacts AS tools
+FROM $GO_RUNTIME
+COPY --from=builder /bin/app /bin/app
+COPY --from=tools /usr/bin/oc /usr/bin/oc
+`
+	if err := os.WriteFile(dockerfile, []byte(content), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	mapper, err := newImageMapper(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseImages, inputs, _, err := discoverInputImages(dockerfile, mapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantBaseImages := map[string]cioperatorapi.ImageStreamTagReference{
+		"openshift_release_rhel-8-release-golang-1.22-openshift-4.17": {
+			Namespace: "openshift",
+			Name:      "release",
+			Tag:       "rhel-8-release-golang-1.22-openshift-4.17",
+		},
+		"ocp_4.17_cli-artifacts": {
+			Namespace: "ocp",
+			Name:      "4.17",
+			Tag:       "cli-artifacts",
+		},
+		"ocp_ubi-minimal_8": {
+			Namespace: "ocp",
+			Name:      "ubi-minimal",
+			Tag:       "8",
+		},
+	}
+	wantInputs := map[string]cioperatorapi.ImageBuildInputs{
+		"openshift_release_rhel-8-release-golang-1.22-openshift-4.17": {
+			As: []string{"$GO_BUILDER"},
+		},
+		"ocp_4.17_cli-artifacts": {
+			As: []string{"registry.ci.openshift.org/ocp/4.17:cli-artifacts"},
+		},
+		"ocp_ubi-minimal_8": {
+			As: []string{"$GO_RUNTIME"},
+		},
+	}
+	if diff := cmp.Diff(wantBaseImages, baseImages); diff != "" {
+		t.Errorf("base images (-want, +got)\n%s", diff)
+	}
+	if diff := cmp.Diff(wantInputs, inputs); diff != "" {
+		t.Errorf("inputs (-want, +got)\n%s", diff)
+	}
+}
+
+func TestDiscoverInputImagesUnresolvable(t *testing.T) {
+	dockerfile := filepath.Join(t.TempDir(), "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("ARG GO_BUILDER\nFROM $GO_BUILDER\n"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	mapper, err := newImageMapper(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := discoverInputImages(dockerfile, mapper); err == nil {
+		t.Fatal("expected error for unresolvable image reference")
+	}
+}
+
+func TestDiscoverInputImagesStageArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerfile string
+		wantInputs map[string]cioperatorapi.ImageBuildInputs
+		wantErr    string
+	}{
+		{
+			name: "same image",
+			dockerfile: `FROM registry.ci.openshift.org/ocp/4.17:cli-artifacts AS tools
+ARG RUNTIME=registry.access.redhat.com/ubi8/ubi-minimal
+FROM registry.ci.openshift.org/ocp/4.17:cli-artifacts
+ARG RUNTIME=registry.access.redhat.com/ubi8-minimal
+`,
+			wantInputs: map[string]cioperatorapi.ImageBuildInputs{
+				"ocp_4.17_cli-artifacts": {
+					As: []string{"registry.ci.openshift.org/ocp/4.17:cli-artifacts"},
+				},
+				"ocp_ubi-minimal_8": {
+					As: []string{"$RUNTIME"},
+				},
+			},
+		},
+		{
+			name: "conflicting images",
+			dockerfile: `FROM registry.ci.openshift.org/ocp/4.17:cli-artifacts AS tools
+ARG RUNTIME=registry.access.redhat.com/ubi8/ubi-minimal
+FROM registry.ci.openshift.org/ocp/4.17:cli-artifacts
+ARG RUNTIME=registry.access.redhat.com/ubi9/ubi-minimal
+`,
+			wantErr: `ARG RUNTIME has conflicting images "registry.access.redhat.com/ubi8/ubi-minimal" (line 2) and "registry.access.redhat.com/ubi9/ubi-minimal" (line 4)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dockerfile := filepath.Join(t.TempDir(), "Dockerfile")
+			if err := os.WriteFile(dockerfile, []byte(tt.dockerfile), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+
+			mapper, err := newImageMapper(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, inputs, _, err := discoverInputImages(dockerfile, mapper)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantInputs, inputs); diff != "" {
+				t.Errorf("inputs (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
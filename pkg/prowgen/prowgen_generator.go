@@ -0,0 +1,344 @@
+package prowgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Generator generates openshift/release configurations for a set of prowgen configurations.
+type Generator struct {
+	configs []*Config
+
+	openShiftRelease Repository
+	outputConfig     string
+
+	build   bool
+	konflux bool
+	owners  bool
+
+	push          bool
+	remote        string
+	branch        string
+	commitMessage string
+
+	cacheManifest string
+	force         bool
+}
+
+// GeneratorOption configures a Generator.
+type GeneratorOption func(g *Generator)
+
+// WithOpenShiftRelease sets the openshift/release repository (or fork) to generate
+// configurations into, openshift/release by default.
+func WithOpenShiftRelease(r Repository) GeneratorOption {
+	return func(g *Generator) {
+		g.openShiftRelease = r
+	}
+}
+
+// WithOutputConfig sets the directory for ci-operator configurations, by default
+// ci-operator/config in the openshift/release clone.
+func WithOutputConfig(dir string) GeneratorOption {
+	return func(g *Generator) {
+		g.outputConfig = dir
+	}
+}
+
+// WithBuild runs the openshift/release generator (make ci-operator-config jobs prow-config)
+// after writing configurations.
+func WithBuild(build bool) GeneratorOption {
+	return func(g *Generator) {
+		g.build = build
+	}
+}
+
+// WithPush commits the changes to the given branch of the openshift/release clone and, when
+// remote isn't empty, pushes the branch to it.
+func WithPush(remote string, branch string, commitMessage string) GeneratorOption {
+	return func(g *Generator) {
+		g.push = true
+		g.remote = remote
+		g.branch = branch
+		g.commitMessage = commitMessage
+	}
+}
+
+// WithKonflux generates Konflux configurations, see GenerateKonflux.
+func WithKonflux(konflux bool) GeneratorOption {
+	return func(g *Generator) {
+		g.konflux = konflux
+	}
+}
+
+// WithOwners generates OWNERS files, see GenerateOwners.
+func WithOwners(owners bool) GeneratorOption {
+	return func(g *Generator) {
+		g.owners = owners
+	}
+}
+
+// WithCacheManifest sets the path of the cache manifest (see CacheManifest), by default
+// CacheManifestName in the workspace root.
+//
+// The manifest must be stored outside of the openshift/release clone, otherwise it's pushed
+// with the generated configurations.
+func WithCacheManifest(path string) GeneratorOption {
+	return func(g *Generator) {
+		g.cacheManifest = path
+	}
+}
+
+// WithForce generates every repository, ignoring the cache, the cache manifest is still updated.
+func WithForce(force bool) GeneratorOption {
+	return func(g *Generator) {
+		g.force = force
+	}
+}
+
+// NewGenerator returns a Generator for the given configurations, by default it only writes
+// configurations to the openshift/release clone.
+func NewGenerator(configs []*Config, opts ...GeneratorOption) *Generator {
+	g := &Generator{
+		configs:          configs,
+		openShiftRelease: Repository{Org: "openshift", Repo: "release"},
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Report is the result of a Generator run.
+type Report struct {
+	Repositories []RepositoryReport `json:"repositories"`
+	Schedule     *ScheduleReport    `json:"schedule,omitempty"`
+	// Errors are the errors not related to a single repository, for example, pushing to the
+	// openshift/release fork.
+	Errors []string `json:"errors,omitempty"`
+}
+
+type RepositoryReport struct {
+	Repository string `json:"repository"`
+	// Cached is true when the repository is unchanged since the previous run and its
+	// configurations are reused.
+	Cached  bool           `json:"cached,omitempty"`
+	Configs []ConfigReport `json:"configs,omitempty"`
+	// ProwConfig is the written Tide and branch protection configuration.
+	ProwConfig string `json:"prowConfig,omitempty"`
+	// Deleted are the existing configurations removed and not generated again.
+	Deleted         []string        `json:"deleted,omitempty"`
+	SkippedBranches []SkippedBranch `json:"skippedBranches,omitempty"`
+	Errors          []string        `json:"errors,omitempty"`
+}
+
+// ConfigReport describes a generated ci-operator configuration.
+type ConfigReport struct {
+	Branch  string `json:"branch"`
+	Variant string `json:"variant,omitempty"`
+	// Path is the written file, relative to the openshift/release root.
+	Path   string   `json:"path"`
+	Tests  []string `json:"tests,omitempty"`
+	Images []string `json:"images,omitempty"`
+	// UnmappedImages are the external images referenced by Dockerfiles that ci-operator can't
+	// resolve, see ImageMapping.
+	UnmappedImages []UnmappedImage `json:"unmappedImages,omitempty"`
+}
+
+type SkippedBranch struct {
+	Branch string `json:"branch"`
+	Reason string `json:"reason"`
+}
+
+// Err returns the errors of the report, if any, joined.
+func (r *Report) Err() error {
+	var errs []error
+	for _, rr := range r.Repositories {
+		for _, err := range rr.Errors {
+			errs = append(errs, fmt.Errorf("[%s] %s", rr.Repository, err))
+		}
+	}
+	for _, err := range r.Errors {
+		errs = append(errs, errors.New(err))
+	}
+	return errors.Join(errs...)
+}
+
+// Write writes the report as JSON.
+func (r *Report) Write(w io.Writer) error {
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// Run generates and writes configurations for every repository.
+//
+// A repository failing to generate doesn't prevent the others from being generated, its errors
+// are reported in the RepositoryReport, however, openshift/release isn't built nor pushed, and
+// Konflux and OWNERS files aren't generated when any repository fails.
+// The returned error contains every error of the report.
+func (g *Generator) Run(ctx context.Context) (*Report, error) {
+	report := &Report{}
+
+	// Default paths are in the workspace of the context.
+	if g.outputConfig == "" {
+		g.outputConfig = filepath.Join(g.openShiftRelease.LocalDirectory(ctx), "ci-operator", "config")
+	}
+	if g.cacheManifest == "" {
+		g.cacheManifest = filepath.Join(WorkspaceFromContext(ctx).Root, CacheManifestName)
+	}
+
+	// Clone openshift/release, configurations of unchanged repositories are reused from the
+	// clone.
+	if err := GitMirror(ctx, g.openShiftRelease); err != nil {
+		return g.fail(report, fmt.Errorf("failed to clone %s: %w", g.openShiftRelease.RepositoryDirectory(), err))
+	}
+	if err := GitCheckout(ctx, g.openShiftRelease, "main"); err != nil {
+		return g.fail(report, fmt.Errorf("failed to checkout %s: %w", g.openShiftRelease.RepositoryDirectory(), err))
+	}
+	cache, err := newGenerationCache(g.cacheManifest, g.outputConfig, g.force)
+	if err != nil {
+		return g.fail(report, err)
+	}
+	cached := cache.resolve(ctx, g.configs)
+
+	// For each repository and branch generate openshift/release configuration.
+	generated, scheduleReport, err := generateRepositories(ctx, g.configs, cached)
+	if err != nil {
+		return g.fail(report, fmt.Errorf("failed to generate configurations: %w", err))
+	}
+	report.Schedule = scheduleReport
+
+	// Clean up existing jobs of the generated repositories, the existing jobs of a failing
+	// repository are kept.
+	deleted := sets.NewString()
+	for _, gr := range generated {
+		if gr.Err != nil {
+			continue
+		}
+		removed, err := deleteExistingRepositoryConfigs(gr.Config, gr.Repository, g.outputConfig)
+		deleted.Insert(removed...)
+		if err != nil {
+			return g.fail(report, fmt.Errorf("[%s] failed to delete existing configurations: %w", gr.Repository.RepositoryDirectory(), err))
+		}
+	}
+
+	// Write generated configurations to the output directory.
+	written := sets.NewString()
+	for _, gr := range generated {
+		rr := RepositoryReport{
+			Repository:      gr.Repository.RepositoryDirectory(),
+			Cached:          gr.Cached,
+			SkippedBranches: skippedBranches(gr.Config.Config),
+		}
+		if gr.Err != nil {
+			rr.Errors = append(rr.Errors, gr.Err.Error())
+			report.Repositories = append(report.Repositories, rr)
+			continue
+		}
+		s, err := saveGeneratedRepository(ctx, g.openShiftRelease, g.outputConfig, gr)
+		deleted.Insert(s.deleted...)
+		written.Insert(s.written...)
+		if err != nil {
+			rr.Errors = append(rr.Errors, fmt.Sprintf("failed to save configurations: %v", err))
+		}
+		for _, cfg := range gr.Configs {
+			rr.Configs = append(rr.Configs, g.configReport(ctx, cfg))
+		}
+		if s.prowConfig != "" {
+			rr.ProwConfig = g.relativePath(ctx, s.prowConfig)
+		}
+		report.Repositories = append(report.Repositories, rr)
+	}
+	for i := range report.Repositories {
+		rr := &report.Repositories[i]
+		dir := filepath.Join(g.outputConfig, rr.Repository) + string(filepath.Separator)
+		for _, path := range deleted.Difference(written).List() {
+			if strings.HasPrefix(path, dir) {
+				rr.Deleted = append(rr.Deleted, g.relativePath(ctx, path))
+			}
+		}
+	}
+	if err := cache.update(generated); err != nil {
+		return g.fail(report, fmt.Errorf("failed to update cache manifest: %w", err))
+	}
+	if err := report.Err(); err != nil {
+		return report, err
+	}
+
+	if g.build {
+		if err := RunOpenShiftReleaseGenerator(ctx, g.openShiftRelease); err != nil {
+			return g.fail(report, fmt.Errorf("failed to run openshift/release generator: %w", err))
+		}
+	}
+	if g.push {
+		if err := PushBranch(ctx, g.openShiftRelease, &g.remote, g.branch, g.commitMessage); err != nil {
+			return g.fail(report, fmt.Errorf("failed to push branch to openshift/release fork %s: %w", g.remote, err))
+		}
+	}
+	if g.konflux {
+		if err := GenerateKonflux(ctx, g.openShiftRelease, g.configs); err != nil {
+			return g.fail(report, fmt.Errorf("failed to generate Konflux configurations: %w", err))
+		}
+	}
+	if g.owners {
+		if err := GenerateOwners(ctx, g.configs); err != nil {
+			return g.fail(report, fmt.Errorf("failed to generate OWNERS files: %w", err))
+		}
+	}
+	return report, nil
+}
+
+func (g *Generator) fail(report *Report, err error) (*Report, error) {
+	report.Errors = append(report.Errors, err.Error())
+	return report, report.Err()
+}
+
+func (g *Generator) configReport(ctx context.Context, cfg ReleaseBuildConfiguration) ConfigReport {
+	c := ConfigReport{
+		Branch:  cfg.Metadata.Branch,
+		Variant: cfg.Metadata.Variant,
+		Path:    g.relativePath(ctx, filepath.Join(g.outputConfig, cfg.Path)),
+
+		UnmappedImages: cfg.UnmappedImages,
+	}
+	for _, t := range cfg.Tests {
+		c.Tests = append(c.Tests, t.As)
+	}
+	for _, img := range cfg.Images.Items {
+		c.Images = append(c.Images, string(img.To))
+	}
+	return c
+}
+
+// relativePath returns the path relative to the openshift/release root, when possible.
+func (g *Generator) relativePath(ctx context.Context, path string) string {
+	if rel, err := filepath.Rel(g.openShiftRelease.LocalDirectory(ctx), path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// skippedBranches returns the configured branches that aren't generated.
+func skippedBranches(cc CommonConfig) []SkippedBranch {
+	var skipped []SkippedBranch
+	for _, name := range sortedKeys(cc.Branches) {
+		b := cc.Branches[name]
+		switch {
+		case b.Prowgen != nil && b.Prowgen.Disabled:
+			skipped = append(skipped, SkippedBranch{Branch: name, Reason: "prowgen is disabled"})
+		case len(b.OpenShiftVersions) == 0:
+			skipped = append(skipped, SkippedBranch{Branch: name, Reason: "no OpenShift versions"})
+		}
+	}
+	return skipped
+}
@@ -0,0 +1,195 @@
+package prowgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGeneratorRun(t *testing.T) {
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, "testorg", "serving.git"), "release-next")
+
+	existing := filepath.Join("ci-operator", "config", "testorg", "serving")
+	existingMissing := filepath.Join("ci-operator", "config", "testorg", "missing", "testorg-missing-release-next__414.yaml")
+	release := t.TempDir()
+	for _, path := range []string{
+		filepath.Join(existing, "testorg-serving-release-next__414.yaml"),
+		filepath.Join(existing, "testorg-serving-release-v1.0__414.yaml"),
+		existingMissing,
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(release, path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(release, path), []byte("{}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seedBareRepository(t, release, filepath.Join(sourceRoot, "openshift", "release.git"), "main")
+
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	inConfigs := []*Config{
+		{
+			Repositories: []Repository{
+				{Org: "testorg", Repo: "serving", ImagePrefix: "knative-serving", E2ETests: []E2ETest{{Match: "test-e2e$"}}},
+				{Org: "testorg", Repo: "missing", ImagePrefix: "knative-missing"},
+			},
+			Config: CommonConfig{
+				Branches: map[string]Branch{
+					"release-next": {
+						OpenShiftVersions: []OpenShift{{Version: "4.14", SkipCron: true}},
+					},
+					"release-v0.1": {
+						OpenShiftVersions: []OpenShift{{Version: "4.14"}},
+						Prowgen:           &Prowgen{Disabled: true},
+					},
+				},
+			},
+		},
+	}
+
+	report, err := NewGenerator(inConfigs).Run(ctx)
+	if err == nil {
+		t.Fatal("expected error for the missing repository")
+	}
+
+	if len(report.Repositories) != 2 {
+		t.Fatalf("expected 2 repositories, got %d", len(report.Repositories))
+	}
+	missing := report.Repositories[1]
+	if missing.Repository != "testorg/missing" || len(missing.Errors) != 1 || len(missing.Configs) != 0 || len(missing.Deleted) != 0 {
+		t.Errorf("unexpected report for the missing repository: %+v", missing)
+	}
+
+	want := RepositoryReport{
+		Repository: "testorg/serving",
+		Configs: []ConfigReport{
+			{
+				Branch:  "release-next",
+				Variant: "414",
+				Path:    filepath.Join(existing, "testorg-serving-release-next__414.yaml"),
+				Tests:   []string{"test-e2e"},
+				Images: []string{
+					"knative-serving-autoscaler",
+					"knative-serving-migrate",
+					"knative-serving-scale-from-zero",
+					"knative-serving-test-webhook",
+					"knative-serving-source-image",
+				},
+			},
+		},
+		ProwConfig:      filepath.Join("core-services", "prow", "02_config", "testorg", "serving", "_prowconfig.yaml"),
+		Deleted:         []string{filepath.Join(existing, "testorg-serving-release-v1.0__414.yaml")},
+		SkippedBranches: []SkippedBranch{{Branch: "release-v0.1", Reason: "prowgen is disabled"}},
+	}
+	if diff := cmp.Diff(want, report.Repositories[0]); diff != "" {
+		t.Error("report (-want, +got):", diff)
+	}
+
+	openShiftRelease := Repository{Org: "openshift", Repo: "release"}
+	if _, err := os.Stat(filepath.Join(openShiftRelease.LocalDirectory(ctx), want.Configs[0].Path)); err != nil {
+		t.Error("expected generated configuration to be written:", err)
+	}
+	if _, err := os.Stat(filepath.Join(openShiftRelease.LocalDirectory(ctx), want.Deleted[0])); !os.IsNotExist(err) {
+		t.Error("expected existing configuration to be deleted:", err)
+	}
+	if _, err := os.Stat(filepath.Join(openShiftRelease.LocalDirectory(ctx), existingMissing)); err != nil {
+		t.Error("expected existing configuration of the failing repository to be kept:", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := report.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	got := &Report{}
+	if err := json.Unmarshal(out.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(report, got); diff != "" {
+		t.Error("JSON round trip (-want, +got):", diff)
+	}
+}
+
+func TestGeneratorRunCache(t *testing.T) {
+	sourceRoot := t.TempDir()
+	source := filepath.Join(sourceRoot, "testorg", "serving.git")
+	seedBareRepository(t, filepath.Join("testdata", "serving"), source, "release-next")
+	release := t.TempDir()
+	if err := os.WriteFile(filepath.Join(release, "README.md"), []byte("openshift/release\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	seedBareRepository(t, release, filepath.Join(sourceRoot, "openshift", "release.git"), "main")
+
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	inConfig := &Config{
+		Repositories: []Repository{
+			{Org: "testorg", Repo: "serving", ImagePrefix: "knative-serving", E2ETests: []E2ETest{{Match: "test-e2e$"}}},
+		},
+		Config: CommonConfig{
+			Branches: map[string]Branch{
+				"release-next": {
+					OpenShiftVersions: []OpenShift{{Version: "4.14", SkipCron: true}},
+				},
+			},
+		},
+	}
+
+	path := filepath.Join(Repository{Org: "openshift", Repo: "release"}.LocalDirectory(ctx), "ci-operator", "config", "testorg", "serving", "testorg-serving-release-next__414.yaml")
+	run := func(t *testing.T, wantCached bool, opts ...GeneratorOption) {
+		t.Helper()
+		report, err := NewGenerator([]*Config{inConfig}, opts...).Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := report.Repositories[0].Cached; got != wantCached {
+			t.Errorf("want cached %v, got %v", wantCached, got)
+		}
+		if len(report.Repositories[0].Configs) != 1 {
+			t.Errorf("want 1 config, got %+v", report.Repositories[0].Configs)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Error("expected configuration to be written:", err)
+		}
+	}
+
+	run(t, false)
+	generated, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, true)
+	cached, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(generated), string(cached)); diff != "" {
+		t.Error("cached configuration (-generated, +cached):", diff)
+	}
+
+	run(t, false, WithForce(true))
+	run(t, true)
+
+	// A new commit invalidates the cache.
+	work := t.TempDir()
+	git(t, "", "clone", "--branch", "release-next", source, work)
+	if err := os.WriteFile(filepath.Join(work, "CHANGELOG.md"), []byte("change\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, work, "add", ".")
+	git(t, work, "-c", "user.name=prowgen", "-c", "user.email=prowgen@example.com", "commit", "-m", "change")
+	git(t, work, "push", "origin", "release-next")
+	run(t, false)
+	run(t, true)
+
+	// A configuration change invalidates the cache.
+	inConfig.Repositories[0].E2ETests = append(inConfig.Repositories[0].E2ETests, E2ETest{Match: "perf-tests$"})
+	run(t, false)
+	run(t, true)
+}
@@ -21,6 +21,23 @@ func GitCheckout(ctx context.Context, r Repository, branch string) error {
 	return err
 }
 
+// GitRemoteHeads returns the commit SHA of each branch in the remote repository, without cloning it.
+func GitRemoteHeads(ctx context.Context, r Repository) (map[string]string, error) {
+	out, err := runNoRepo(ctx, "git", "ls-remote", "--heads", WorkspaceFromContext(ctx).RemoteURL(r))
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to list remote branches: %w", r.RepositoryDirectory(), err)
+	}
+	heads := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		heads[strings.TrimPrefix(fields[1], "refs/heads/")] = fields[0]
+	}
+	return heads, nil
+}
+
 func GitMirror(ctx context.Context, r Repository) error {
 	return gitClone(ctx, r, true)
 }
@@ -136,31 +153,31 @@ func gitClone(ctx context.Context, r Repository, mirror bool) error {
 	default:
 	}
 
-	if _, err := os.Stat(r.RepositoryDirectory()); !errors.Is(err, os.ErrNotExist) {
+	if _, err := os.Stat(r.LocalDirectory(ctx)); !errors.Is(err, os.ErrNotExist) {
 		log.Println("Repository", r.RepositoryDirectory(), "already cloned")
 		return nil
 	}
 
-	if err := os.RemoveAll(r.RepositoryDirectory()); err != nil {
+	if err := os.RemoveAll(r.LocalDirectory(ctx)); err != nil {
 		return fmt.Errorf("[%s] failed to delete directory: %w", r.RepositoryDirectory(), err)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(r.RepositoryDirectory()), os.ModePerm); err != nil {
+	if err := os.MkdirAll(filepath.Dir(r.LocalDirectory(ctx)), os.ModePerm); err != nil {
 		return fmt.Errorf("[%s] failed to create directory: %w", r.RepositoryDirectory(), err)
 	}
 
-	remoteRepo := fmt.Sprintf("https://github.com/%s/%s.git", r.Org, r.Repo)
+	remoteRepo := WorkspaceFromContext(ctx).RemoteURL(r)
 	if mirror {
-		log.Println("Mirroring repository", r.RepositoryDirectory())
-		if _, err := runNoRepo(ctx, "git", "clone", "--mirror", remoteRepo, filepath.Join(r.RepositoryDirectory(), ".git")); err != nil {
+		log.Println("Mirroring repository", r.RepositoryDirectory(), "from", remoteRepo)
+		if _, err := runNoRepo(ctx, "git", "clone", "--mirror", remoteRepo, filepath.Join(r.LocalDirectory(ctx), ".git")); err != nil {
 			return fmt.Errorf("[%s] failed to clone repository: %w", r.RepositoryDirectory(), err)
 		}
 		if _, err := Run(ctx, r, "git", "config", "--bool", "core.bare", "false"); err != nil {
 			return fmt.Errorf("[%s] failed to set config for repository: %w", r.RepositoryDirectory(), err)
 		}
 	} else {
-		log.Println("Cloning repository", r.RepositoryDirectory())
-		if _, err := runNoRepo(ctx, "git", "clone", remoteRepo, r.RepositoryDirectory()); err != nil {
+		log.Println("Cloning repository", r.RepositoryDirectory(), "from", remoteRepo)
+		if _, err := runNoRepo(ctx, "git", "clone", remoteRepo, r.LocalDirectory(ctx)); err != nil {
 			return fmt.Errorf("[%s] failed to clone repository: %w", r.RepositoryDirectory(), err)
 		}
 	}
@@ -174,8 +191,7 @@ func GitMerge(ctx context.Context, r Repository, sha string) error {
 }
 
 func GitFetch(ctx context.Context, r Repository, sha string) error {
-	remoteRepo := fmt.Sprintf("https://github.com/%s/%s.git", r.Org, r.Repo)
-	_, err := Run(ctx, r, "git", "fetch", remoteRepo, sha)
+	_, err := Run(ctx, r, "git", "fetch", WorkspaceFromContext(ctx).RemoteURL(r), sha)
 	return err
 }
 
@@ -0,0 +1,152 @@
+package prowgen
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GitSource resolves the remote a repository is cloned and fetched from.
+type GitSource interface {
+	// RemoteURL returns the URL, or path, passed to git for the given repository.
+	RemoteURL(r Repository) string
+}
+
+// GitHubSource clones repositories from https://github.com.
+type GitHubSource struct{}
+
+func (GitHubSource) RemoteURL(r Repository) string {
+	return fmt.Sprintf("https://github.com/%s/%s.git", r.Org, r.Repo)
+}
+
+// LocalSource clones repositories from a local directory laid out as <Root>/<org>/<repo>.
+// Each repository can either be a bare repository (<repo>.git) or a regular clone (<repo>),
+// bare repositories take precedence.
+type LocalSource struct {
+	Root string
+}
+
+func (s LocalSource) RemoteURL(r Repository) string {
+	root := s.Root
+	if abs, err := filepath.Abs(root); err == nil {
+		// Git commands run in the repository directory, so the remote has to be absolute.
+		root = abs
+	}
+	bare := filepath.Join(root, r.Org, r.Repo+".git")
+	if _, err := os.Stat(bare); err == nil {
+		return bare
+	}
+	return filepath.Join(root, r.Org, r.Repo)
+}
+
+// NewGitSource parses a git source specification:
+// - "" or "github" clones from GitHub,
+// - "file://<path>" or "<path>" clones from LocalSource rooted at <path>.
+func NewGitSource(spec string) (GitSource, error) {
+	if spec == "" || spec == "github" {
+		return GitHubSource{}, nil
+	}
+	root := strings.TrimPrefix(spec, "file://")
+	if fi, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("failed to stat git source %q: %w", spec, err)
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("git source %q is not a directory", spec)
+	}
+	return LocalSource{Root: root}, nil
+}
+
+// Workspace is where repositories are cloned from and into.
+type Workspace struct {
+	// Source is where repositories are cloned and fetched from, GitHubSource when nil.
+	Source GitSource
+	// Root is the directory where repositories are cloned into, an empty string means the
+	// current working directory.
+	Root string
+}
+
+// RegisterWorkspaceFlags registers the --git-source and --workspace flags in fs, the returned
+// workspace is configured when fs is parsed.
+func RegisterWorkspaceFlags(fs *flag.FlagSet) *Workspace {
+	w := &Workspace{Source: GitHubSource{}}
+	fs.Var(&gitSourceFlag{source: &w.Source}, "git-source", "Where repositories are cloned from: 'github', or a local directory (or file:// URL) containing <org>/<repo>[.git] repositories")
+	fs.StringVar(&w.Root, "workspace", "", "Directory where repositories are cloned into (default current directory)")
+	return w
+}
+
+// gitSourceFlag is a flag.Value parsing the git source with NewGitSource.
+type gitSourceFlag struct {
+	source *GitSource
+	spec   string
+}
+
+func (f *gitSourceFlag) String() string {
+	if f == nil || f.spec == "" {
+		return "github"
+	}
+	return f.spec
+}
+
+func (f *gitSourceFlag) Set(spec string) error {
+	s, err := NewGitSource(spec)
+	if err != nil {
+		return err
+	}
+	*f.source = s
+	f.spec = spec
+	return nil
+}
+
+// RemoteURL returns the URL, or path, the repository is cloned and fetched from.
+func (w *Workspace) RemoteURL(r Repository) string {
+	if w == nil || w.Source == nil {
+		return GitHubSource{}.RemoteURL(r)
+	}
+	return w.Source.RemoteURL(r)
+}
+
+// LocalDirectory returns the path of the local clone of the repository in the workspace.
+func (w *Workspace) LocalDirectory(r Repository) string {
+	dir := r.RepositoryDirectory()
+	if dir == "" || w == nil || w.Root == "" {
+		return dir
+	}
+	return filepath.Join(w.Root, dir)
+}
+
+type workspaceKey struct{}
+
+// WithWorkspace returns a context in which repository operations use the given workspace.
+func WithWorkspace(ctx context.Context, w *Workspace) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, w)
+}
+
+// WorkspaceFromContext returns the workspace of the context, repositories are cloned from
+// GitHub into the current working directory when the context has none.
+func WorkspaceFromContext(ctx context.Context) *Workspace {
+	if w, ok := ctx.Value(workspaceKey{}).(*Workspace); ok && w != nil {
+		return w
+	}
+	return &Workspace{Source: GitHubSource{}}
+}
+
+// LocalDirectory returns the path of the local clone of the repository in the workspace of the
+// context.
+//
+// Differently from RepositoryDirectory, which is the logical "<org>/<repo>" path used in
+// generated configurations, LocalDirectory must be used for file system access.
+func (r Repository) LocalDirectory(ctx context.Context) string {
+	return WorkspaceFromContext(ctx).LocalDirectory(r)
+}
+
+// relativeToRepository returns the path of a file in the local clone relative to the
+// repository root.
+func relativeToRepository(ctx context.Context, r Repository, path string) string {
+	rel, err := filepath.Rel(r.LocalDirectory(ctx), path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,195 @@
+package prowgen
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewGitSource(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    GitSource
+		wantErr bool
+	}{
+		{name: "default", spec: "", want: GitHubSource{}},
+		{name: "github", spec: "github", want: GitHubSource{}},
+		{name: "path", spec: dir, want: LocalSource{Root: dir}},
+		{name: "file URL", spec: "file://" + dir, want: LocalSource{Root: dir}},
+		{name: "non existing path", spec: filepath.Join(dir, "non-existing"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewGitSource(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewGitSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("NewGitSource() (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestLocalSourceRemoteURL(t *testing.T) {
+	root := t.TempDir()
+	r := Repository{Org: "openshift-knative", Repo: "serving"}
+
+	s := LocalSource{Root: root}
+	if got, want := s.RemoteURL(r), filepath.Join(root, "openshift-knative", "serving"); got != want {
+		t.Errorf("RemoteURL() want %q, got %q", want, got)
+	}
+
+	bare := filepath.Join(root, "openshift-knative", "serving.git")
+	if err := os.MkdirAll(bare, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.RemoteURL(r); got != bare {
+		t.Errorf("RemoteURL() want %q, got %q", bare, got)
+	}
+}
+
+func TestNewGenerateConfigsLocalSource(t *testing.T) {
+	r := Repository{
+		Org:         "testorg",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests:    []E2ETest{{Match: "test-e2e$"}},
+	}
+
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, r.Org, r.Repo+".git"), "release-next")
+
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	cc := CommonConfig{
+		Branches: map[string]Branch{
+			"release-next": {
+				OpenShiftVersions: []OpenShift{{Version: "4.14", SkipCron: true}},
+			},
+		},
+	}
+	cfgs, err := NewGenerateConfigs(ctx, r, cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfgs) != 1 {
+		t.Fatalf("expected 1 config, got %d", len(cfgs))
+	}
+
+	if want := filepath.Join("testorg", "serving", "testorg-serving-release-next__414.yaml"); cfgs[0].Path != want {
+		t.Errorf("want path %q, got %q", want, cfgs[0].Path)
+	}
+	var tests []string
+	for _, tst := range cfgs[0].Tests {
+		tests = append(tests, tst.As)
+	}
+	if diff := cmp.Diff([]string{"test-e2e"}, tests); diff != "" {
+		t.Error("tests (-want, +got):", diff)
+	}
+	var images []string
+	for _, img := range cfgs[0].Images.Items {
+		images = append(images, string(img.To))
+	}
+	if diff := cmp.Diff([]string{
+		"knative-serving-autoscaler",
+		"knative-serving-migrate",
+		"knative-serving-scale-from-zero",
+		"knative-serving-test-webhook",
+		"knative-serving-source-image",
+	}, images); diff != "" {
+		t.Error("images (-want, +got):", diff)
+	}
+	if _, err := os.Stat(filepath.Join(WorkspaceFromContext(ctx).Root, "testorg", "serving", "Makefile")); err != nil {
+		t.Error("expected repository to be cloned into the workspace:", err)
+	}
+}
+
+// withWorkspace returns the context of the test with the given git source and workspace root.
+func withWorkspace(t *testing.T, source GitSource, root string) context.Context {
+	t.Helper()
+	return WithWorkspace(context.Background(), &Workspace{Source: source, Root: root})
+}
+
+// seedBareRepository creates a bare repository at dst with a single commit containing the files in src
+// on the given branch.
+func seedBareRepository(t *testing.T, src string, dst string, branch string) {
+	t.Helper()
+
+	work := t.TempDir()
+	if err := os.CopyFS(work, os.DirFS(src)); err != nil {
+		t.Fatal(err)
+	}
+	git(t, work, "init", "--initial-branch", branch)
+	git(t, work, "add", ".")
+	git(t, work, "-c", "user.name=prowgen", "-c", "user.email=prowgen@example.com", "commit", "-m", "seed")
+	git(t, "", "clone", "--bare", work, dst)
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestRegisterWorkspaceFlags(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    *Workspace
+		wantErr bool
+	}{
+		{name: "default", want: &Workspace{Source: GitHubSource{}}},
+		{name: "local source", args: []string{"--git-source", "file://" + dir, "--workspace", "ws"}, want: &Workspace{Source: LocalSource{Root: dir}, Root: "ws"}},
+		{name: "non existing source", args: []string{"--git-source", filepath.Join(dir, "non-existing")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			got := RegisterWorkspaceFlags(fs)
+			if err := fs.Parse(tt.args); (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("workspace (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaceFromContext(t *testing.T) {
+	r := Repository{Org: "openshift-knative", Repo: "serving"}
+
+	if got, want := r.LocalDirectory(context.Background()), filepath.Join("openshift-knative", "serving"); got != want {
+		t.Errorf("LocalDirectory() want %q, got %q", want, got)
+	}
+	if got, want := WorkspaceFromContext(context.Background()).RemoteURL(r), "https://github.com/openshift-knative/serving.git"; got != want {
+		t.Errorf("RemoteURL() want %q, got %q", want, got)
+	}
+
+	ctx := WithWorkspace(context.Background(), &Workspace{Source: LocalSource{Root: "/mirrors"}, Root: "/workspace"})
+	if got, want := r.LocalDirectory(ctx), filepath.Join("/workspace", "openshift-knative", "serving"); got != want {
+		t.Errorf("LocalDirectory() want %q, got %q", want, got)
+	}
+	if got, want := WorkspaceFromContext(ctx).RemoteURL(r), filepath.Join("/mirrors", "openshift-knative", "serving"); got != want {
+		t.Errorf("RemoteURL() want %q, got %q", want, got)
+	}
+}
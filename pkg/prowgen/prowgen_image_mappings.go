@@ -0,0 +1,163 @@
+package prowgen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ImageMapping maps external pull specs to an image stream tag that ci-operator can resolve, so
+// that images referenced by Dockerfiles are added as base images and image inputs.
+//
+// Repository mappings take precedence over the default ones (see defaultImageMappings), within
+// each set a mapping with a tag takes precedence over a mapping with a wildcard tag, which takes
+// precedence over a mapping without tag, mappings are otherwise evaluated in order.
+type ImageMapping struct {
+	// From is the pull spec to map, <registry>/<repository>[:<tag>], the tag can contain a
+	// single * wildcard, for example, rhel_9_golang_*. A pull spec without tag matches any tag
+	// or digest.
+	From string `json:"from,omitempty" yaml:"from,omitempty"`
+	// To is the image stream tag, <namespace>/<name>:<tag>, * is replaced with the part of the
+	// tag matched by the From wildcard, or with the whole tag when From has no tag.
+	To string `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// defaultImageMappings map the UBI minimal images to the corresponding images in the CI registry,
+// other images, for example, Go builders tied to an OpenShift release, are mapped by the
+// repositories configurations.
+var defaultImageMappings = []ImageMapping{
+	{From: "registry.access.redhat.com/ubi8-minimal", To: "ocp/ubi-minimal:8"},
+	{From: "registry.access.redhat.com/ubi8/ubi-minimal", To: "ocp/ubi-minimal:8"},
+	{From: "registry.access.redhat.com/ubi9-minimal", To: "ocp/ubi-minimal:9"},
+	{From: "registry.access.redhat.com/ubi9/ubi-minimal", To: "ocp/ubi-minimal:9"},
+}
+
+// imageMapper resolves pull specs to image stream tags, see ImageMapping.
+type imageMapper struct {
+	mappings []imageMapping
+}
+
+type imageMapping struct {
+	ImageMapping
+
+	repository string
+	// tag is empty when From has no tag.
+	tag string
+	to  orgRepoTag
+}
+
+// newImageMapper returns an imageMapper for the given repository mappings, followed by the
+// default ones.
+func newImageMapper(mappings []ImageMapping) (*imageMapper, error) {
+	m := &imageMapper{}
+	for _, set := range [][]ImageMapping{mappings, defaultImageMappings} {
+		parsed := make([]imageMapping, 0, len(set))
+		for _, mapping := range set {
+			p, err := parseImageMapping(mapping)
+			if err != nil {
+				return nil, err
+			}
+			parsed = append(parsed, p)
+		}
+		sort.SliceStable(parsed, func(i, j int) bool {
+			return parsed[i].precedence() > parsed[j].precedence()
+		})
+		m.mappings = append(m.mappings, parsed...)
+	}
+	return m, nil
+}
+
+func parseImageMapping(mapping ImageMapping) (imageMapping, error) {
+	p := imageMapping{ImageMapping: mapping}
+
+	p.repository, p.tag = splitPullSpec(mapping.From)
+	if !strings.Contains(p.repository, "/") || strings.Contains(p.repository, "*") || strings.Contains(mapping.From, "@") {
+		return p, fmt.Errorf("invalid image mapping from %q, expected <registry>/<repository>[:<tag>]", mapping.From)
+	}
+	if strings.Count(p.tag, "*") > 1 {
+		return p, fmt.Errorf("invalid image mapping from %q, the tag can contain a single * wildcard", mapping.From)
+	}
+
+	namespace, nameTag, _ := strings.Cut(mapping.To, "/")
+	name, tag, _ := strings.Cut(nameTag, ":")
+	if namespace == "" || name == "" || tag == "" || strings.Contains(tag, "/") {
+		return p, fmt.Errorf("invalid image mapping to %q, expected <namespace>/<name>:<tag>", mapping.To)
+	}
+	if strings.Contains(mapping.To, "*") && p.tag != "" && !strings.Contains(p.tag, "*") {
+		return p, fmt.Errorf("invalid image mapping to %q, * requires a wildcard tag or no tag in %q", mapping.To, mapping.From)
+	}
+	p.to = orgRepoTag{Org: namespace, Repo: name, Tag: tag}
+	return p, nil
+}
+
+func (m imageMapping) precedence() int {
+	switch {
+	case m.tag == "":
+		return 0
+	case strings.Contains(m.tag, "*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// match returns the image stream tag of the pull spec, if it matches.
+func (m imageMapping) match(pullSpec string) (orgRepoTag, bool) {
+	repository, tag := splitPullSpec(pullSpec)
+	if repository != m.repository {
+		return orgRepoTag{}, false
+	}
+
+	var matched string
+	switch {
+	case m.tag == "":
+		matched = tag
+		if matched == "" {
+			matched = "latest"
+		}
+	case strings.Contains(m.tag, "*"):
+		prefix, suffix, _ := strings.Cut(m.tag, "*")
+		if len(tag) < len(prefix)+len(suffix) || !strings.HasPrefix(tag, prefix) || !strings.HasSuffix(tag, suffix) {
+			return orgRepoTag{}, false
+		}
+		matched = tag[len(prefix) : len(tag)-len(suffix)]
+	case tag == m.tag || (tag == "" && m.tag == "latest"):
+	default:
+		return orgRepoTag{}, false
+	}
+
+	return orgRepoTag{
+		Org:  strings.ReplaceAll(m.to.Org, "*", matched),
+		Repo: strings.ReplaceAll(m.to.Repo, "*", matched),
+		Tag:  strings.ReplaceAll(m.to.Tag, "*", matched),
+	}, true
+}
+
+// resolve returns the image stream tag for the pull spec, images in the CI registry that aren't
+// mapped resolve to the corresponding image stream tag.
+func (m *imageMapper) resolve(pullSpec string) (orgRepoTag, bool, error) {
+	for _, mapping := range m.mappings {
+		if ort, ok := mapping.match(pullSpec); ok {
+			return ort, true, nil
+		}
+	}
+	if match := ciRegistryRegex.FindString(pullSpec); match != "" {
+		ort, err := orgRepoTagFromPullString(match)
+		if err != nil {
+			return ort, false, fmt.Errorf("failed to parse string %s as pullspec: %w", match, err)
+		}
+		return ort, true, nil
+	}
+	return orgRepoTag{}, false, nil
+}
+
+// splitPullSpec returns the repository, including the registry, and the tag of the pull spec,
+// the tag is empty when the pull spec has no tag, for example, when it references a digest.
+func splitPullSpec(pullSpec string) (string, string) {
+	pullSpec, _, _ = strings.Cut(pullSpec, "@")
+	i := strings.LastIndex(pullSpec, ":")
+	if i < 0 || strings.Contains(pullSpec[i:], "/") {
+		return pullSpec, ""
+	}
+	return pullSpec[:i], pullSpec[i+1:]
+}
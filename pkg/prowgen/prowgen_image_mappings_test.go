@@ -0,0 +1,163 @@
+package prowgen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImageMapperResolve(t *testing.T) {
+	mapper, err := newImageMapper([]ImageMapping{
+		{From: "registry.access.redhat.com/ubi9/nodejs-20", To: "openshift-knative/nodejs:20"},
+		{From: "quay.io/openshift-knative/builder:*", To: "openshift-knative/builder:*"},
+		{From: "quay.io/openshift-knative/builder:v1.0", To: "openshift-knative/builder:stable"},
+		{From: "registry.ci.openshift.org/openshift/release:golang-1.21", To: "openshift/release:golang-1.22"},
+		{From: "brew.registry.redhat.io/rh-osbs/openshift-golang-builder:rhel_9_golang_*", To: "openshift/release:rhel-9-release-golang-*-openshift-4.21"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pullSpec string
+		want     orgRepoTag
+		wantOK   bool
+	}{
+		{
+			pullSpec: "registry.access.redhat.com/ubi8/ubi-minimal",
+			want:     orgRepoTag{Org: "ocp", Repo: "ubi-minimal", Tag: "8"},
+			wantOK:   true,
+		},
+		{
+			pullSpec: "registry.access.redhat.com/ubi9-minimal:latest",
+			want:     orgRepoTag{Org: "ocp", Repo: "ubi-minimal", Tag: "9"},
+			wantOK:   true,
+		},
+		{
+			pullSpec: "registry.access.redhat.com/ubi9/ubi-minimal@sha256:0123456789abcdef",
+			want:     orgRepoTag{Org: "ocp", Repo: "ubi-minimal", Tag: "9"},
+			wantOK:   true,
+		},
+		{
+			// Repository mappings take precedence over the default ones.
+			pullSpec: "registry.access.redhat.com/ubi9/nodejs-20:latest",
+			want:     orgRepoTag{Org: "openshift-knative", Repo: "nodejs", Tag: "20"},
+			wantOK:   true,
+		},
+		{
+			pullSpec: "brew.registry.redhat.io/rh-osbs/openshift-golang-builder:rhel_9_golang_1.25",
+			want:     orgRepoTag{Org: "openshift", Repo: "release", Tag: "rhel-9-release-golang-1.25-openshift-4.21"},
+			wantOK:   true,
+		},
+		{
+			// Only the UBI minimal images are mapped by default.
+			pullSpec: "registry.redhat.io/openshift4/ose-cli-artifacts:v4.17",
+		},
+		{
+			pullSpec: "registry.access.redhat.com/ubi9/openjdk-21",
+		},
+		{
+			pullSpec: "quay.io/openshift-knative/builder:v1.1",
+			want:     orgRepoTag{Org: "openshift-knative", Repo: "builder", Tag: "v1.1"},
+			wantOK:   true,
+		},
+		{
+			// A tag takes precedence over a wildcard tag.
+			pullSpec: "quay.io/openshift-knative/builder:v1.0",
+			want:     orgRepoTag{Org: "openshift-knative", Repo: "builder", Tag: "stable"},
+			wantOK:   true,
+		},
+		{
+			pullSpec: "registry.ci.openshift.org/openshift/release:golang-1.21",
+			want:     orgRepoTag{Org: "openshift", Repo: "release", Tag: "golang-1.22"},
+			wantOK:   true,
+		},
+		{
+			pullSpec: "registry.ci.openshift.org/ocp/4.17:cli-artifacts",
+			want:     orgRepoTag{Org: "ocp", Repo: "4.17", Tag: "cli-artifacts"},
+			wantOK:   true,
+		},
+		{
+			pullSpec: "brew.registry.redhat.io/rh-osbs/openshift-golang-builder:v1.22",
+		},
+		{
+			pullSpec: "quay.io/openshift-knative/other:v1.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pullSpec, func(t *testing.T) {
+			got, ok, err := mapper.resolve(tt.pullSpec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("expected resolved %v, got %v (%v)", tt.wantOK, ok, got)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImageMappingErrors(t *testing.T) {
+	tests := []ImageMapping{
+		{From: "ubi-minimal", To: "ocp/ubi-minimal:9"},
+		{From: "quay.io/*/builder", To: "ocp/builder:latest"},
+		{From: "quay.io/openshift-knative/builder:*-*", To: "ocp/builder:*"},
+		{From: "quay.io/openshift-knative/builder@sha256:0123", To: "ocp/builder:latest"},
+		{From: "quay.io/openshift-knative/builder", To: "builder:latest"},
+		{From: "quay.io/openshift-knative/builder", To: "ocp/builder"},
+		{From: "quay.io/openshift-knative/builder:v1", To: "ocp/builder:*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.From+" "+tt.To, func(t *testing.T) {
+			if _, err := newImageMapper([]ImageMapping{tt}); err == nil {
+				t.Errorf("expected error for %+v", tt)
+			}
+		})
+	}
+}
+
+func TestDiscoverUnmappedImages(t *testing.T) {
+	root := t.TempDir()
+	r := Repository{Org: "openshift-knative", Repo: "serving", ImageMappings: []ImageMapping{
+		{From: "quay.io/openshift-knative/builder:*", To: "openshift-knative/builder:*"},
+	}}
+	ctx := withWorkspace(t, LocalSource{Root: root}, root)
+
+	dockerfiles := map[string]string{
+		"openshift/ci-operator/knative-images/controller/Dockerfile": `FROM quay.io/openshift-knative/builder:v1.0 AS builder
+FROM quay.io/openshift-knative/base:latest
+COPY --from=builder /controller /controller
+COPY --from=docker.io/library/busybox:1.36 /bin/sh /bin/sh
+`,
+		"openshift/ci-operator/knative-images/webhook/Dockerfile": `FROM registry.access.redhat.com/ubi9/ubi-minimal
+FROM quay.io/openshift-knative/base:latest
+`,
+	}
+	for path, content := range dockerfiles {
+		path = filepath.Join(r.LocalDirectory(ctx), path)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := discoverUnmappedImages(ctx, r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []UnmappedImage{
+		{Image: "quay.io/openshift-knative/base:latest", Dockerfile: "openshift/ci-operator/knative-images/controller/Dockerfile"},
+		{Image: "docker.io/library/busybox:1.36", Dockerfile: "openshift/ci-operator/knative-images/controller/Dockerfile"},
+		{Image: "quay.io/openshift-knative/base:latest", Dockerfile: "openshift/ci-operator/knative-images/webhook/Dockerfile"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+}
@@ -1,7 +1,7 @@
 package prowgen
 
 import (
-	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -24,18 +24,7 @@ const (
 )
 
 var (
-	ciRegistryRegex   = regexp.MustCompile(`registry\.(|svc\.)ci\.openshift\.org/\S+`)
-	ubiMinimal8Regex1 = regexp.MustCompile(`registry\.access\.redhat\.com/ubi8-minimal\S*`)
-	ubiMinimal8Regex2 = regexp.MustCompile(`registry\.access\.redhat\.com/ubi8/ubi-minimal\S*`)
-	ubiMinimal9Regex1 = regexp.MustCompile(`registry\.access\.redhat\.com/ubi9-minimal\S*`)
-	ubiMinimal9Regex2 = regexp.MustCompile(`registry\.access\.redhat\.com/ubi9/ubi-minimal\S*`)
-
-	imageOverrides = map[*regexp.Regexp]orgRepoTag{
-		ubiMinimal8Regex1: {Org: "ocp", Repo: "ubi-minimal", Tag: "8"},
-		ubiMinimal8Regex2: {Org: "ocp", Repo: "ubi-minimal", Tag: "8"},
-		ubiMinimal9Regex1: {Org: "ocp", Repo: "ubi-minimal", Tag: "9"},
-		ubiMinimal9Regex2: {Org: "ocp", Repo: "ubi-minimal", Tag: "9"},
-	}
+	ciRegistryRegex = regexp.MustCompile(`registry\.(|svc\.)ci\.openshift\.org/\S+`)
 
 	defaultDockerfileIncludes = []string{
 		"openshift/ci-operator/.*images?.*",
@@ -57,10 +46,18 @@ func (ort orgRepoTag) String() string {
 	return ort.Org + "_" + ort.Repo + "_" + ort.Tag
 }
 
-func DiscoverImages(r Repository, skipDockerFiles []string) ReleaseBuildConfigurationOption {
+// UnmappedImage is an external image referenced by a Dockerfile that isn't mapped to an image
+// stream tag (see ImageMapping), ci-operator can't resolve it and builds pull it directly.
+type UnmappedImage struct {
+	Image string `json:"image"`
+	// Dockerfile is the path of the Dockerfile, relative to the repository root.
+	Dockerfile string `json:"dockerfile"`
+}
+
+func DiscoverImages(ctx context.Context, r Repository, skipDockerFiles []string) ReleaseBuildConfigurationOption {
 	return func(cfg *cioperatorapi.ReleaseBuildConfiguration) error {
 		log.Println(r.RepositoryDirectory(), "Discovering images")
-		opts, err := discoverImages(r, skipDockerFiles)
+		opts, _, err := discoverImages(ctx, r, skipDockerFiles)
 		if err != nil {
 			return err
 		}
@@ -69,34 +66,56 @@ func DiscoverImages(r Repository, skipDockerFiles []string) ReleaseBuildConfigur
 	}
 }
 
-func discoverImages(r Repository, skipDockerFiles []string) ([]ReleaseBuildConfigurationOption, error) {
-	dockerfiles, err := discoverDockerfiles(r, skipDockerFiles)
+// discoverUnmappedImages returns the external images referenced by the Dockerfiles that aren't
+// mapped to an image stream tag, sorted by Dockerfile and image.
+func discoverUnmappedImages(ctx context.Context, r Repository, skipDockerFiles []string) ([]UnmappedImage, error) {
+	_, unmapped, err := discoverImages(ctx, r, skipDockerFiles)
 	if err != nil {
 		return nil, err
 	}
+	for _, u := range unmapped {
+		log.Println(r.RepositoryDirectory(), "Image", u.Image, "referenced by", u.Dockerfile, "isn't mapped to an image stream tag")
+	}
+	return unmapped, nil
+}
+
+func discoverImages(ctx context.Context, r Repository, skipDockerFiles []string) ([]ReleaseBuildConfigurationOption, []UnmappedImage, error) {
+	mapper, err := newImageMapper(r.ImageMappings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
+	}
+
+	dockerfiles, err := discoverDockerfiles(ctx, r, skipDockerFiles)
+	if err != nil {
+		return nil, nil, err
+	}
 	sort.Strings(dockerfiles)
 
 	log.Println(r.RepositoryDirectory(), "Discovered Dockerfiles", dockerfiles)
 
 	options := make([]ReleaseBuildConfigurationOption, 0, len(dockerfiles))
+	var unmapped []UnmappedImage
 
 	for _, dockerfile := range dockerfiles {
-		requiredBaseImages, inputImages, err := discoverInputImages(dockerfile)
+		requiredBaseImages, inputImages, unmappedImages, err := discoverInputImages(dockerfile, mapper)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		for _, image := range unmappedImages {
+			unmapped = append(unmapped, UnmappedImage{Image: image, Dockerfile: relativeToRepository(ctx, r, dockerfile)})
 		}
 
 		options = append(options,
 			WithBaseImages(requiredBaseImages),
 			WithImage(ProjectDirectoryImageBuildStepConfigurationFuncFromImageInput(r, ImageInput{
 				Context:        discoverImageContext(dockerfile),
-				DockerfilePath: strings.Join(strings.Split(dockerfile, string(os.PathSeparator))[2:], string(os.PathSeparator)),
+				DockerfilePath: relativeToRepository(ctx, r, dockerfile),
 				Inputs:         inputImages,
 			})),
 		)
 	}
 
-	return options, nil
+	return options, unmapped, nil
 }
 
 func discoverImageContext(dockerfile string) imageContext {
@@ -107,7 +126,7 @@ func discoverImageContext(dockerfile string) imageContext {
 	return context
 }
 
-func discoverDockerfiles(r Repository, skipDockerFiles []string) ([]string, error) {
+func discoverDockerfiles(ctx context.Context, r Repository, skipDockerFiles []string) ([]string, error) {
 	dockerFilesToInclude := defaultDockerfileIncludes
 	if len(r.Dockerfiles.Matches) != 0 {
 		dockerFilesToInclude = r.Dockerfiles.Matches
@@ -135,7 +154,7 @@ func discoverDockerfiles(r Repository, skipDockerFiles []string) ([]string, erro
 	}
 
 	dockerfiles := sets.NewString()
-	rootDir := r.RepositoryDirectory()
+	rootDir := r.LocalDirectory(ctx)
 	err = filepath.Walk(rootDir, func(path string, info fs.FileInfo, err error) error {
 		if info.IsDir() || !strings.HasSuffix(info.Name(), "Dockerfile") {
 			return nil
@@ -176,7 +195,7 @@ func discoverDockerfiles(r Repository, skipDockerFiles []string) ([]string, erro
 		return nil, fmt.Errorf("failed while discovering container images: %w", err)
 	}
 
-	srcImageDockerfile, err := discoverSourceImageDockerfile(r)
+	srcImageDockerfile, err := discoverSourceImageDockerfile(ctx, r)
 	if err != nil {
 		return nil, err
 	}
@@ -187,8 +206,8 @@ func discoverDockerfiles(r Repository, skipDockerFiles []string) ([]string, erro
 	return dockerfiles.List(), nil
 }
 
-func discoverSourceImageDockerfile(r Repository) (string, error) {
-	srcImageDockerfile := filepath.Join(r.RepositoryDirectory(), "openshift", "ci-operator", "source-image", "Dockerfile")
+func discoverSourceImageDockerfile(ctx context.Context, r Repository) (string, error) {
+	srcImageDockerfile := filepath.Join(r.LocalDirectory(ctx), "openshift", "ci-operator", "source-image", "Dockerfile")
 	if _, err := os.Stat(srcImageDockerfile); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return "", nil
@@ -198,146 +217,86 @@ func discoverSourceImageDockerfile(r Repository) (string, error) {
 	return srcImageDockerfile, nil
 }
 
-func discoverInputImages(dockerfile string) (map[string]cioperatorapi.ImageStreamTagReference, map[string]cioperatorapi.ImageBuildInputs, error) {
-	imagePaths, err := getPullStringsFromDockerfile(dockerfile)
+// discoverInputImages returns the base images and the image inputs of the Dockerfile and the
+// referenced external images that aren't mapped, in order.
+func discoverInputImages(dockerfile string, mapper *imageMapper) (map[string]cioperatorapi.ImageStreamTagReference, map[string]cioperatorapi.ImageBuildInputs, []string, error) {
+	images, err := loadDockerfileImages(dockerfile)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not get pull images from dockerfile: %w", err)
+		return nil, nil, nil, fmt.Errorf("could not get pull images from dockerfile: %w", err)
 	}
 
 	requiredBaseImages := make(map[string]cioperatorapi.ImageStreamTagReference)
 	inputImages := make(map[string]cioperatorapi.ImageBuildInputs)
+	unmapped := sets.NewString()
+	var unmappedImages []string
 
-	for _, imagePath := range imagePaths {
-		if imagePath == srcImage {
-			inputImages[srcImage] = cioperatorapi.ImageBuildInputs{As: []string{srcImage}}
-		} else {
-			orgRepoTag, err := getOrgRepoTag(imagePath)
-			if err != nil {
-				return nil, nil, err
-			}
-
-			requiredBaseImages[orgRepoTag.String()] = cioperatorapi.ImageStreamTagReference{
-				Namespace: orgRepoTag.Org,
-				Name:      orgRepoTag.Repo,
-				Tag:       orgRepoTag.Tag,
-			}
-
-			inputs := inputImages[orgRepoTag.String()]
-			inputs.As = sets.NewString(inputs.As...).Insert(imagePath).List() //different registries can resolve to the same orgRepoTag
-			inputImages[orgRepoTag.String()] = inputs
-		}
-	}
-
-	// Also parse args and possibly generate inputs for any pull specs defined in args.
-	args, err := getArgsWithPullSpec(dockerfile)
-	if err != nil {
-		return nil, nil, fmt.Errorf("could not get args from dockerfile: %w", err)
-	}
-	for arg, imagePath := range args {
-		orgRepoTag, err := getOrgRepoTag(imagePath)
-		if err != nil {
-			return nil, nil, err
-		}
+	addInput := func(orgRepoTag orgRepoTag, as string) {
 		requiredBaseImages[orgRepoTag.String()] = cioperatorapi.ImageStreamTagReference{
 			Namespace: orgRepoTag.Org,
 			Name:      orgRepoTag.Repo,
 			Tag:       orgRepoTag.Tag,
 		}
+
 		inputs := inputImages[orgRepoTag.String()]
-		// Add the arg as variable formatted as $XYZ. This allows specifying "FROM $XYZ" in Dockerfile
-		// and CI operator will still be able to replace the image.
-		inputs.As = sets.NewString(inputs.As...).Insert(fmt.Sprintf("$%s", arg)).List()
+		inputs.As = sets.NewString(inputs.As...).Insert(as).List() //different registries can resolve to the same orgRepoTag
 		inputImages[orgRepoTag.String()] = inputs
 	}
 
-	return requiredBaseImages, inputImages, nil
-}
-
-func getOrgRepoTag(imagePath string) (*orgRepoTag, error) {
-	orgRepoTag, err := orgRepoTagFromPullString(imagePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse string %s as pullspec: %w", imagePath, err)
-	}
-	for k, override := range imageOverrides {
-		if k.FindString(imagePath) != "" {
-			orgRepoTag = override
-			break
-		}
-	}
-	return &orgRepoTag, nil
-}
-
-func getPullStringsFromDockerfile(filename string) ([]string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open Dockerfile %s: %w", filename, err)
-	}
-	defer file.Close()
-
-	var images []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !(strings.Contains(line, "FROM ") || strings.Contains(line, "--from=")) {
+	for _, image := range images.External {
+		if image.Image == srcImage {
+			inputImages[srcImage] = cioperatorapi.ImageBuildInputs{As: []string{srcImage}}
 			continue
 		}
-
-		if match := matchKnownPullSpec(line); match != "" {
-			images = append(images, match)
+		orgRepoTag, ok, err := mapper.resolve(image.Image)
+		if err != nil {
+			return nil, nil, nil, err
 		}
-
-		if line == "FROM src" {
-			images = append(images, srcImage)
+		if !ok {
+			// Other images are pulled directly.
+			if !unmapped.Has(image.Image) {
+				unmapped.Insert(image.Image)
+				unmappedImages = append(unmappedImages, image.Image)
+			}
+			continue
 		}
+		// CI operator replaces references as written in the Dockerfile, for example "FROM ${BUILDER}".
+		as := image.Image
+		if strings.Contains(image.Reference, "$") {
+			as = image.Reference
+		}
+		addInput(orgRepoTag, as)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read Dockerfile %s: %w", filename, err)
-	}
-
-	return images, nil
-}
-
-func getArgsWithPullSpec(filename string) (map[string]string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open Dockerfile %s: %w", filename, err)
+	// Also generate inputs for any pull specs defined in args. CI operator replaces $XYZ with a
+	// single image, so an ARG whose defaults in different stages resolve to different images
+	// can't be an input.
+	type resolvedArg struct {
+		arg        dockerfileArg
+		orgRepoTag orgRepoTag
 	}
-	defer file.Close()
-
-	args := make(map[string]string)
-	scanner := bufio.NewScanner(file)
-	prefix := "ARG "
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, prefix) {
-			argVal := strings.TrimPrefix(line, prefix)
-			split := strings.Split(argVal, "=")
-			if len(split) == 2 {
-				if match := matchKnownPullSpec(split[1]); match != "" {
-					args[split[0]] = match
-				}
+	resolvedArgs := make(map[string]resolvedArg)
+	for _, arg := range images.Args {
+		orgRepoTag, ok, err := mapper.resolve(arg.Value)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+		if prev, ok := resolvedArgs[arg.Name]; ok {
+			if prev.orgRepoTag != orgRepoTag {
+				return nil, nil, nil, fmt.Errorf("Dockerfile %s: ARG %s has conflicting images %q (line %d) and %q (line %d)",
+					dockerfile, arg.Name, prev.arg.Value, prev.arg.Line, arg.Value, arg.Line)
 			}
+			continue
 		}
+		resolvedArgs[arg.Name] = resolvedArg{arg: arg, orgRepoTag: orgRepoTag}
+		// Add the arg as variable formatted as $XYZ. This allows specifying "FROM $XYZ" in Dockerfile
+		// and CI operator will still be able to replace the image.
+		addInput(orgRepoTag, fmt.Sprintf("$%s", arg.Name))
 	}
 
-	return args, nil
-}
-
-func matchKnownPullSpec(line string) string {
-	var match string
-	match = ciRegistryRegex.FindString(line)
-	if match != "" {
-		return match
-	}
-	// Also include any images for which there are overrides.
-	for r := range imageOverrides {
-		match := r.FindString(line)
-		if match != "" {
-			return match
-		}
-	}
-	return ""
+	return requiredBaseImages, inputImages, unmappedImages, nil
 }
 
 func orgRepoTagFromPullString(pullString string) (orgRepoTag, error) {
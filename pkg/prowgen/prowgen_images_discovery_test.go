@@ -1,6 +1,7 @@
 package prowgen
 
 import (
+	"context"
 	"testing"
 
 	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
@@ -9,6 +10,7 @@ import (
 )
 
 func TestDiscoverImages(t *testing.T) {
+	ctx := context.Background()
 
 	r := Repository{
 		Org:                   "testdata",
@@ -17,7 +19,7 @@ func TestDiscoverImages(t *testing.T) {
 		CanonicalGoRepository: pointer.String("knative.dev/eventing"),
 	}
 
-	options := DiscoverImages(r, nil)
+	options := DiscoverImages(ctx, r, nil)
 
 	expectedImages := []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{
 		{
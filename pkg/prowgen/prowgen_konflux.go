@@ -61,7 +61,7 @@ func GenerateKonflux(ctx context.Context, openshiftRelease Repository, configs [
 					continue
 				}
 
-				dependabotConfig := dependabotgen.NewDependabotConfig()
+				dependabotConfig := repositoryDependabotConfig(r, config.Config)
 
 				for branchName, b := range config.Config.Branches {
 					if b.Konflux != nil && b.Konflux.Enabled {
@@ -78,36 +78,6 @@ func GenerateKonflux(ctx context.Context, openshiftRelease Repository, configs [
 						} else {
 							soVersion = soversion.FromUpstreamVersion(branchName)
 							soBranchName = soversion.BranchName(soVersion)
-
-							if b.DependabotEnabled == nil || *b.DependabotEnabled {
-								dependabotConfig.WithGo(branchName)
-								if r.IsEKB() {
-									dependabotConfig.WithMaven([]string{"/data-plane"}, branchName)
-								}
-								if r.IsEventingIntegrations() {
-									dependabotConfig.WithNPM([]string{
-										"/transform-jsonata",
-									}, branchName)
-									dependabotConfig.WithMaven([]string{"/"}, branchName)
-								}
-								if r.IsBackstagePlugins() {
-									dependabotConfig.WithNPM([]string{
-										"/backstage",
-										"/backstage/plugins/knative-event-mesh-backend",
-										"/backstage/packages/app",
-										"/backstage/packages/backend",
-										"/backstage/plugins/knative-event-mesh-backend/dist-dynamic",
-									}, branchName)
-								}
-								if r.IsFunc() {
-									dependabotConfig.WithMaven([]string{
-										"/templates/quarkus/http",
-										"/templates/quarkus/cloudevents",
-										"/templates/springboot/http",
-										"/templates/springboot/cloudevents",
-									}, branchName)
-								}
-							}
 						}
 
 						log.Printf("targetBranch: %s, soBranchName: %s, soVersion: %s\n", targetBranch, soBranchName, soVersion)
@@ -132,7 +102,7 @@ func GenerateKonflux(ctx context.Context, openshiftRelease Repository, configs [
 							}
 							// For non-existent branches we keep going and use downstreamVersion for versionLabel.
 						} else {
-							soProjectYamlPath := filepath.Join(soRepo.RepositoryDirectory(),
+							soProjectYamlPath := filepath.Join(soRepo.LocalDirectory(ctx),
 								"olm-catalog", "serverless-operator", "project.yaml")
 							soMetadata, err := project.ReadMetadataFile(soProjectYamlPath)
 							if err != nil {
@@ -207,13 +177,13 @@ func GenerateKonflux(ctx context.Context, openshiftRelease Repository, configs [
 
 						nudges := b.Konflux.Nudges
 
-						prefetchDeps, err := getPrefetchDeps(r, targetBranch)
+						prefetchDeps, err := getPrefetchDeps(ctx, r, targetBranch)
 						if err != nil {
 							return fmt.Errorf("could not get prefetchDeps: %w", err)
 						}
 
 						cfg := konfluxgen.Config{
-							OpenShiftReleasePath: openshiftRelease.RepositoryDirectory(),
+							OpenShiftReleasePath: openshiftRelease.LocalDirectory(ctx),
 							ApplicationName:      konfluxgen.AppName(soBranchName),
 							BuildArgs:            buildArgs,
 							Includes: []string{
@@ -222,10 +192,10 @@ func GenerateKonflux(ctx context.Context, openshiftRelease Repository, configs [
 							Excludes:                  b.Konflux.Excludes,
 							ExcludesImages:            b.Konflux.ExcludesImages,
 							JavaImages:                b.Konflux.JavaImages,
-							ResourcesOutputPath:       fmt.Sprintf("%s/.konflux", r.RepositoryDirectory()),
-							RepositoryRootPath:        r.RepositoryDirectory(),
-							GlobalResourcesOutputPath: fmt.Sprintf("%s/.konflux", hackRepo.RepositoryDirectory()),
-							PipelinesOutputPath:       fmt.Sprintf("%s/.tekton", r.RepositoryDirectory()),
+							ResourcesOutputPath:       fmt.Sprintf("%s/.konflux", r.LocalDirectory(ctx)),
+							RepositoryRootPath:        r.LocalDirectory(ctx),
+							GlobalResourcesOutputPath: fmt.Sprintf("%s/.konflux", hackRepo.LocalDirectory(ctx)),
+							PipelinesOutputPath:       fmt.Sprintf("%s/.tekton", r.LocalDirectory(ctx)),
 							Nudges:                    nudges,
 							// Preserve the version tag as first tag in any instance since SO, when bumping the patch version
 							// will change it before merging the PR.
@@ -243,7 +213,7 @@ func GenerateKonflux(ctx context.Context, openshiftRelease Repository, configs [
 							return fmt.Errorf("failed to generate Konflux configurations for %s (%s): %w", r.RepositoryDirectory(), branchName, err)
 						}
 
-						if err := dependabotgen.WriteDependabotWorkflow(r.RepositoryDirectory(), r.RunCodegenCommand()); err != nil {
+						if err := dependabotgen.WriteDependabotWorkflow(r.LocalDirectory(ctx), r.RunCodegenCommand()); err != nil {
 							return fmt.Errorf("[%s][%s] failed to write dependabot workflow: %w", r.RepositoryDirectory(), branchName, err)
 						}
 
@@ -287,7 +257,7 @@ func writeDependabotConfig(ctx context.Context, dependabotConfig *dependabotgen.
 		if err := GitCheckout(ctx, r, dependabotgen.DefaultTargetBranch); err != nil {
 			return err
 		}
-		if err := dependabotConfig.Write(r.RepositoryDirectory(), r.RunCodegenCommand()); err != nil {
+		if err := dependabotConfig.Write(r.LocalDirectory(ctx), r.RunCodegenCommand()); err != nil {
 			return fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
 		}
 
@@ -303,6 +273,49 @@ func writeDependabotConfig(ctx context.Context, dependabotConfig *dependabotgen.
 	return nil
 }
 
+// repositoryDependabotConfig returns the dependabot configuration of the repository, covering
+// the release branches with Konflux enabled.
+func repositoryDependabotConfig(r Repository, cc CommonConfig) *dependabotgen.DependabotConfig {
+	dependabotConfig := dependabotgen.NewDependabotConfig()
+	for _, branchName := range sortedKeys(cc.Branches) {
+		b := cc.Branches[branchName]
+		if b.Konflux == nil || !b.Konflux.Enabled || branchName == "release-next" {
+			continue
+		}
+		if b.DependabotEnabled != nil && !*b.DependabotEnabled {
+			continue
+		}
+		dependabotConfig.WithGo(branchName)
+		if r.IsEKB() {
+			dependabotConfig.WithMaven([]string{"/data-plane"}, branchName)
+		}
+		if r.IsEventingIntegrations() {
+			dependabotConfig.WithNPM([]string{
+				"/transform-jsonata",
+			}, branchName)
+			dependabotConfig.WithMaven([]string{"/"}, branchName)
+		}
+		if r.IsBackstagePlugins() {
+			dependabotConfig.WithNPM([]string{
+				"/backstage",
+				"/backstage/plugins/knative-event-mesh-backend",
+				"/backstage/packages/app",
+				"/backstage/packages/backend",
+				"/backstage/plugins/knative-event-mesh-backend/dist-dynamic",
+			}, branchName)
+		}
+		if r.IsFunc() {
+			dependabotConfig.WithMaven([]string{
+				"/templates/quarkus/http",
+				"/templates/quarkus/cloudevents",
+				"/templates/springboot/http",
+				"/templates/springboot/cloudevents",
+			}, branchName)
+		}
+	}
+	return dependabotConfig
+}
+
 func ServerlessOperatorKonfluxVersions(ctx context.Context) (map[string]string, error) {
 	r := Repository{Org: "openshift-knative", Repo: "serverless-operator"}
 	sortedBranches, err := ReleaseBranches(ctx, r)
@@ -350,7 +363,7 @@ func GenerateKonfluxServerlessOperator(ctx context.Context, openshiftRelease Rep
 	}
 	log.Println("Recreating konflux configurations for serverless operator")
 
-	resourceOutputPath := fmt.Sprintf("%s/.konflux", hackRepo.RepositoryDirectory())
+	resourceOutputPath := fmt.Sprintf("%s/.konflux", hackRepo.LocalDirectory(ctx))
 
 	for release, branch := range konfluxVersions {
 
@@ -381,7 +394,7 @@ func GenerateKonfluxServerlessOperator(ctx context.Context, openshiftRelease Rep
 			log.Printf("Using configuration for branch main")
 		}
 
-		soProjectYamlPath := filepath.Join(r.RepositoryDirectory(),
+		soProjectYamlPath := filepath.Join(r.LocalDirectory(ctx),
 			"olm-catalog", "serverless-operator", "project.yaml")
 		soMetadata, err := project.ReadMetadataFile(soProjectYamlPath)
 		if err != nil {
@@ -410,7 +423,7 @@ func GenerateKonfluxServerlessOperator(ctx context.Context, openshiftRelease Rep
 			buildArgs = append(buildArgs, fmt.Sprintf("CLI_ARTIFACTS=%s", cliImage))
 		}
 
-		prefetchDeps, err := getPrefetchDeps(r, branch)
+		prefetchDeps, err := getPrefetchDeps(ctx, r, branch)
 		if err != nil {
 			return fmt.Errorf("could not get prefetchDeps: %w", err)
 		}
@@ -421,7 +434,7 @@ func GenerateKonfluxServerlessOperator(ctx context.Context, openshiftRelease Rep
 		}
 
 		cfg := konfluxgen.Config{
-			OpenShiftReleasePath: openshiftRelease.RepositoryDirectory(),
+			OpenShiftReleasePath: openshiftRelease.LocalDirectory(ctx),
 			ApplicationName:      konfluxgen.AppName(release),
 			BuildArgs:            buildArgs,
 			ComponentNameFunc: func(cfg cioperatorapi.ReleaseBuildConfiguration, ib cioperatorapi.ProjectDirectoryImageBuildStepConfiguration) string {
@@ -456,13 +469,13 @@ func GenerateKonfluxServerlessOperator(ctx context.Context, openshiftRelease Rep
 			// main with the same name but different "revision" (branch).
 			ResourcesOutputPathSkipRemove: true,
 			ResourcesOutputPath:           resourceOutputPath,
-			RepositoryRootPath:            r.RepositoryDirectory(),
+			RepositoryRootPath:            r.LocalDirectory(ctx),
 			GlobalResourcesOutputPath:     resourceOutputPath,
-			PipelinesOutputPath:           fmt.Sprintf("%s/.tekton", r.RepositoryDirectory()),
+			PipelinesOutputPath:           fmt.Sprintf("%s/.tekton", r.LocalDirectory(ctx)),
 			Nudges:                        b.Konflux.Nudges,
 			ComponentReleasePlanConfig: &konfluxgen.ComponentReleasePlanConfig{
 				FirstRelease:              semverRelease,
-				ClusterServiceVersionPath: filepath.Join(r.RepositoryDirectory(), "olm-catalog", "serverless-operator", "manifests", "serverless-operator.clusterserviceversion.yaml"),
+				ClusterServiceVersionPath: filepath.Join(r.LocalDirectory(ctx), "olm-catalog", "serverless-operator", "manifests", "serverless-operator.clusterserviceversion.yaml"),
 				BundleComponentName:       "serverless-bundle",
 				BundleImageRepoName:       "serverless-operator-bundle",
 			},
@@ -484,11 +497,11 @@ func GenerateKonfluxServerlessOperator(ctx context.Context, openshiftRelease Rep
 			return fmt.Errorf("failed to generate Konflux configurations for %s (%s): %w", r.RepositoryDirectory(), branch, err)
 		}
 
-		if err := generateFBCApplications(soMetadata, openshiftRelease, r, branch, release, resourceOutputPath, buildArgs); err != nil {
+		if err := generateFBCApplications(ctx, soMetadata, openshiftRelease, r, branch, release, resourceOutputPath, buildArgs); err != nil {
 			return fmt.Errorf("failed to generate FBC applications for %s (%s): %w", r.RepositoryDirectory(), branch, err)
 		}
 
-		if err := dependabotgen.WriteDependabotWorkflow(r.RepositoryDirectory(), r.RunCodegenCommand()); err != nil {
+		if err := dependabotgen.WriteDependabotWorkflow(r.LocalDirectory(ctx), r.RunCodegenCommand()); err != nil {
 			return fmt.Errorf("[%s][%s] failed to write dependabot workflow: %w", r.RepositoryDirectory(), branch, err)
 		}
 
@@ -529,20 +542,20 @@ func getCLIArtifactsImage(ocpVersion string) (string, error) {
 	}
 }
 
-func getPrefetchDeps(repo Repository, branch string) (*konfluxgen.PrefetchDeps, error) {
+func getPrefetchDeps(ctx context.Context, repo Repository, branch string) (*konfluxgen.PrefetchDeps, error) {
 	prefetchDeps := konfluxgen.PrefetchDeps{}
-	if _, err := os.Stat(filepath.Join(repo.RepositoryDirectory(), "rpms.lock.yaml")); err == nil {
+	if _, err := os.Stat(filepath.Join(repo.LocalDirectory(ctx), "rpms.lock.yaml")); err == nil {
 		// If rpms.lock.yaml is present enable dev-package-managers and RPM caching
 		prefetchDeps.DevPackageManagers = "true"
 		prefetchDeps.WithRPMs()
 	}
 
-	_, err := os.Stat(filepath.Join(repo.RepositoryDirectory(), "vendor"))
+	_, err := os.Stat(filepath.Join(repo.LocalDirectory(ctx), "vendor"))
 	if err != nil {
 		if !errors.Is(err, fs.ErrNotExist) {
 			return nil, fmt.Errorf("[%s - %s] failed to verify if the project uses Go vendoring: %w", repo.RepositoryDirectory(), branch, err)
 		}
-		if _, err := os.Stat(filepath.Join(repo.RepositoryDirectory(), "go.mod")); err == nil {
+		if _, err := os.Stat(filepath.Join(repo.LocalDirectory(ctx), "go.mod")); err == nil {
 			// If it's a Go project and no vendor dir is present enable Go caching
 			prefetchDeps.WithUnvendoredGo("." /* root of the repository */)
 		}
@@ -555,7 +568,7 @@ func getPrefetchDeps(repo Repository, branch string) (*konfluxgen.PrefetchDeps,
 	return &prefetchDeps, nil
 }
 
-func generateFBCApplications(soMetadata *project.Metadata, openshiftRelease Repository, r Repository, branch string, release string, resourceOutputPath string, buildArgs []string) error {
+func generateFBCApplications(ctx context.Context, soMetadata *project.Metadata, openshiftRelease Repository, r Repository, branch string, release string, resourceOutputPath string, buildArgs []string) error {
 	fbcApps := make([]string, 0, len(soMetadata.Requirements.OcpVersion.List))
 
 	for _, ocpVersion := range soMetadata.Requirements.OcpVersion.List {
@@ -577,13 +590,13 @@ func generateFBCApplications(soMetadata *project.Metadata, openshiftRelease Repo
 		fbcAppName := konfluxgen.FBCAppName(release, ocpVersion)
 
 		c := konfluxgen.Config{
-			OpenShiftReleasePath:      openshiftRelease.RepositoryDirectory(),
+			OpenShiftReleasePath:      openshiftRelease.LocalDirectory(ctx),
 			ApplicationName:           fbcAppName,
 			BuildArgs:                 buildArgs,
 			ResourcesOutputPath:       resourceOutputPath,
-			GlobalResourcesOutputPath: fmt.Sprintf("%s/.konflux", hackRepo.RepositoryDirectory()),
-			RepositoryRootPath:        r.RepositoryDirectory(),
-			PipelinesOutputPath:       fmt.Sprintf("%s/.tekton", r.RepositoryDirectory()),
+			GlobalResourcesOutputPath: fmt.Sprintf("%s/.konflux", hackRepo.LocalDirectory(ctx)),
+			RepositoryRootPath:        r.LocalDirectory(ctx),
+			PipelinesOutputPath:       fmt.Sprintf("%s/.tekton", r.LocalDirectory(ctx)),
 			AdditionalTektonCELExpressionFunc: func(cfg cioperatorapi.ReleaseBuildConfiguration, ib cioperatorapi.ProjectDirectoryImageBuildStepConfiguration) string {
 				return fmt.Sprintf("&& ("+
 					" files.all.exists(x, x.matches('^olm-catalog/serverless-operator-index/v%s/')) ||"+
@@ -0,0 +1,345 @@
+package prowgen
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// MakefileTarget is a target defined in a makefile.
+type MakefileTarget struct {
+	Name string
+	// File is the makefile defining the target, relative to the repository root.
+	File string
+	Line int
+}
+
+// Source returns the position of the target definition, for example, Makefile:12.
+func (t MakefileTarget) Source() string {
+	return fmt.Sprintf("%s:%d", t.File, t.Line)
+}
+
+var (
+	// makefileSpecialTargets are built-in target names with a special meaning for make,
+	// see https://www.gnu.org/software/make/manual/html_node/Special-Targets.html
+	makefileSpecialTargets = sets.NewString(
+		".PHONY",
+		".SUFFIXES",
+		".DEFAULT",
+		".PRECIOUS",
+		".INTERMEDIATE",
+		".NOTINTERMEDIATE",
+		".SECONDARY",
+		".SECONDEXPANSION",
+		".DELETE_ON_ERROR",
+		".IGNORE",
+		".LOW_RESOLUTION_TIME",
+		".SILENT",
+		".EXPORT_ALL_VARIABLES",
+		".NOTPARALLEL",
+		".ONESHELL",
+		".POSIX",
+		".WAIT",
+		".RECIPEPREFIX",
+		".DEFAULT_GOAL",
+	)
+
+	// makefileConditionals are skipped, targets in every branch are discovered.
+	makefileConditionals = sets.NewString("ifeq", "ifneq", "ifdef", "ifndef", "else", "endif")
+
+	makefileAssignmentPattern = regexp.MustCompile(`^(?:(?:export|override|private)\s+)*([^\s:#=$]+)\s*(=|:=|::=|:::=|\?=|\+=|!=)\s*(.*)$`)
+	makefileDefinePattern     = regexp.MustCompile(`^(?:(?:export|override)\s+)*define\s+([^\s:#=]+)`)
+	makefileIncludePattern    = regexp.MustCompile(`^(-include|sinclude|include)\s+(.*)$`)
+)
+
+// maxMakefileExpansionDepth limits the expansion of recursive variables.
+const maxMakefileExpansionDepth = 16
+
+// MakefileTargets returns the targets defined in the given makefile, relative to the repository
+// root, and in the makefiles it includes, in definition order.
+//
+// Only a subset of make is supported: include directives, simple and recursive variables
+// and rules, including double-colon rules and multiple targets per rule. Conditionals aren't
+// evaluated and targets names using make functions are skipped, as well as special targets
+// and pattern rules.
+func MakefileTargets(root string, makefile string) ([]MakefileTarget, error) {
+	p := &makefileParser{
+		root:    root,
+		vars:    map[string]string{},
+		visited: sets.NewString(),
+		seen:    sets.NewString(),
+	}
+	if err := p.parseFile(filepath.Join(root, makefile)); err != nil {
+		return nil, err
+	}
+	return p.targets, nil
+}
+
+type makefileParser struct {
+	root string
+	vars map[string]string
+	// visited are the parsed makefiles, to break include cycles.
+	visited sets.String
+	// seen are the discovered target names, only the first definition of a target is reported.
+	seen    sets.String
+	targets []MakefileTarget
+}
+
+func (p *makefileParser) parseFile(path string) error {
+	if p.visited.Has(path) {
+		return nil
+	}
+	p.visited.Insert(path)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	file, err := filepath.Rel(p.root, path)
+	if err != nil {
+		file = path
+	}
+
+	var (
+		define      string
+		defineValue []string
+		inDefine    bool
+	)
+	for _, l := range makefileLines(string(content)) {
+		if inDefine {
+			if strings.TrimSpace(l.text) == "endef" {
+				p.vars[define] = strings.Join(defineValue, "\n")
+				inDefine = false
+				continue
+			}
+			defineValue = append(defineValue, l.text)
+			continue
+		}
+
+		// Recipes are not relevant to discover targets.
+		if strings.HasPrefix(l.text, "\t") {
+			continue
+		}
+		text := strings.TrimSpace(stripMakefileComment(l.text))
+		if text == "" {
+			continue
+		}
+		if makefileConditionals.Has(strings.Fields(text)[0]) {
+			continue
+		}
+
+		if m := makefileDefinePattern.FindStringSubmatch(text); m != nil {
+			define, defineValue, inDefine = m[1], nil, true
+			continue
+		}
+		if m := makefileIncludePattern.FindStringSubmatch(text); m != nil {
+			if err := p.include(file, l.number, m[1] != "include", m[2]); err != nil {
+				return err
+			}
+			continue
+		}
+		if m := makefileAssignmentPattern.FindStringSubmatch(text); m != nil {
+			p.assign(m[1], m[2], m[3])
+			continue
+		}
+		p.rule(file, l.number, text)
+	}
+	return nil
+}
+
+func (p *makefileParser) include(file string, line int, optional bool, paths string) error {
+	for _, include := range strings.Fields(p.expand(paths, 0)) {
+		if isUnresolvedMakefileExpansion(include) {
+			log.Printf("%s:%d: skipping include %q, make functions are not supported", file, line, include)
+			continue
+		}
+		// make resolves includes relative to the working directory, the repository root.
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(p.root, include)
+		}
+		matches, err := filepath.Glob(include)
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid include %q: %w", file, line, include, err)
+		}
+		if len(matches) == 0 {
+			// Included makefiles might be generated by other rules.
+			if !optional {
+				log.Printf("%s:%d: skipping include %q, file not found", file, line, include)
+			}
+			continue
+		}
+		for _, match := range matches {
+			if err := p.parseFile(match); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *makefileParser) assign(name string, op string, value string) {
+	switch op {
+	case "=":
+		p.vars[name] = value
+	case ":=", "::=", ":::=":
+		p.vars[name] = p.expand(value, 0)
+	case "?=":
+		if _, ok := p.vars[name]; !ok {
+			p.vars[name] = value
+		}
+	case "+=":
+		if existing, ok := p.vars[name]; ok && existing != "" {
+			p.vars[name] = existing + " " + value
+		} else {
+			p.vars[name] = value
+		}
+	case "!=":
+		// Shell assignments are not evaluated.
+	}
+}
+
+func (p *makefileParser) rule(file string, line int, text string) {
+	i := topLevelColon(text)
+	if i < 0 {
+		return
+	}
+	names := strings.TrimSpace(p.expand(text[:i], 0))
+	if isUnresolvedMakefileExpansion(names) {
+		log.Printf("%s:%d: skipping targets %q, make functions are not supported", file, line, names)
+		return
+	}
+	for _, name := range strings.Fields(names) {
+		// Grouped targets, for example, "a b &: c".
+		name = strings.TrimSuffix(name, "&")
+		if name == "" || makefileSpecialTargets.Has(name) || strings.Contains(name, "%") {
+			continue
+		}
+		if p.seen.Has(name) {
+			continue
+		}
+		p.seen.Insert(name)
+		p.targets = append(p.targets, MakefileTarget{Name: name, File: file, Line: line})
+	}
+}
+
+// expand expands variable references in s, references using make functions are left as-is.
+func (p *makefileParser) expand(s string, depth int) string {
+	if depth > maxMakefileExpansionDepth || !strings.Contains(s, "$") {
+		return s
+	}
+	sb := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		next := s[i+1]
+		switch next {
+		case '$':
+			sb.WriteByte('$')
+			i++
+		case '(', '{':
+			end := matchingParen(s, i+1)
+			if end < 0 {
+				sb.WriteString(s[i:])
+				return sb.String()
+			}
+			ref := s[i+2 : end]
+			if strings.ContainsAny(ref, " \t,:=") {
+				// Function call or substitution reference, for example, $(shell ...) or $(SRCS:.c=.o).
+				sb.WriteString(s[i : end+1])
+			} else {
+				sb.WriteString(p.expand(p.vars[p.expand(ref, depth+1)], depth+1))
+			}
+			i = end
+		default:
+			sb.WriteString(p.expand(p.vars[string(next)], depth+1))
+			i++
+		}
+	}
+	return sb.String()
+}
+
+func isUnresolvedMakefileExpansion(s string) bool {
+	return strings.Contains(s, "$(") || strings.Contains(s, "${")
+}
+
+type makefileLine struct {
+	text string
+	// number is the 1-based line number where the logical line starts.
+	number int
+}
+
+// makefileLines returns the logical lines of a makefile, joining lines ending with a backslash.
+func makefileLines(content string) []makefileLine {
+	physical := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	lines := make([]makefileLine, 0, len(physical))
+	for i := 0; i < len(physical); i++ {
+		l := makefileLine{text: physical[i], number: i + 1}
+		for hasContinuation(l.text) && i+1 < len(physical) {
+			i++
+			l.text = strings.TrimRight(strings.TrimSuffix(l.text, "\\"), " \t") + " " + strings.TrimLeft(physical[i], " \t")
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// hasContinuation returns true when the line ends with an odd number of backslashes.
+func hasContinuation(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, "\\"))
+	return n%2 == 1
+}
+
+func stripMakefileComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] != '\\') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// topLevelColon returns the index of the first colon outside variable references, or -1.
+func topLevelColon(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '{':
+			depth++
+		case ')', '}':
+			depth--
+		case ':':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// matchingParen returns the index of the parenthesis closing the one at open, or -1.
+func matchingParen(s string, open int) int {
+	closing := byte(')')
+	if s[open] == '{' {
+		closing = '}'
+	}
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case s[open]:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,123 @@
+package prowgen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMakefileTargets(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"Makefile": `# Main makefile
+include hack/e2e.mk
+-include hack/missing.mk
+
+SUITE := conformance
+PREFIX = test
+ARCHS ?= amd64
+ARCHS += arm64
+
+.PHONY: test-e2e test-unit
+.DEFAULT_GOAL := test-unit
+
+test-unit: ## Run unit tests
+	go test ./...
+
+test-e2e test-e2e-tls: build
+	./test/e2e.sh
+
+$(PREFIX)-$(SUITE):: build
+	./test/$(SUITE).sh
+$(PREFIX)-$(SUITE):: cleanup
+
+test-long: \
+    build \
+    cleanup
+	./test/long.sh
+
+ifeq ($(ARCHS),amd64)
+test-amd64:
+	./test/amd64.sh
+else
+test-multi-arch:
+	./test/multi.sh
+endif
+
+define RUN_TEST
+fake-target-in-define:
+	./run.sh
+endef
+
+gen-a gen-b &: gen.yaml
+	./gen.sh
+
+%.o: %.c
+	cc -c $<
+
+$(foreach arch,$(ARCHS),test-$(arch)):
+	./test.sh
+
+test-reconciler: VERBOSE = true
+test-reconciler:
+	./test/reconciler.sh
+`,
+		"hack/e2e.mk": `include hack/upgrade.mk
+
+test-e2e-local:
+	./test/e2e-local.sh
+`,
+		"hack/upgrade.mk": `# Include cycles are ignored.
+include Makefile
+
+test-upgrade:
+	./test/upgrade.sh
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := MakefileTargets(root, "Makefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upgradeMakefile := filepath.Join("hack", "upgrade.mk")
+	e2eMakefile := filepath.Join("hack", "e2e.mk")
+	want := []MakefileTarget{
+		{Name: "test-upgrade", File: upgradeMakefile, Line: 4},
+		{Name: "test-e2e-local", File: e2eMakefile, Line: 3},
+		{Name: "test-unit", File: "Makefile", Line: 13},
+		{Name: "test-e2e", File: "Makefile", Line: 16},
+		{Name: "test-e2e-tls", File: "Makefile", Line: 16},
+		{Name: "test-conformance", File: "Makefile", Line: 19},
+		{Name: "test-long", File: "Makefile", Line: 23},
+		{Name: "test-amd64", File: "Makefile", Line: 29},
+		{Name: "test-multi-arch", File: "Makefile", Line: 32},
+		{Name: "gen-a", File: "Makefile", Line: 41},
+		{Name: "gen-b", File: "Makefile", Line: 41},
+		{Name: "test-reconciler", File: "Makefile", Line: 50},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("MakefileTargets() (-want, +got):", diff)
+	}
+
+	if got, want := got[0].Source(), upgradeMakefile+":4"; got != want {
+		t.Errorf("Source() = %q, want %q", got, want)
+	}
+}
+
+func TestMakefileTargetsMissingMakefile(t *testing.T) {
+	if _, err := MakefileTargets(t.TempDir(), "Makefile"); err == nil {
+		t.Fatal("expected error")
+	}
+}
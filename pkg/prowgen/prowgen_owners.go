@@ -29,30 +29,11 @@ func GenerateOwners(ctx context.Context, configs []*Config) error {
 					return fmt.Errorf("could not get branches for %q: %w", r.Repo, err)
 				}
 
-				for branchName := range config.Config.Branches {
-					if branchName == "release-next" {
-						// skip updates on release-next
-						continue
-					}
-
-					if !slices.Contains(branchesInGit, branchName) {
-						// some repos have branch configs, but the branches are not cut yet
-						// (e.g. SO with the latest release branch). So we skip those.
-						log.Printf("Skipping branch %q for %q, because banch does not exist in Git yet", branchName, r.Repo)
-						continue
-					}
-
+				for _, branchName := range ownersBranches(r, config.Config, branchesInGit) {
 					if err := createOwnersFile(ctx, r, branchName); err != nil {
 						return fmt.Errorf("failed to create ownersfile for branch %s: %w", branchName, err)
 					}
 				}
-
-				if _, ok := config.Config.Branches["main"]; !ok {
-					// no main branch in config list. Create it out of the loop for main
-					if err := createOwnersFile(ctx, r, "main"); err != nil {
-						return fmt.Errorf("failed to create ownersfile for branch main: %w", err)
-					}
-				}
 			}
 
 			return nil
@@ -66,6 +47,33 @@ func GenerateOwners(ctx context.Context, configs []*Config) error {
 	return nil
 }
 
+// ownersBranches returns the branches with a generated OWNERS file, the configured branches
+// existing in Git, except release-next, and main.
+func ownersBranches(r Repository, cc CommonConfig, branchesInGit []string) []string {
+	var branches []string
+	for _, branchName := range sortedKeys(cc.Branches) {
+		if branchName == "release-next" {
+			// skip updates on release-next
+			continue
+		}
+
+		if !slices.Contains(branchesInGit, branchName) {
+			// some repos have branch configs, but the branches are not cut yet
+			// (e.g. SO with the latest release branch). So we skip those.
+			log.Printf("Skipping branch %q for %q, because banch does not exist in Git yet", branchName, r.Repo)
+			continue
+		}
+
+		branches = append(branches, branchName)
+	}
+
+	if _, ok := cc.Branches["main"]; !ok {
+		// no main branch in config list. Create it out of the loop for main
+		branches = append(branches, "main")
+	}
+	return branches
+}
+
 func createOwnersFile(ctx context.Context, r Repository, branchName string) error {
 	// This is a special GH log format: https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions#example-grouping-log-lines
 	log.Printf("::group::ownersfilegen %s %s\n", r.RepositoryDirectory(), branchName)
@@ -80,7 +88,7 @@ func createOwnersFile(ctx context.Context, r Repository, branchName string) erro
 
 	pushBranch := fmt.Sprintf("%s%s", ownersfilegen.SyncBranchPrefix, branchName)
 
-	if err := ownersfilegen.WriteOwnersFile(r.RepositoryDirectory(), r.Owners.Reviewers, r.Owners.Approvers); err != nil {
+	if err := ownersfilegen.WriteOwnersFile(r.LocalDirectory(ctx), r.Owners.Reviewers, r.Owners.Approvers); err != nil {
 		return fmt.Errorf("[%s][%s] failed to write OWNERS file: %w", r.RepositoryDirectory(), branchName, err)
 	}
 
@@ -0,0 +1,377 @@
+package prowgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift-knative/hack/pkg/verify"
+)
+
+const (
+	PlanFormatHuman = "human"
+	PlanFormatJSON  = "json"
+)
+
+// ChangeType describes how a file or a test changes.
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+)
+
+// Plan is the set of changes that prowgen would apply to the openshift/release repository.
+type Plan struct {
+	Added   []FileChange `json:"added,omitempty"`
+	Removed []FileChange `json:"removed,omitempty"`
+	Changed []FileChange `json:"changed,omitempty"`
+}
+
+// FileChange is a change to a single file, the path is relative to the openshift/release root.
+type FileChange struct {
+	Path string `json:"path"`
+	// Fields are the changed top-level fields, except tests.
+	Fields []string `json:"fields,omitempty"`
+	// Tests are the added, removed or changed tests (by "as").
+	Tests []TestChange `json:"tests,omitempty"`
+}
+
+// TestChange is a change to a single test in a ci-operator config file.
+type TestChange struct {
+	As     string     `json:"as"`
+	Change ChangeType `json:"change"`
+	// Diff is a human-readable (-existing, +generated) diff of a changed test.
+	Diff string `json:"diff,omitempty"`
+}
+
+// NewPlan generates all configurations into a temporary copy of the openshift/release clone and
+// compares them with the existing configurations and jobs, without modifying the clone, see
+// generateDeterminized.
+func NewPlan(ctx context.Context, openShiftRelease Repository, inConfigs []*Config, outputConfig string) (*Plan, error) {
+	dirs, cleanup, err := generateDeterminized(ctx, openShiftRelease, inConfigs, outputConfig)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+	generated := make(map[string][]byte)
+	var removals []string
+	for _, d := range dirs {
+		files, removed, err := verify.DirFiles(d.existing, d.generated, func(string) bool { return true })
+		if err != nil {
+			return nil, err
+		}
+		for path, content := range files {
+			generated[path] = content
+		}
+		removals = append(removals, removed...)
+	}
+	return comparePlan(openShiftRelease.LocalDirectory(ctx), generated, removals)
+}
+
+// determinizedDir is a directory of the openshift/release clone and the corresponding
+// directory of the determinized copy.
+type determinizedDir struct {
+	existing  string
+	generated string
+}
+
+// generateDeterminized generates all configurations into a temporary worktree of the
+// openshift/release clone and runs the openshift/release generator there (see
+// RunOpenShiftReleaseGenerator), the existing files in the clone are already determinized by
+// the generator, so only the determinized files are comparable with them.
+//
+// It returns the ci-operator configuration, jobs and prow configuration directories of each
+// generated repository and a function removing the worktree, which must be called also on
+// errors.
+func generateDeterminized(ctx context.Context, openShiftRelease Repository, inConfigs []*Config, outputConfig string) ([]determinizedDir, func(), error) {
+	cleanup := func() {}
+
+	if err := GitMirror(ctx, openShiftRelease); err != nil {
+		return nil, cleanup, err
+	}
+	if err := GitCheckout(ctx, openShiftRelease, "main"); err != nil {
+		return nil, cleanup, err
+	}
+	releaseDir := openShiftRelease.LocalDirectory(ctx)
+	outputRel, err := filepath.Rel(releaseDir, outputConfig)
+	if err != nil || strings.HasPrefix(outputRel, "..") {
+		return nil, cleanup, fmt.Errorf("output %q isn't in the %s clone %q", outputConfig, openShiftRelease.RepositoryDirectory(), releaseDir)
+	}
+
+	tmp, err := os.MkdirTemp("", "prowgen-release-")
+	if err != nil {
+		return nil, cleanup, err
+	}
+	// The worktree shares the objects of the clone, the generator runs there as in the clone.
+	copyCtx := WithWorkspace(ctx, &Workspace{Source: WorkspaceFromContext(ctx).Source, Root: tmp})
+	copyDir := openShiftRelease.LocalDirectory(copyCtx)
+	if !filepath.IsAbs(copyDir) {
+		// Git commands run in the clone directory.
+		if copyDir, err = filepath.Abs(copyDir); err != nil {
+			return nil, cleanup, err
+		}
+	}
+	cleanup = func() {
+		if _, err := Run(ctx, openShiftRelease, "git", "worktree", "remove", "--force", copyDir); err != nil {
+			log.Println("Failed to remove worktree", copyDir, err)
+		}
+		_ = os.RemoveAll(tmp)
+	}
+	if _, err := Run(ctx, openShiftRelease, "git", "worktree", "add", "--detach", copyDir, "HEAD"); err != nil {
+		return nil, cleanup, err
+	}
+	copyOutput := filepath.Join(copyDir, outputRel)
+
+	repositories, _, err := generateRepositories(ctx, inConfigs, nil)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	var errs []error
+	for _, g := range repositories {
+		if g.Err != nil {
+			errs = append(errs, g.Err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, cleanup, err
+	}
+
+	var dirs []determinizedDir
+	for _, g := range repositories {
+		if _, err := deleteExistingRepositoryConfigs(g.Config, g.Repository, copyOutput); err != nil {
+			return nil, cleanup, err
+		}
+		if _, err := saveGeneratedRepository(copyCtx, openShiftRelease, copyOutput, g); err != nil {
+			return nil, cleanup, err
+		}
+		for _, dir := range []string{
+			filepath.Join(outputRel, g.Repository.RepositoryDirectory()),
+			filepath.Join("ci-operator", "jobs", g.Repository.RepositoryDirectory()),
+			filepath.Dir(prowConfigShard(g.Repository)),
+		} {
+			dirs = append(dirs, determinizedDir{existing: filepath.Join(releaseDir, dir), generated: filepath.Join(copyDir, dir)})
+		}
+	}
+
+	if err := RunOpenShiftReleaseGenerator(copyCtx, openShiftRelease); err != nil {
+		return nil, cleanup, fmt.Errorf("failed to run openshift/release generator: %w", err)
+	}
+	return dirs, cleanup, nil
+}
+
+// comparePlan compares generated files with the existing files, any file in removals
+// that isn't generated is reported as removed.
+func comparePlan(root string, generated map[string][]byte, removals []string) (*Plan, error) {
+	plan := &Plan{}
+
+	paths := make([]string, 0, len(generated))
+	for path := range generated {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		existing, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %q: %w", path, err)
+		}
+		if err != nil {
+			plan.Added = append(plan.Added, diffFile(root, path, nil, generated[path]))
+			continue
+		}
+		if bytes.Equal(existing, generated[path]) {
+			continue
+		}
+		plan.Changed = append(plan.Changed, diffFile(root, path, existing, generated[path]))
+	}
+
+	removed := make(map[string]struct{}, len(removals))
+	for _, path := range removals {
+		if _, ok := generated[path]; ok {
+			continue
+		}
+		if _, ok := removed[path]; ok {
+			continue
+		}
+		removed[path] = struct{}{}
+
+		existing, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q: %w", path, err)
+		}
+		plan.Removed = append(plan.Removed, diffFile(root, path, existing, nil))
+	}
+	sort.Slice(plan.Removed, func(i, j int) bool {
+		return plan.Removed[i].Path < plan.Removed[j].Path
+	})
+
+	return plan, nil
+}
+
+// diffFile computes the semantic diff between the existing and the generated content,
+// nil content means that the file doesn't exist.
+func diffFile(root string, path string, existing []byte, generated []byte) FileChange {
+	change := FileChange{Path: path}
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		change.Path = rel
+	}
+
+	existingObj := unmarshalPlanFile(existing)
+	generatedObj := unmarshalPlanFile(generated)
+
+	if existing != nil && generated != nil {
+		for k := range mergeKeys(existingObj, generatedObj) {
+			if k == "tests" {
+				continue
+			}
+			if !reflect.DeepEqual(existingObj[k], generatedObj[k]) {
+				change.Fields = append(change.Fields, k)
+			}
+		}
+		sort.Strings(change.Fields)
+	}
+
+	existingTests := testsByName(existingObj)
+	generatedTests := testsByName(generatedObj)
+	for as := range mergeKeys(existingTests, generatedTests) {
+		e, inExisting := existingTests[as]
+		g, inGenerated := generatedTests[as]
+		switch {
+		case !inExisting:
+			change.Tests = append(change.Tests, TestChange{As: as, Change: ChangeAdded})
+		case !inGenerated:
+			change.Tests = append(change.Tests, TestChange{As: as, Change: ChangeRemoved})
+		case !reflect.DeepEqual(e, g):
+			change.Tests = append(change.Tests, TestChange{As: as, Change: ChangeChanged, Diff: cmp.Diff(e, g)})
+		}
+	}
+	sort.Slice(change.Tests, func(i, j int) bool {
+		return change.Tests[i].As < change.Tests[j].As
+	})
+
+	return change
+}
+
+func unmarshalPlanFile(content []byte) map[string]interface{} {
+	obj := make(map[string]interface{})
+	if content == nil {
+		return obj
+	}
+	if err := yaml.Unmarshal(content, &obj); err != nil || obj == nil {
+		// Not every file is a YAML object (for example, .config.prowgen), compare them as a whole.
+		return map[string]interface{}{"content": string(content)}
+	}
+	return obj
+}
+
+func testsByName(obj map[string]interface{}) map[string]interface{} {
+	tests := make(map[string]interface{})
+	list, ok := obj["tests"].([]interface{})
+	if !ok {
+		return tests
+	}
+	for i, t := range list {
+		as := fmt.Sprintf("#%d", i)
+		if m, ok := t.(map[string]interface{}); ok {
+			if name, ok := m["as"].(string); ok {
+				as = name
+			}
+		}
+		tests[as] = t
+	}
+	return tests
+}
+
+func mergeKeys(a, b map[string]interface{}) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
+
+// Empty returns true when the plan has no changes.
+func (p *Plan) Empty() bool {
+	return len(p.Added) == 0 && len(p.Removed) == 0 && len(p.Changed) == 0
+}
+
+// Write writes the plan to w in the given format, see PlanFormatHuman and PlanFormatJSON.
+func (p *Plan) Write(w io.Writer, format string) error {
+	switch format {
+	case PlanFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p)
+	case PlanFormatHuman, "":
+		return p.writeHuman(w)
+	default:
+		return fmt.Errorf("unknown plan format %q, supported formats: %s, %s", format, PlanFormatHuman, PlanFormatJSON)
+	}
+}
+
+func (p *Plan) writeHuman(w io.Writer) error {
+	if p.Empty() {
+		_, err := io.WriteString(w, "No changes.\n")
+		return err
+	}
+
+	b := &strings.Builder{}
+	_, _ = fmt.Fprintf(b, "Plan: %d to add, %d to change, %d to remove.\n", len(p.Added), len(p.Changed), len(p.Removed))
+
+	sections := []struct {
+		symbol string
+		files  []FileChange
+	}{
+		{symbol: "+", files: p.Added},
+		{symbol: "~", files: p.Changed},
+		{symbol: "-", files: p.Removed},
+	}
+	for _, s := range sections {
+		for _, f := range s.files {
+			_, _ = fmt.Fprintf(b, "\n%s %s\n", s.symbol, f.Path)
+			for _, field := range f.Fields {
+				_, _ = fmt.Fprintf(b, "    ~ %s\n", field)
+			}
+			for _, t := range f.Tests {
+				_, _ = fmt.Fprintf(b, "    %s test %s\n", changeSymbol(t.Change), t.As)
+				if t.Diff != "" {
+					for _, line := range strings.Split(strings.TrimRight(t.Diff, "\n"), "\n") {
+						_, _ = fmt.Fprintf(b, "        %s\n", line)
+					}
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func changeSymbol(c ChangeType) string {
+	switch c {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
@@ -0,0 +1,255 @@
+package prowgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestComparePlan(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "ci-operator", "config", "openshift-knative", "serving")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	unchanged := filepath.Join(dir, "openshift-knative-serving-release-v1.15__414.yaml")
+	changed := filepath.Join(dir, "openshift-knative-serving-release-v1.15__415.yaml")
+	removed := filepath.Join(dir, "openshift-knative-serving-release-v1.14__414.yaml")
+	added := filepath.Join(dir, "openshift-knative-serving-release-v1.15__416.yaml")
+	ignored := filepath.Join(dir, "openshift-knative-serving-main__414.yaml")
+
+	existing := map[string]string{
+		unchanged: "tests:\n- as: e2e\n  commands: make test-e2e\n",
+		changed: `images:
+- to: knative-serving-controller
+tests:
+- as: e2e
+  commands: make test-e2e
+- as: upgrade
+  commands: make test-upgrade
+- as: conformance
+  commands: make test-conformance
+`,
+		removed: "tests:\n- as: e2e\n",
+		ignored: "tests:\n- as: e2e\n",
+	}
+	for path, content := range existing {
+		if err := os.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	generated := map[string][]byte{
+		unchanged: []byte(existing[unchanged]),
+		changed: []byte(`images:
+- to: knative-serving-webhook
+tests:
+- as: e2e
+  commands: make test-e2e-tls
+- as: upgrade
+  commands: make test-upgrade
+- as: reconciler
+  commands: make test-reconciler
+`),
+		added: []byte("tests:\n- as: e2e\n"),
+	}
+
+	plan, err := comparePlan(root, generated, []string{unchanged, changed, removed, removed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rel := func(p string) string {
+		r, _ := filepath.Rel(root, p)
+		return r
+	}
+	want := &Plan{
+		Added: []FileChange{{
+			Path:  rel(added),
+			Tests: []TestChange{{As: "e2e", Change: ChangeAdded}},
+		}},
+		Removed: []FileChange{{
+			Path:  rel(removed),
+			Tests: []TestChange{{As: "e2e", Change: ChangeRemoved}},
+		}},
+		Changed: []FileChange{{
+			Path:   rel(changed),
+			Fields: []string{"images"},
+			Tests: []TestChange{
+				{As: "conformance", Change: ChangeRemoved},
+				{As: "e2e", Change: ChangeChanged},
+				{As: "reconciler", Change: ChangeAdded},
+			},
+		}},
+	}
+	if diff := cmp.Diff(want, plan, cmpopts.IgnoreFields(TestChange{}, "Diff")); diff != "" {
+		t.Error("plan (-want, +got):", diff)
+	}
+	if d := plan.Changed[0].Tests[1].Diff; !strings.Contains(d, "make test-e2e-tls") {
+		t.Errorf("expected test diff to contain the generated command, got:\n%s", d)
+	}
+
+	human := &bytes.Buffer{}
+	if err := plan.Write(human, PlanFormatHuman); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"Plan: 1 to add, 1 to change, 1 to remove.",
+		"+ " + rel(added),
+		"~ " + rel(changed),
+		"    ~ images",
+		"    - test conformance",
+		"    + test reconciler",
+		"- " + rel(removed),
+	} {
+		if !strings.Contains(human.String(), line+"\n") {
+			t.Errorf("expected human output to contain %q, got:\n%s", line, human.String())
+		}
+	}
+
+	out := &bytes.Buffer{}
+	if err := plan.Write(out, PlanFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	got := &Plan{}
+	if err := json.Unmarshal(out.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(plan, got); diff != "" {
+		t.Error("JSON round trip (-want, +got):", diff)
+	}
+
+	if err := plan.Write(out, "xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestComparePlanNoChanges(t *testing.T) {
+	plan, err := comparePlan(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := plan.Write(out, PlanFormatHuman); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "No changes.\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+// determinized prefixes the ci-operator configurations normalized by
+// releaseGeneratorExecutor.
+var determinized = []byte("# determinized\n")
+
+// releaseGeneratorExecutor executes commands as processes, except the openshift/release
+// generator (make), which it fakes: ci-operator configurations are normalized by prefixing them
+// with determinized and a presubmits file is written for each of them.
+type releaseGeneratorExecutor struct{}
+
+func (releaseGeneratorExecutor) Execute(ctx context.Context, c Command) ([]byte, error) {
+	if c.Name != "make" {
+		return (&OSExecutor{}).Execute(ctx, c)
+	}
+	configs := filepath.Join(c.Dir, "ci-operator", "config")
+	jobs := filepath.Join(c.Dir, "ci-operator", "jobs")
+	if err := os.RemoveAll(jobs); err != nil {
+		return nil, err
+	}
+	return nil, filepath.WalkDir(configs, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(content, determinized) {
+			if err := os.WriteFile(path, append(determinized, content...), 0o644); err != nil {
+				return err
+			}
+		}
+		rel, err := filepath.Rel(configs, path)
+		if err != nil {
+			return err
+		}
+		job := filepath.Join(jobs, strings.TrimSuffix(rel, ".yaml")+"-presubmits.yaml")
+		if err := os.MkdirAll(filepath.Dir(job), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(job, []byte("presubmits: {}\n"), 0o644)
+	})
+}
+
+func TestNewPlanAfterGenerate(t *testing.T) {
+	withExecutor(t, releaseGeneratorExecutor{})
+
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, "testorg", "serving.git"), "release-next")
+
+	release := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(release, "ci-operator", "config"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(release, "Makefile"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	seedBareRepository(t, release, filepath.Join(sourceRoot, "openshift", "release.git"), "main")
+
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	inConfigs := []*Config{
+		{
+			Repositories: []Repository{
+				{Org: "testorg", Repo: "serving", ImagePrefix: "knative-serving", E2ETests: []E2ETest{{Match: "test-e2e$"}}},
+			},
+			Config: CommonConfig{
+				Branches: map[string]Branch{
+					"release-next": {
+						OpenShiftVersions: []OpenShift{{Version: "4.14", SkipCron: true}},
+					},
+				},
+			},
+		},
+	}
+
+	openShiftRelease := Repository{Org: "openshift", Repo: "release"}
+	outputConfig := filepath.Join(openShiftRelease.LocalDirectory(ctx), "ci-operator", "config")
+	plan, err := NewPlan(ctx, openShiftRelease, inConfigs, outputConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var added []string
+	for _, c := range plan.Added {
+		added = append(added, filepath.ToSlash(c.Path))
+	}
+	wantAdded := []string{
+		"ci-operator/config/testorg/serving/testorg-serving-release-next__414.yaml",
+		"ci-operator/jobs/testorg/serving/testorg-serving-release-next__414-presubmits.yaml",
+		"core-services/prow/02_config/testorg/serving/_prowconfig.yaml",
+	}
+	if diff := cmp.Diff(wantAdded, added); diff != "" {
+		t.Errorf("added files mismatch before generate (-want, +got):\n%s", diff)
+	}
+
+	if _, err := NewGenerator(inConfigs, WithBuild(true)).Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err = NewPlan(ctx, openShiftRelease, inConfigs, outputConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Errorf("expected an empty plan after generate, got: %+v", plan)
+	}
+}
@@ -0,0 +1,293 @@
+package prowgen
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Resolve returns a copy of the configuration where every branch extending a profile, or
+// referencing an OpenShift version set, is flattened, the returned configuration has no
+// profiles and version sets (cluster profiles are preserved).
+//
+// Zero values of Go values (for example, false) are unset and never override a profile value,
+// configuration files can override them, see UnmarshalConfig.
+func (cc CommonConfig) Resolve() (CommonConfig, error) {
+	raw, err := cc.raw()
+	if err != nil {
+		return CommonConfig{}, err
+	}
+	return raw.resolve(cc)
+}
+
+// ResolveBranch returns the flattened configuration of the given branch, see Resolve.
+func (cc CommonConfig) ResolveBranch(name string) (Branch, error) {
+	raw, err := cc.raw()
+	if err != nil {
+		return Branch{}, err
+	}
+	return raw.resolveBranch(name)
+}
+
+// jsonObject is a decoded JSON object.
+type jsonObject = map[string]interface{}
+
+// rawCommonConfig is the JSON representation of the branches, profiles and OpenShift version sets
+// of a CommonConfig. Profiles and version sets are merged on the JSON objects, which, unlike Go
+// values, tell unset fields from fields set to the zero value, so that a branch can override a
+// profile value with false, "" or 0.
+type rawCommonConfig struct {
+	Branches             map[string]jsonObject   `json:"branches,omitempty"`
+	Profiles             map[string]jsonObject   `json:"profiles,omitempty"`
+	OpenShiftVersionSets map[string][]jsonObject `json:"openShiftVersionSets,omitempty"`
+}
+
+// unmarshalRawCommonConfig returns the raw CommonConfig of a configuration file.
+func unmarshalRawCommonConfig(rawYaml []byte) (*rawCommonConfig, error) {
+	j, err := yaml.YAMLToJSON(rawYaml)
+	if err != nil {
+		return nil, err
+	}
+	cfg := struct {
+		Config rawCommonConfig `json:"config"`
+	}{}
+	if err := json.Unmarshal(j, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall config: %w", err)
+	}
+	return &cfg.Config, nil
+}
+
+// raw returns the raw representation of the configuration, zero values are omitted.
+func (cc CommonConfig) raw() (*rawCommonConfig, error) {
+	b, err := json.Marshal(cc)
+	if err != nil {
+		return nil, err
+	}
+	raw := &rawCommonConfig{}
+	if err := json.Unmarshal(b, raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// resolve returns the resolved configuration of the branches of cc, see CommonConfig.Resolve.
+func (raw *rawCommonConfig) resolve(cc CommonConfig) (CommonConfig, error) {
+	resolved := CommonConfig{ClusterProfiles: cc.ClusterProfiles}
+	if cc.Branches == nil {
+		return resolved, nil
+	}
+	resolved.Branches = make(map[string]Branch, len(cc.Branches))
+	for name := range cc.Branches {
+		b, err := raw.resolveBranch(name)
+		if err != nil {
+			return CommonConfig{}, err
+		}
+		resolved.Branches[name] = b
+	}
+	return resolved, nil
+}
+
+func (raw *rawCommonConfig) resolveBranch(name string) (Branch, error) {
+	b, ok := raw.Branches[name]
+	if !ok {
+		return Branch{}, fmt.Errorf("branch %q not found", name)
+	}
+	obj, err := raw.resolveBranchObject(b, nil)
+	if err != nil {
+		return Branch{}, fmt.Errorf("failed to resolve branch %q: %w", name, err)
+	}
+	// Every branch gets its own copy, profiles are shared by multiple branches.
+	var resolved Branch
+	j, err := json.Marshal(obj)
+	if err != nil {
+		return Branch{}, fmt.Errorf("failed to resolve branch %q: %w", name, err)
+	}
+	if err := json.Unmarshal(j, &resolved); err != nil {
+		return Branch{}, fmt.Errorf("failed to resolve branch %q: %w", name, err)
+	}
+	return resolved, nil
+}
+
+func (raw *rawCommonConfig) resolveBranchObject(b jsonObject, profiles []string) (jsonObject, error) {
+	resolved := mergeObjects(nil, b)
+	if extends, _ := b["extends"].(string); extends != "" {
+		if i := slices.Index(profiles, extends); i >= 0 {
+			return nil, fmt.Errorf("profile cycle %s", strings.Join(append(profiles[i:], extends), " -> "))
+		}
+		p, ok := raw.Profiles[extends]
+		if !ok {
+			return nil, fmt.Errorf("profile %q not found", extends)
+		}
+		parent, err := raw.resolveBranchObject(p, append(profiles, extends))
+		if err != nil {
+			return nil, err
+		}
+		resolved = mergeObjects(parent, b)
+		delete(resolved, "extends")
+	}
+
+	if ovs, ok := resolved["openShiftVersions"].([]interface{}); ok {
+		resolvedOvs, err := raw.resolveOpenShiftVersions(ovs)
+		if err != nil {
+			return nil, err
+		}
+		resolved["openShiftVersions"] = resolvedOvs
+	}
+	return resolved, nil
+}
+
+func (raw *rawCommonConfig) resolveOpenShiftVersions(ovs []interface{}) ([]interface{}, error) {
+	resolved := make([]interface{}, 0, len(ovs))
+	for _, v := range ovs {
+		ov, _ := v.(jsonObject)
+		versionSet, _ := ov["versionSet"].(string)
+		if versionSet == "" {
+			resolved = append(resolved, v)
+			continue
+		}
+		set, ok := raw.OpenShiftVersionSets[versionSet]
+		if !ok {
+			return nil, fmt.Errorf("OpenShift version set %q not found", versionSet)
+		}
+		if version, _ := ov["version"].(string); version != "" {
+			return nil, fmt.Errorf("OpenShift version set %q reference cannot set version %q", versionSet, version)
+		}
+		overrides := mergeObjects(nil, ov)
+		delete(overrides, "versionSet")
+		for _, setOv := range set {
+			if s, _ := setOv["versionSet"].(string); s != "" {
+				return nil, fmt.Errorf("OpenShift version set %q cannot reference other version sets", versionSet)
+			}
+			resolved = append(resolved, mergeObjects(setOv, overrides))
+		}
+	}
+	return resolved, nil
+}
+
+// mergeObjects returns a copy of dst overridden by every value set in src, including zero
+// values (for example, false):
+//   - objects are merged key by key,
+//   - arrays (including empty arrays) and any other value replace the dst value,
+//   - null values are unset.
+func mergeObjects(dst jsonObject, src jsonObject) jsonObject {
+	merged := make(jsonObject, len(dst)+len(src))
+	for k, v := range dst {
+		merged[k] = v
+	}
+	for k, v := range src {
+		if v == nil {
+			continue
+		}
+		srcObj, srcIsObj := v.(jsonObject)
+		dstObj, dstIsObj := merged[k].(jsonObject)
+		if srcIsObj && dstIsObj {
+			merged[k] = mergeObjects(dstObj, srcObj)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// mergeInto overrides dst with every non-zero value in src:
+//   - structs, and pointers to structs, are merged field by field,
+//   - maps are merged key by key,
+//   - slices (including empty, non-nil slices) replace the dst slice,
+//   - any other non-zero value replaces the dst value.
+//
+// As a consequence, a zero value (for example, false) never overrides a dst value.
+func mergeInto(dst reflect.Value, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			if !dst.Type().Field(i).IsExported() {
+				continue
+			}
+			mergeInto(dst.Field(i), src.Field(i))
+		}
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		if dst.IsNil() || src.Elem().Kind() != reflect.Struct {
+			dst.Set(src)
+			return
+		}
+		merged := reflect.New(dst.Elem().Type())
+		merged.Elem().Set(dst.Elem())
+		mergeInto(merged.Elem(), src.Elem())
+		dst.Set(merged)
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		merged := reflect.MakeMapWithSize(dst.Type(), dst.Len()+src.Len())
+		for _, k := range dst.MapKeys() {
+			merged.SetMapIndex(k, dst.MapIndex(k))
+		}
+		for _, k := range src.MapKeys() {
+			merged.SetMapIndex(k, src.MapIndex(k))
+		}
+		dst.Set(merged)
+	case reflect.Slice:
+		if !src.IsNil() {
+			dst.Set(src)
+		}
+	default:
+		if !src.IsZero() {
+			dst.Set(src)
+		}
+	}
+}
+
+// ResolveMain is the entry point of `prowgen resolve`, it prints the resolved configuration
+// of a repository and branch.
+func ResolveMain(args []string) {
+	fset := flag.NewFlagSet("resolve", flag.ExitOnError)
+	inputConfig := fset.String("config", "", "Configuration file")
+	repo := fset.String("repo", "", "Only print the given repository (default all repositories)")
+	branch := fset.String("branch", "", "Only print the given branch (default all branches)")
+	_ = fset.Parse(args)
+
+	if *inputConfig == "" {
+		log.Fatalln("-config is required")
+	}
+
+	cfg, err := LoadConfig(*inputConfig)
+	if err != nil {
+		log.Fatalln("Failed to load config", *inputConfig, err)
+	}
+
+	if *repo != "" {
+		var repositories []Repository
+		for _, r := range cfg.Repositories {
+			if r.Repo == *repo || r.RepositoryDirectory() == *repo {
+				repositories = append(repositories, r)
+			}
+		}
+		if len(repositories) == 0 {
+			log.Fatalln("Repository", *repo, "not found in", *inputConfig)
+		}
+		cfg.Repositories = repositories
+	}
+	if *branch != "" {
+		b, ok := cfg.Config.Branches[*branch]
+		if !ok {
+			log.Fatalln("Branch", *branch, "not found in", *inputConfig)
+		}
+		cfg.Config.Branches = map[string]Branch{*branch: b}
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		log.Fatalln("Failed to marshal config", err)
+	}
+	_, _ = os.Stdout.Write(out)
+}
@@ -0,0 +1,200 @@
+package prowgen
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+)
+
+func TestUnmarshalConfigProfiles(t *testing.T) {
+	cfg, err := UnmarshalConfig([]byte(`
+config:
+  openShiftVersionSets:
+    supported:
+    - version: "4.14"
+      useClusterPool: true
+    - version: "4.17"
+  profiles:
+    base:
+      skipDockerFilesMatches:
+      - ".*hack/.*"
+      konflux:
+        enabled: true
+        imageOverrides:
+        - name: GO_BUILDER
+          pullSpec: registry.ci.openshift.org/openshift/release:rhel-8-release-golang-1.22-openshift-4.17
+    serving:
+      extends: base
+      golangVersion: "1.22"
+      openShiftVersions:
+      - versionSet: supported
+        skipCron: true
+      - version: "4.18"
+  branches:
+    release-v1.14:
+      extends: serving
+      konflux:
+        nudges:
+        - serverless-operator-114-bundle
+    release-v1.15:
+      extends: serving
+      skipDockerFilesMatches: []
+      openShiftVersions:
+      - version: "4.19"
+    release-next:
+      skipE2EMatches:
+      - test-e2e$
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imageOverrides := []Image{{
+		Name:     "GO_BUILDER",
+		PullSpec: "registry.ci.openshift.org/openshift/release:rhel-8-release-golang-1.22-openshift-4.17",
+	}}
+	want := CommonConfig{
+		Branches: map[string]Branch{
+			"release-v1.14": {
+				SkipDockerFilesMatches: []string{".*hack/.*"},
+				Konflux: &Konflux{
+					Enabled:        true,
+					Nudges:         []string{"serverless-operator-114-bundle"},
+					ImageOverrides: imageOverrides,
+				},
+				GolangVersion: ptr.To("1.22"),
+				OpenShiftVersions: []OpenShift{
+					{Version: "4.14", UseClusterPool: true, SkipCron: true},
+					{Version: "4.17", SkipCron: true},
+					{Version: "4.18"},
+				},
+			},
+			"release-v1.15": {
+				SkipDockerFilesMatches: []string{},
+				Konflux: &Konflux{
+					Enabled:        true,
+					ImageOverrides: imageOverrides,
+				},
+				GolangVersion:     ptr.To("1.22"),
+				OpenShiftVersions: []OpenShift{{Version: "4.19"}},
+			},
+			"release-next": {
+				SkipE2EMatches: []string{"test-e2e$"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, cfg.Config); diff != "" {
+		t.Error("resolved config (-want, +got):", diff)
+	}
+
+	// Profiles must not be shared between branches.
+	cfg.Config.Branches["release-v1.14"].Konflux.ImageOverrides[0].Name = "GO_RUNTIME"
+	if got := cfg.Config.Branches["release-v1.15"].Konflux.ImageOverrides[0].Name; got != "GO_BUILDER" {
+		t.Errorf("expected branches to have independent copies of profiles, got %q", got)
+	}
+}
+
+func TestUnmarshalConfigProfilesZeroValues(t *testing.T) {
+	cfg, err := UnmarshalConfig([]byte(`
+config:
+  openShiftVersionSets:
+    supported:
+    - version: "4.14"
+      useClusterPool: true
+      skipCron: true
+  profiles:
+    base:
+      konflux:
+        enabled: true
+        nudges:
+        - serverless-operator-bundle
+      golangVersion: "1.22"
+  branches:
+    release-v1.15:
+      extends: base
+      konflux:
+        enabled: false
+      golangVersion: ""
+      openShiftVersions:
+      - versionSet: supported
+        useClusterPool: false
+    release-v1.16:
+      extends: base
+      openShiftVersions:
+      - versionSet: supported
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := CommonConfig{
+		Branches: map[string]Branch{
+			"release-v1.15": {
+				Konflux: &Konflux{
+					Nudges: []string{"serverless-operator-bundle"},
+				},
+				GolangVersion:     ptr.To(""),
+				OpenShiftVersions: []OpenShift{{Version: "4.14", SkipCron: true}},
+			},
+			"release-v1.16": {
+				Konflux: &Konflux{
+					Enabled: true,
+					Nudges:  []string{"serverless-operator-bundle"},
+				},
+				GolangVersion:     ptr.To("1.22"),
+				OpenShiftVersions: []OpenShift{{Version: "4.14", UseClusterPool: true, SkipCron: true}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, cfg.Config); diff != "" {
+		t.Error("resolved config (-want, +got):", diff)
+	}
+}
+
+func TestResolveBranchErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cc      CommonConfig
+		wantErr string
+	}{
+		{
+			name: "unknown profile",
+			cc: CommonConfig{
+				Branches: map[string]Branch{"main": {Extends: "unknown"}},
+			},
+			wantErr: `failed to resolve branch "main": profile "unknown" not found`,
+		},
+		{
+			name: "cycle",
+			cc: CommonConfig{
+				Branches: map[string]Branch{"main": {Extends: "a"}},
+				Profiles: map[string]Branch{"a": {Extends: "b"}, "b": {Extends: "a"}},
+			},
+			wantErr: `failed to resolve branch "main": profile cycle a -> b -> a`,
+		},
+		{
+			name: "unknown version set",
+			cc: CommonConfig{
+				Branches: map[string]Branch{"main": {OpenShiftVersions: []OpenShift{{VersionSet: "unknown"}}}},
+			},
+			wantErr: `failed to resolve branch "main": OpenShift version set "unknown" not found`,
+		},
+		{
+			name: "version set reference with version",
+			cc: CommonConfig{
+				Branches:             map[string]Branch{"main": {OpenShiftVersions: []OpenShift{{VersionSet: "s", Version: "4.14"}}}},
+				OpenShiftVersionSets: map[string][]OpenShift{"s": {{Version: "4.15"}}},
+			},
+			wantErr: `failed to resolve branch "main": OpenShift version set "s" reference cannot set version "4.14"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cc.ResolveBranch("main")
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("want error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
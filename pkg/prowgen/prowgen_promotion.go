@@ -0,0 +1,178 @@
+package prowgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+
+	"github.com/openshift-knative/hack/pkg/soversion"
+)
+
+// OpenShiftPromotion configures the promotion of the images built for an OpenShift version.
+//
+// By default, images built for the first OpenShift version of a branch (not skipping promotion)
+// are promoted by name and the images built for the second one are promoted by tag. When any
+// OpenShift version of a branch configures a promotion, only the OpenShift versions with a
+// promotion promote images.
+//
+// Name and Tag are templates, ${version} is replaced with the branch version (for example,
+// v1.15 for release-v1.15 and nightly for release-next), ${ocpVersion} with the OpenShift
+// version, ${branch} with the branch name and ${soVersion} with the Serverless Operator version
+// of the branch (for example, 1.35 for release-v1.15 and nightly for release-next).
+type OpenShiftPromotion struct {
+	// Namespace defaults to the branch or repository promotion namespace, or openshift.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Name promotes images to the <namespace>/<name>:<image> image stream tags.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Tag promotes images to the <namespace>/<image>:<tag> image stream tags.
+	Tag string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	// TagByCommit also promotes images tagged with the commit SHA.
+	TagByCommit bool `json:"tagByCommit,omitempty" yaml:"tagByCommit,omitempty"`
+	// ExcludedImages aren't promoted, * excludes every built image.
+	ExcludedImages []string `json:"excludedImages,omitempty" yaml:"excludedImages,omitempty"`
+	// AdditionalImages are promoted on top of the built images and the source image, they map
+	// the promoted name to the pipeline image, for example, src.
+	AdditionalImages map[string]string `json:"additionalImages,omitempty" yaml:"additionalImages,omitempty"`
+}
+
+var (
+	promotionTemplateVariableRegex = regexp.MustCompile(`\$\{([a-zA-Z]+)\}`)
+	soReleaseBranchRegex           = regexp.MustCompile(`^release-[0-9]+\.[0-9]+$`)
+	upstreamReleaseBranchRegex     = regexp.MustCompile(`^release-v?[0-9]+\.[0-9]+$`)
+)
+
+func (p *OpenShiftPromotion) validate() error {
+	if (p.Name == "") == (p.Tag == "") {
+		return fmt.Errorf("exactly one of name or tag is required")
+	}
+	for _, tpl := range []string{p.Name, p.Tag} {
+		for _, m := range promotionTemplateVariableRegex.FindAllStringSubmatch(tpl, -1) {
+			switch m[1] {
+			case "version", "ocpVersion", "branch", "soVersion":
+			default:
+				return fmt.Errorf("unknown variable %s in %q, supported variables are ${version}, ${ocpVersion}, ${branch} and ${soVersion}", m[0], tpl)
+			}
+		}
+	}
+	return nil
+}
+
+// branchPromotionTargets returns the promotion target of the images built for each OpenShift
+// version of the branch, nil when the images built for the OpenShift version aren't promoted.
+func branchPromotionTargets(r Repository, branchName string, branch Branch) ([]*cioperatorapi.PromotionTarget, error) {
+	p := r.Promotion
+	if branch.Promotion.Namespace != "" {
+		p.Namespace = branch.Promotion.Namespace
+	}
+	if branch.Promotion.Template != "" {
+		p.Template = branch.Promotion.Template
+	}
+	ns := "openshift"
+	if p.Namespace != "" {
+		ns = p.Namespace
+	}
+
+	explicit := false
+	for _, ov := range branch.OpenShiftVersions {
+		explicit = explicit || ov.Promotion != nil
+	}
+
+	targets := make([]*cioperatorapi.PromotionTarget, len(branch.OpenShiftVersions))
+	promotionIndex := 0
+	for i, ov := range branch.OpenShiftVersions {
+		if ov.SkipPromotion {
+			continue
+		}
+		vars := promotionTemplateVars(r, branchName, ov)
+		target := &cioperatorapi.PromotionTarget{
+			Namespace: ns,
+			AdditionalImages: map[string]string{
+				// Add source image
+				transformLegacyKnativeSourceImageName(r): "src",
+			},
+		}
+
+		if explicit {
+			op := ov.Promotion
+			if op == nil {
+				continue
+			}
+			if err := op.validate(); err != nil {
+				return nil, fmt.Errorf("[%s] branch %s OpenShift %s: invalid promotion: %w", r.RepositoryDirectory(), branchName, ov.Version, err)
+			}
+			if op.Namespace != "" {
+				target.Namespace = op.Namespace
+			}
+			target.Name = expandPromotionTemplate(op.Name, vars)
+			target.Tag = expandPromotionTemplate(op.Tag, vars)
+			target.TagByCommit = op.TagByCommit
+			target.ExcludedImages = append(target.ExcludedImages, op.ExcludedImages...)
+			for name, image := range op.AdditionalImages {
+				target.AdditionalImages[name] = image
+			}
+			targets[i] = target
+			continue
+		}
+
+		tpl := "knative-${version}"
+		if p.Template != "" {
+			tpl = p.Template
+		}
+		switch promotionIndex {
+		case 0:
+			target.Name = expandPromotionTemplate(tpl, vars)
+			targets[i] = target
+		case 1:
+			target.Tag = expandPromotionTemplate(tpl, vars)
+			target.TagByCommit = false // TODO: revisit this later
+			targets[i] = target
+		}
+		promotionIndex++
+	}
+	return targets, nil
+}
+
+// promotionTemplateVars returns the variables of promotion templates, see OpenShiftPromotion.
+func promotionTemplateVars(r Repository, branchName string, ov OpenShift) map[string]string {
+	version := strings.Replace(branchName, "release-", "", 1)
+	if version == "next" {
+		version = "nightly"
+	}
+
+	soVersion := "nightly"
+	switch {
+	case r.Repo == "serverless-operator" && soReleaseBranchRegex.MatchString(branchName):
+		soVersion = strings.TrimPrefix(branchName, "release-")
+	case r.Repo != "serverless-operator" && upstreamReleaseBranchRegex.MatchString(branchName):
+		v := soversion.FromUpstreamVersion(branchName)
+		soVersion = fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	}
+
+	return map[string]string{
+		"version":    version,
+		"ocpVersion": ov.Version,
+		"branch":     branchName,
+		"soVersion":  soVersion,
+	}
+}
+
+func expandPromotionTemplate(tpl string, vars map[string]string) string {
+	return promotionTemplateVariableRegex.ReplaceAllStringFunc(tpl, func(s string) string {
+		name := promotionTemplateVariableRegex.FindStringSubmatch(s)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return s
+	})
+}
+
+func withPromotion(target cioperatorapi.PromotionTarget) ReleaseBuildConfigurationOption {
+	return func(cfg *cioperatorapi.ReleaseBuildConfiguration) error {
+		cfg.PromotionConfiguration = &cioperatorapi.PromotionConfiguration{
+			Targets: []cioperatorapi.PromotionTarget{target},
+		}
+		return nil
+	}
+}
@@ -0,0 +1,118 @@
+package prowgen
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+)
+
+func TestBranchPromotionTargets(t *testing.T) {
+	serving := Repository{Org: "openshift-knative", Repo: "serving"}
+	src := map[string]string{"knative-serving-src": "src"}
+
+	tests := []struct {
+		name       string
+		repository Repository
+		branchName string
+		branch     Branch
+		want       []*cioperatorapi.PromotionTarget
+		wantErr    bool
+	}{
+		{
+			name:       "default",
+			repository: serving,
+			branchName: "release-v1.15",
+			branch: Branch{
+				OpenShiftVersions: []OpenShift{{Version: "4.14", SkipPromotion: true}, {Version: "4.15"}, {Version: "4.16"}, {Version: "4.17"}},
+			},
+			want: []*cioperatorapi.PromotionTarget{
+				nil,
+				{Namespace: "openshift", Name: "knative-v1.15", AdditionalImages: src},
+				{Namespace: "openshift", Tag: "knative-v1.15", AdditionalImages: src},
+				nil,
+			},
+		},
+		{
+			name:       "default with template",
+			repository: Repository{Org: "openshift-knative", Repo: "serving", Promotion: Promotion{Namespace: "knative"}},
+			branchName: "release-next",
+			branch: Branch{
+				Promotion:         Promotion{Template: "serverless-${soVersion}-ocp-${ocpVersion}"},
+				OpenShiftVersions: []OpenShift{{Version: "4.17"}},
+			},
+			want: []*cioperatorapi.PromotionTarget{
+				{Namespace: "knative", Name: "serverless-nightly-ocp-4.17", AdditionalImages: src},
+			},
+		},
+		{
+			name:       "explicit",
+			repository: serving,
+			branchName: "release-v1.15",
+			branch: Branch{
+				OpenShiftVersions: []OpenShift{
+					{Version: "4.14"},
+					{Version: "4.15", Promotion: &OpenShiftPromotion{
+						Namespace:        "serverless",
+						Tag:              "${soVersion}-${ocpVersion}",
+						TagByCommit:      true,
+						ExcludedImages:   []string{"knative-serving-test-webhook"},
+						AdditionalImages: map[string]string{"knative-serving-test-runner": "test-bin"},
+					}},
+					{Version: "4.16", Promotion: &OpenShiftPromotion{Name: "${branch}"}},
+				},
+			},
+			want: []*cioperatorapi.PromotionTarget{
+				nil,
+				{
+					Namespace:        "serverless",
+					Tag:              "1.35-4.15",
+					TagByCommit:      true,
+					ExcludedImages:   []string{"knative-serving-test-webhook"},
+					AdditionalImages: map[string]string{"knative-serving-src": "src", "knative-serving-test-runner": "test-bin"},
+				},
+				{Namespace: "openshift", Name: "release-v1.15", AdditionalImages: src},
+			},
+		},
+		{
+			name:       "serverless operator",
+			repository: Repository{Org: "openshift-knative", Repo: "serverless-operator"},
+			branchName: "release-1.36",
+			branch: Branch{
+				OpenShiftVersions: []OpenShift{{Version: "4.17", Promotion: &OpenShiftPromotion{Name: "serverless-${soVersion}"}}},
+			},
+			want: []*cioperatorapi.PromotionTarget{
+				{Namespace: "openshift", Name: "serverless-1.36", AdditionalImages: map[string]string{"serverless-operator-src": "src"}},
+			},
+		},
+		{
+			name:       "name and tag",
+			repository: serving,
+			branchName: "release-v1.15",
+			branch: Branch{
+				OpenShiftVersions: []OpenShift{{Version: "4.17", Promotion: &OpenShiftPromotion{Name: "a", Tag: "b"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name:       "unknown variable",
+			repository: serving,
+			branchName: "release-v1.15",
+			branch: Branch{
+				OpenShiftVersions: []OpenShift{{Version: "4.17", Promotion: &OpenShiftPromotion{Name: "${release}"}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := branchPromotionTargets(tt.repository, tt.branchName, tt.branch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package prowgen
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/openshift/ci-tools/pkg/api/shardprowconfig"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/git/types"
+)
+
+// ProwConfig configures Tide and branch protection of a repository, any unset field uses the
+// default configuration.
+type ProwConfig struct {
+	// MergeMethod is the Tide merge method (merge, rebase, squash or ifNecessary), squash by default.
+	MergeMethod types.PullRequestMergeType `json:"mergeMethod,omitempty" yaml:"mergeMethod,omitempty"`
+	// Labels are the labels required to merge a pull request, approved and lgtm by default.
+	Labels []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// MissingLabels are the labels preventing a pull request from merging.
+	MissingLabels []string `json:"missingLabels,omitempty" yaml:"missingLabels,omitempty"`
+	// Bots are trusted authors whose pull requests merge with the skip-review label only,
+	// the Konflux bot and serverless-qe by default.
+	Bots []string `json:"bots,omitempty" yaml:"bots,omitempty"`
+	// Queries are additional Tide queries, missing labels default to MissingLabels.
+	Queries []TideQuery `json:"queries,omitempty" yaml:"queries,omitempty"`
+	// Branches are branch protection policies by branch name, by default release-next and
+	// release-next-ci are not protected, except for serverless-operator, which doesn't have them.
+	Branches map[string]BranchPolicy `json:"branches,omitempty" yaml:"branches,omitempty"`
+	// ReleaseBranches enables the protection of release branches requiring the generated presubmits.
+	ReleaseBranches *ReleaseBranchProtection `json:"releaseBranches,omitempty" yaml:"releaseBranches,omitempty"`
+}
+
+type TideQuery struct {
+	Labels                 []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	MissingLabels          []string `json:"missingLabels,omitempty" yaml:"missingLabels,omitempty"`
+	Author                 string   `json:"author,omitempty" yaml:"author,omitempty"`
+	ReviewApprovedRequired bool     `json:"reviewApprovedRequired,omitempty" yaml:"reviewApprovedRequired,omitempty"`
+}
+
+type BranchPolicy struct {
+	Protect *bool `json:"protect,omitempty" yaml:"protect,omitempty"`
+	// RequiredContexts are the status contexts required to merge, for example, ci/prow/images.
+	RequiredContexts []string `json:"requiredContexts,omitempty" yaml:"requiredContexts,omitempty"`
+	// Strict requires pull requests to be up-to-date with the branch before merging.
+	Strict *bool `json:"strict,omitempty" yaml:"strict,omitempty"`
+	// Admins enforces the policy for administrators.
+	Admins *bool `json:"admins,omitempty" yaml:"admins,omitempty"`
+	// Restrictions limits who can push to the branch.
+	Restrictions *BranchRestrictions `json:"restrictions,omitempty" yaml:"restrictions,omitempty"`
+	// RequiredApprovingReviews is the number of GitHub approving reviews required to merge.
+	RequiredApprovingReviews *int `json:"requiredApprovingReviews,omitempty" yaml:"requiredApprovingReviews,omitempty"`
+}
+
+type BranchRestrictions struct {
+	Apps  []string `json:"apps,omitempty" yaml:"apps,omitempty"`
+	Users []string `json:"users,omitempty" yaml:"users,omitempty"`
+	Teams []string `json:"teams,omitempty" yaml:"teams,omitempty"`
+}
+
+// ReleaseBranchProtection protects release branches, requiring the presubmits that always run
+// and aren't optional, policies in ProwConfig.Branches take precedence.
+type ReleaseBranchProtection struct {
+	// Match is a regular expression matching release branches, ^release-v?[0-9]+\.[0-9]+$ by default.
+	Match string `json:"match,omitempty" yaml:"match,omitempty"`
+	// ExcludeContexts are regular expressions matching contexts that aren't required.
+	ExcludeContexts []string `json:"excludeContexts,omitempty" yaml:"excludeContexts,omitempty"`
+	// AdditionalContexts are required in addition to the generated presubmits.
+	AdditionalContexts []string `json:"additionalContexts,omitempty" yaml:"additionalContexts,omitempty"`
+	Strict             *bool    `json:"strict,omitempty" yaml:"strict,omitempty"`
+}
+
+const defaultReleaseBranchPattern = `^release-v?[0-9]+\.[0-9]+$`
+
+var (
+	defaultTideLabels = []string{
+		"approved",
+		"lgtm",
+	}
+
+	defaultTideMissingLabels = []string{
+		"backports/unvalidated-commits",
+		"do-not-merge/hold",
+		"do-not-merge/invalid-owners-file",
+		"do-not-merge/work-in-progress",
+		"jira/invalid-bug",
+		"needs-rebase",
+	}
+
+	defaultTideBots = []string{
+		"red-hat-konflux-kflux-prd-rh02[bot]",
+		"serverless-qe",
+	}
+)
+
+func (r Repository) prowConfig() ProwConfig {
+	p := ProwConfig{}
+	if r.Prow != nil {
+		p = *r.Prow
+	}
+	if p.MergeMethod == "" {
+		p.MergeMethod = types.MergeSquash
+	}
+	if len(p.Labels) == 0 {
+		p.Labels = defaultTideLabels
+	}
+	if len(p.MissingLabels) == 0 {
+		p.MissingLabels = defaultTideMissingLabels
+	}
+	if len(p.Bots) == 0 {
+		p.Bots = defaultTideBots
+	}
+	if p.Branches == nil && !r.IsServerlessOperator() {
+		// SO does not have release-next nor release-next-ci branches
+		p.Branches = map[string]BranchPolicy{
+			"release-next":    {Protect: ptr.To(false)},
+			"release-next-ci": {Protect: ptr.To(false)},
+		}
+	}
+	return p
+}
+
+func (p ProwConfig) validate() error {
+	switch p.MergeMethod {
+	case "", types.MergeMerge, types.MergeRebase, types.MergeSquash, types.MergeIfNecessary:
+	default:
+		return fmt.Errorf("invalid merge method %q", p.MergeMethod)
+	}
+	for i, q := range p.Queries {
+		if len(q.Labels) == 0 && q.Author == "" {
+			return fmt.Errorf("queries[%d]: labels or author are required", i)
+		}
+	}
+	if rb := p.ReleaseBranches; rb != nil {
+		if rb.Match != "" {
+			if _, err := regexp.Compile(rb.Match); err != nil {
+				return fmt.Errorf("releaseBranches.match: invalid regular expression %q: %w", rb.Match, err)
+			}
+		}
+		for _, expr := range rb.ExcludeContexts {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("releaseBranches.excludeContexts: invalid regular expression %q: %w", expr, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p ProwConfig) tide(repo string) *shardprowconfig.TideConfig {
+	queries := config.TideQueries{
+		config.TideQuery{
+			Labels:        p.Labels,
+			MissingLabels: p.MissingLabels,
+			Repos:         []string{repo},
+		},
+	}
+	for _, bot := range p.Bots {
+		queries = append(queries, config.TideQuery{
+			Labels:                 []string{"skip-review"},
+			MissingLabels:          p.MissingLabels,
+			Repos:                  []string{repo},
+			Author:                 bot,
+			ReviewApprovedRequired: false,
+		})
+	}
+	for _, q := range p.Queries {
+		missingLabels := q.MissingLabels
+		if len(missingLabels) == 0 {
+			missingLabels = p.MissingLabels
+		}
+		queries = append(queries, config.TideQuery{
+			Labels:                 q.Labels,
+			MissingLabels:          missingLabels,
+			Repos:                  []string{repo},
+			Author:                 q.Author,
+			ReviewApprovedRequired: q.ReviewApprovedRequired,
+		})
+	}
+
+	return &shardprowconfig.TideConfig{
+		MergeType: map[string]types.PullRequestMergeType{
+			repo: p.MergeMethod,
+		},
+		Queries: queries,
+	}
+}
+
+// branches returns the branch protection policies, including the generated ones for release
+// branches.
+func (p ProwConfig) branches(cfgs []ReleaseBuildConfiguration) (map[string]config.Branch, error) {
+	policies := make(map[string]BranchPolicy, len(p.Branches))
+	if rb := p.ReleaseBranches; rb != nil {
+		generated, err := rb.policies(cfgs)
+		if err != nil {
+			return nil, err
+		}
+		for branch, policy := range generated {
+			policies[branch] = policy
+		}
+	}
+	for branch, policy := range p.Branches {
+		policies[branch] = policy
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	branches := make(map[string]config.Branch, len(policies))
+	for branch, policy := range policies {
+		branches[branch] = config.Branch{Policy: policy.policy()}
+	}
+	return branches, nil
+}
+
+func (bp BranchPolicy) policy() config.Policy {
+	policy := config.Policy{
+		Protect: bp.Protect,
+		Admins:  bp.Admins,
+	}
+	if len(bp.RequiredContexts) > 0 || bp.Strict != nil {
+		policy.RequiredStatusChecks = &config.ContextPolicy{
+			Contexts: bp.RequiredContexts,
+			Strict:   bp.Strict,
+		}
+	}
+	if r := bp.Restrictions; r != nil {
+		policy.Restrictions = &config.Restrictions{
+			Apps:  r.Apps,
+			Users: r.Users,
+			Teams: r.Teams,
+		}
+	}
+	if bp.RequiredApprovingReviews != nil {
+		policy.RequiredPullRequestReviews = &config.ReviewPolicy{
+			Approvals: bp.RequiredApprovingReviews,
+		}
+	}
+	return policy
+}
+
+// policies returns the policies of release branches, the required contexts are the presubmits
+// generated for the branch.
+func (rb ReleaseBranchProtection) policies(cfgs []ReleaseBuildConfiguration) (map[string]BranchPolicy, error) {
+	match := rb.Match
+	if match == "" {
+		match = defaultReleaseBranchPattern
+	}
+	branchRegexp, err := regexp.Compile(match)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release branch regular expression %q: %w", match, err)
+	}
+	excludes := make([]*regexp.Regexp, 0, len(rb.ExcludeContexts))
+	for _, expr := range rb.ExcludeContexts {
+		r, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid context regular expression %q: %w", expr, err)
+		}
+		excludes = append(excludes, r)
+	}
+
+	contexts := make(map[string]sets.String)
+	for _, cfg := range cfgs {
+		branch := cfg.Metadata.Branch
+		if !branchRegexp.MatchString(branch) {
+			continue
+		}
+		if _, ok := contexts[branch]; !ok {
+			contexts[branch] = sets.NewString(rb.AdditionalContexts...)
+		}
+		for _, c := range requiredPresubmitContexts(cfg) {
+			excluded := false
+			for _, r := range excludes {
+				if r.MatchString(c) {
+					excluded = true
+					break
+				}
+			}
+			if !excluded {
+				contexts[branch].Insert(c)
+			}
+		}
+	}
+
+	policies := make(map[string]BranchPolicy, len(contexts))
+	for branch, c := range contexts {
+		policies[branch] = BranchPolicy{
+			Protect:          ptr.To(true),
+			RequiredContexts: c.List(),
+			Strict:           rb.Strict,
+		}
+	}
+	return policies, nil
+}
+
+// requiredPresubmitContexts returns the status contexts of the presubmits generated by
+// ci-operator for the given configuration that run on every pull request and aren't optional.
+//
+// Presubmits with skip_if_only_changed are required since Prow reports them as successful when
+// they're skipped.
+func requiredPresubmitContexts(cfg ReleaseBuildConfiguration) []string {
+	prefix := "ci/prow/"
+	if cfg.Metadata.Variant != "" {
+		prefix += cfg.Metadata.Variant + "-"
+	}
+
+	var contexts []string
+	if images := cfg.Images; len(images.Items) > 0 && images.RunIfChanged == "" {
+		contexts = append(contexts, prefix+"images")
+	}
+	for _, t := range cfg.Tests {
+		if t.Cron != nil || t.Interval != nil || t.MinimumInterval != nil || t.Postsubmit {
+			continue
+		}
+		if t.Optional || t.RunIfChanged != "" || (t.AlwaysRun != nil && !*t.AlwaysRun) {
+			continue
+		}
+		contexts = append(contexts, prefix+t.As)
+	}
+	sort.Strings(contexts)
+	return contexts
+}
@@ -0,0 +1,159 @@
+package prowgen
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openshift/ci-tools/pkg/api/shardprowconfig"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/git/types"
+)
+
+func TestNewProwConfig(t *testing.T) {
+	missingLabels := []string{
+		"backports/unvalidated-commits",
+		"do-not-merge/hold",
+		"do-not-merge/invalid-owners-file",
+		"do-not-merge/work-in-progress",
+		"jira/invalid-bug",
+		"needs-rebase",
+	}
+	defaultTide := func(repo string) *shardprowconfig.TideConfig {
+		return &shardprowconfig.TideConfig{
+			MergeType: map[string]types.PullRequestMergeType{repo: types.MergeSquash},
+			Queries: config.TideQueries{
+				{Labels: []string{"approved", "lgtm"}, MissingLabels: missingLabels, Repos: []string{repo}},
+				{Labels: []string{"skip-review"}, MissingLabels: missingLabels, Repos: []string{repo}, Author: "red-hat-konflux-kflux-prd-rh02[bot]"},
+				{Labels: []string{"skip-review"}, MissingLabels: missingLabels, Repos: []string{repo}, Author: "serverless-qe"},
+			},
+		}
+	}
+	branchProtection := func(org, repo string, branches map[string]config.Branch) *config.BranchProtection {
+		return &config.BranchProtection{
+			Orgs: map[string]config.Org{
+				org: {Repos: map[string]config.Repo{repo: {Branches: branches}}},
+			},
+		}
+	}
+
+	r := Repository{
+		Org:         "openshift-knative",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests:    []E2ETest{{Match: "test-e2e$"}, {Match: "test-e2e-tls$"}},
+	}
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, r.Org, r.Repo+".git"), "release-v1.15")
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	// Tests run on every pull request skip only changes of non-code files
+	// (skip_if_only_changed), on demand tests never run automatically (always_run: false).
+	cfgs, err := NewGenerateConfigs(ctx, r, CommonConfig{
+		Branches: map[string]Branch{
+			"release-v1.15": {
+				OpenShiftVersions: []OpenShift{
+					{Version: "4.17", UseClusterPool: true},
+					{Version: "4.16", OnDemand: true},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		r    Repository
+		want shardprowconfig.ProwConfigWithPointers
+	}{
+		{
+			name: "default",
+			r:    Repository{Org: "openshift-knative", Repo: "serving"},
+			want: shardprowconfig.ProwConfigWithPointers{
+				BranchProtection: branchProtection("openshift-knative", "serving", map[string]config.Branch{
+					"release-next":    {Policy: config.Policy{Protect: ptr.To(false)}},
+					"release-next-ci": {Policy: config.Policy{Protect: ptr.To(false)}},
+				}),
+				Tide: defaultTide("openshift-knative/serving"),
+			},
+		},
+		{
+			name: "serverless operator",
+			r:    Repository{Org: "openshift-knative", Repo: "serverless-operator"},
+			want: shardprowconfig.ProwConfigWithPointers{
+				Tide: defaultTide("openshift-knative/serverless-operator"),
+			},
+		},
+		{
+			name: "configured",
+			r: Repository{
+				Org:  "openshift-knative",
+				Repo: "serving",
+				Prow: &ProwConfig{
+					MergeMethod:   types.MergeRebase,
+					MissingLabels: []string{"do-not-merge/hold"},
+					Bots:          []string{"openshift-ci-robot"},
+					Queries: []TideQuery{
+						{Labels: []string{"approved", "lgtm", "backport-risk-assessed"}, MissingLabels: []string{"needs-rebase"}, ReviewApprovedRequired: true},
+					},
+					Branches: map[string]BranchPolicy{
+						"main": {
+							Protect:                  ptr.To(true),
+							Admins:                   ptr.To(true),
+							RequiredApprovingReviews: ptr.To(1),
+							Restrictions:             &BranchRestrictions{Teams: []string{"serverless-release"}},
+						},
+					},
+					ReleaseBranches: &ReleaseBranchProtection{
+						ExcludeContexts:    []string{"^ci/prow/417-test-e2e-tls$"},
+						AdditionalContexts: []string{"tide"},
+						Strict:             ptr.To(true),
+					},
+				},
+			},
+			want: shardprowconfig.ProwConfigWithPointers{
+				BranchProtection: branchProtection("openshift-knative", "serving", map[string]config.Branch{
+					"main": {Policy: config.Policy{
+						Protect:                    ptr.To(true),
+						Admins:                     ptr.To(true),
+						Restrictions:               &config.Restrictions{Teams: []string{"serverless-release"}},
+						RequiredPullRequestReviews: &config.ReviewPolicy{Approvals: ptr.To(1)},
+					}},
+					"release-v1.15": {Policy: config.Policy{
+						Protect: ptr.To(true),
+						RequiredStatusChecks: &config.ContextPolicy{
+							Contexts: []string{
+								"ci/prow/417-images",
+								"ci/prow/417-test-e2e",
+								"tide",
+							},
+							Strict: ptr.To(true),
+						},
+					}},
+				}),
+				Tide: &shardprowconfig.TideConfig{
+					MergeType: map[string]types.PullRequestMergeType{"openshift-knative/serving": types.MergeRebase},
+					Queries: config.TideQueries{
+						{Labels: []string{"approved", "lgtm"}, MissingLabels: []string{"do-not-merge/hold"}, Repos: []string{"openshift-knative/serving"}},
+						{Labels: []string{"skip-review"}, MissingLabels: []string{"do-not-merge/hold"}, Repos: []string{"openshift-knative/serving"}, Author: "openshift-ci-robot"},
+						{Labels: []string{"approved", "lgtm", "backport-risk-assessed"}, MissingLabels: []string{"needs-rebase"}, Repos: []string{"openshift-knative/serving"}, ReviewApprovedRequired: true},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewProwConfig(tt.r, cfgs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("NewProwConfig() (-want, +got):", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package prowgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+)
+
+// Schedule configures the global scheduler of periodic jobs, it assigns stable cron schedules
+// to periodic tests of every repository, unless the OpenShift version has an explicit cron,
+// respecting cluster pool limits.
+type Schedule struct {
+	// Windows are the days and hours periodic jobs start in, the first window matching a
+	// repository is used.
+	// By default, serverless-operator jobs start on Monday and Friday and any other job on
+	// Tuesday and Saturday, between 00:00 and 06:00 UTC.
+	Windows []ScheduleWindow `json:"windows,omitempty" yaml:"windows,omitempty"`
+	// Limits are the maximum number of periodic jobs starting in the same hour using the same
+	// cluster pool, the first limit matching a cluster pool is used.
+	Limits []ScheduleLimit `json:"limits,omitempty" yaml:"limits,omitempty"`
+}
+
+type ScheduleWindow struct {
+	// Repositories are regular expressions matching <org>/<repo>, the window applies to
+	// every repository when empty.
+	Repositories []string `json:"repositories,omitempty" yaml:"repositories,omitempty"`
+	// Days are the days of the week, from 0 (Sunday) to 6 (Saturday).
+	Days []int `json:"days,omitempty" yaml:"days,omitempty"`
+	// StartHour (inclusive) and EndHour (exclusive) are UTC hours, from 0 to 24.
+	StartHour int `json:"startHour,omitempty" yaml:"startHour,omitempty"`
+	EndHour   int `json:"endHour,omitempty" yaml:"endHour,omitempty"`
+}
+
+type ScheduleLimit struct {
+	// Pool is a pattern (see path.Match) matching cluster pool names, either
+	// claim:<owner>/<cloud>/<OpenShift version>/<architecture> for tests claiming clusters
+	// or profile:<cluster profile> for tests starting clusters from scratch.
+	Pool string `json:"pool,omitempty" yaml:"pool,omitempty"`
+	Max  int    `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// ScheduleReport is the result of scheduling periodic jobs.
+type ScheduleReport struct {
+	Jobs []ScheduledJob `json:"jobs"`
+	// Load is the number of jobs starting in each day and hour per cluster pool.
+	Load []ScheduleLoad `json:"load"`
+}
+
+type ScheduledJob struct {
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+	Variant    string `json:"variant,omitempty"`
+	Test       string `json:"test"`
+	Pool       string `json:"pool,omitempty"`
+	Cron       string `json:"cron"`
+	// Overbooked is true when no slot within the pool limit was available.
+	Overbooked bool `json:"overbooked,omitempty"`
+}
+
+type ScheduleLoad struct {
+	Pool  string `json:"pool"`
+	Day   int    `json:"day"`
+	Hour  int    `json:"hour"`
+	Jobs  int    `json:"jobs"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+var defaultScheduleWindows = []ScheduleWindow{
+	// Run s-o tests on other days to prevent hitting limits in AWS.
+	{Repositories: []string{".*/serverless-operator"}, Days: []int{1, 5}, StartHour: 0, EndHour: 6},
+	{Days: []int{2, 6}, StartHour: 0, EndHour: 6},
+}
+
+// scheduleForConfigs returns the schedule defined in the given configurations, at most one
+// configuration can define it.
+func scheduleForConfigs(inConfigs []*Config) (*Schedule, error) {
+	var schedule *Schedule
+	for _, inConfig := range inConfigs {
+		if inConfig.Schedule == nil {
+			continue
+		}
+		if schedule != nil {
+			return nil, fmt.Errorf("schedule is defined in multiple configurations")
+		}
+		schedule = inConfig.Schedule
+	}
+	return schedule, nil
+}
+
+func (s Schedule) windows() []ScheduleWindow {
+	if len(s.Windows) == 0 {
+		return defaultScheduleWindows
+	}
+	return s.Windows
+}
+
+func (s Schedule) validate() error {
+	for i, w := range s.Windows {
+		if len(w.Days) == 0 {
+			return fmt.Errorf("windows[%d]: days are required", i)
+		}
+		for _, d := range w.Days {
+			if d < 0 || d > 6 {
+				return fmt.Errorf("windows[%d]: invalid day %d, expected 0 (Sunday) to 6 (Saturday)", i, d)
+			}
+		}
+		if w.StartHour < 0 || w.EndHour > 24 || w.StartHour >= w.EndHour {
+			return fmt.Errorf("windows[%d]: invalid hours [%d, %d), expected 0 <= startHour < endHour <= 24", i, w.StartHour, w.EndHour)
+		}
+		for _, expr := range w.Repositories {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("windows[%d]: invalid regular expression %q: %w", i, expr, err)
+			}
+		}
+	}
+	for i, l := range s.Limits {
+		if _, err := path.Match(l.Pool, ""); err != nil {
+			return fmt.Errorf("limits[%d]: invalid pool pattern %q: %w", i, l.Pool, err)
+		}
+		if l.Max <= 0 {
+			return fmt.Errorf("limits[%d]: max must be positive", i)
+		}
+	}
+	return nil
+}
+
+func (s Schedule) window(repository string) (ScheduleWindow, bool) {
+	for _, w := range s.windows() {
+		if len(w.Repositories) == 0 {
+			return w, true
+		}
+		for _, expr := range w.Repositories {
+			if regexp.MustCompile(expr).MatchString(repository) {
+				return w, true
+			}
+		}
+	}
+	return ScheduleWindow{}, false
+}
+
+func (s Schedule) limit(pool string) int {
+	for _, l := range s.Limits {
+		if ok, _ := path.Match(l.Pool, pool); ok {
+			return l.Max
+		}
+	}
+	return 0
+}
+
+// clusterPool returns the name of the cluster pool used by the given test, see ScheduleLimit.Pool.
+func clusterPool(test cioperatorapi.TestStepConfiguration) string {
+	if c := test.ClusterClaim; c != nil {
+		return fmt.Sprintf("claim:%s/%s/%s/%s", c.Owner, c.Cloud, c.Version, c.Architecture)
+	}
+	if ms := test.MultiStageTestConfiguration; ms != nil && ms.ClusterProfile != "" {
+		return "profile:" + string(ms.ClusterProfile)
+	}
+	return ""
+}
+
+type scheduleSlot struct {
+	pool string
+	day  int
+	hour int
+}
+
+// ScheduleConfigs assigns cron schedules to the periodic tests of the given configurations
+// using the default schedule (see ReleaseBuildConfiguration.ScheduledTests).
+//
+// Each job gets a preferred slot derived from a hash of repository, branch, variant and test,
+// so that schedules don't change when other jobs are added or removed, the next hour in the
+// window is used when the preferred one exceeds the cluster pool limit.
+func ScheduleConfigs(schedule Schedule, cfgs []*ReleaseBuildConfiguration) (*ScheduleReport, error) {
+	if err := schedule.validate(); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	type job struct {
+		key    string
+		test   *cioperatorapi.TestStepConfiguration
+		report ScheduledJob
+	}
+	var jobs []job
+	for _, cfg := range cfgs {
+		scheduled := make(map[string]bool, len(cfg.ScheduledTests))
+		for _, name := range cfg.ScheduledTests {
+			scheduled[name] = true
+		}
+		for i := range cfg.Tests {
+			test := &cfg.Tests[i]
+			if !scheduled[test.As] {
+				continue
+			}
+			repository := cfg.Metadata.Org + "/" + cfg.Metadata.Repo
+			jobs = append(jobs, job{
+				key:  strings.Join([]string{repository, cfg.Metadata.Branch, cfg.Metadata.Variant, test.As}, "/"),
+				test: test,
+				report: ScheduledJob{
+					Repository: repository,
+					Branch:     cfg.Metadata.Branch,
+					Variant:    cfg.Metadata.Variant,
+					Test:       test.As,
+					Pool:       clusterPool(*test),
+				},
+			})
+		}
+	}
+	// Jobs are scheduled in a stable order, regardless of the order of configurations.
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].key < jobs[j].key
+	})
+
+	load := make(map[scheduleSlot]int)
+	report := &ScheduleReport{Jobs: make([]ScheduledJob, 0, len(jobs))}
+	for _, j := range jobs {
+		w, ok := schedule.window(j.report.Repository)
+		if !ok {
+			return nil, fmt.Errorf("no schedule window for %s", j.key)
+		}
+		hours := w.EndHour - w.StartHour
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(j.key))
+		preferred := int(h.Sum32() % uint32(hours*60))
+		minute := preferred % 60
+
+		limit := 0
+		if j.report.Pool != "" {
+			limit = schedule.limit(j.report.Pool)
+		}
+		hour, overbooked := -1, true
+		leastLoaded, leastLoad := -1, 0
+		for offset := 0; offset < hours; offset++ {
+			candidate := w.StartHour + (preferred/60+offset)%hours
+			maxLoad := 0
+			for _, d := range w.Days {
+				maxLoad = max(maxLoad, load[scheduleSlot{pool: j.report.Pool, day: d, hour: candidate}])
+			}
+			if limit == 0 || maxLoad < limit {
+				hour, overbooked = candidate, false
+				break
+			}
+			if leastLoaded < 0 || maxLoad < leastLoad {
+				leastLoaded, leastLoad = candidate, maxLoad
+			}
+		}
+		if overbooked {
+			hour = leastLoaded
+			log.Printf("Periodic job %s exceeds the limit of %d jobs per hour for cluster pool %s", j.key, limit, j.report.Pool)
+		}
+		for _, d := range w.Days {
+			load[scheduleSlot{pool: j.report.Pool, day: d, hour: hour}]++
+		}
+
+		days := make([]string, 0, len(w.Days))
+		for _, d := range w.Days {
+			days = append(days, strconv.Itoa(d))
+		}
+		cron := fmt.Sprintf("%d %d * * %s", minute, hour, strings.Join(days, ","))
+		j.test.Cron = &cron
+
+		j.report.Cron = cron
+		j.report.Overbooked = overbooked
+		report.Jobs = append(report.Jobs, j.report)
+	}
+
+	report.Load = make([]ScheduleLoad, 0, len(load))
+	for slot, n := range load {
+		l := ScheduleLoad{Pool: slot.pool, Day: slot.day, Hour: slot.hour, Jobs: n}
+		if slot.pool != "" {
+			l.Limit = schedule.limit(slot.pool)
+		}
+		report.Load = append(report.Load, l)
+	}
+	sort.Slice(report.Load, func(i, j int) bool {
+		a, b := report.Load[i], report.Load[j]
+		if a.Pool != b.Pool {
+			return a.Pool < b.Pool
+		}
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		return a.Hour < b.Hour
+	})
+	return report, nil
+}
+
+// Write writes the report as JSON.
+func (r *ScheduleReport) Write(w io.Writer) error {
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
@@ -0,0 +1,179 @@
+package prowgen
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+)
+
+func TestScheduleConfigs(t *testing.T) {
+	claim := &cioperatorapi.ClusterClaim{
+		Owner:        "serverless-ci",
+		Cloud:        cioperatorapi.CloudAWS,
+		Version:      "4.16",
+		Architecture: cioperatorapi.ReleaseArchitectureAMD64,
+	}
+	periodic := func(as string) cioperatorapi.TestStepConfiguration {
+		return cioperatorapi.TestStepConfiguration{As: as, Cron: pointer.String("0 0 * * 2,6"), ClusterClaim: claim}
+	}
+	newConfigs := func() []*ReleaseBuildConfiguration {
+		return []*ReleaseBuildConfiguration{
+			{
+				ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+					Metadata: cioperatorapi.Metadata{Org: "openshift-knative", Repo: "serving", Branch: "release-next", Variant: "416"},
+					Tests: []cioperatorapi.TestStepConfiguration{
+						{As: "test-e2e", ClusterClaim: claim},
+						periodic("test-e2e-c"),
+						periodic("test-e2e-tls-c"),
+						periodic("test-upgrade-c"),
+						{As: "custom-c", Cron: pointer.String("0 12 * * *")},
+					},
+				},
+				ScheduledTests: []string{"test-e2e-c", "test-e2e-tls-c", "test-upgrade-c"},
+			},
+			{
+				ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+					Metadata: cioperatorapi.Metadata{Org: "openshift-knative", Repo: "serverless-operator", Branch: "main", Variant: "416"},
+					Tests:    []cioperatorapi.TestStepConfiguration{periodic("operator-e2e-c")},
+				},
+				ScheduledTests: []string{"operator-e2e-c"},
+			},
+		}
+	}
+
+	schedule := Schedule{
+		Windows: []ScheduleWindow{
+			{Repositories: []string{".*/serverless-operator"}, Days: []int{1, 5}, StartHour: 0, EndHour: 6},
+			{Days: []int{2, 6}, StartHour: 0, EndHour: 2},
+		},
+		Limits: []ScheduleLimit{{Pool: "claim:serverless-ci/*/*/*", Max: 1}},
+	}
+
+	cfgs := newConfigs()
+	report, err := ScheduleConfigs(schedule, cfgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 jobs in a 2 hours window with 1 job per hour overbook one of the hours.
+	overbooked := 0
+	for _, j := range report.Jobs {
+		if j.Overbooked {
+			overbooked++
+		}
+	}
+	if overbooked != 1 {
+		t.Errorf("want 1 overbooked job, got %d: %+v", overbooked, report.Jobs)
+	}
+
+	crons := map[string]string{}
+	for _, cfg := range cfgs {
+		for _, test := range cfg.Tests {
+			if test.Cron != nil {
+				crons[test.As] = *test.Cron
+			}
+		}
+	}
+	for _, j := range report.Jobs {
+		if crons[j.Test] != j.Cron {
+			t.Errorf("test %s: cron %q doesn't match report %q", j.Test, crons[j.Test], j.Cron)
+		}
+	}
+	if got := crons["custom-c"]; got != "0 12 * * *" {
+		t.Errorf("explicit cron changed: %q", got)
+	}
+	if got := crons["operator-e2e-c"]; got[len(got)-3:] != "1,5" {
+		t.Errorf("serverless-operator job not scheduled in its window: %q", got)
+	}
+
+	wantLoad := []ScheduleLoad{
+		{Pool: "claim:serverless-ci/aws/4.16/amd64", Day: 1, Hour: 5, Jobs: 1, Limit: 1},
+		{Pool: "claim:serverless-ci/aws/4.16/amd64", Day: 2, Hour: 0, Jobs: 1, Limit: 1},
+		{Pool: "claim:serverless-ci/aws/4.16/amd64", Day: 2, Hour: 1, Jobs: 2, Limit: 1},
+		{Pool: "claim:serverless-ci/aws/4.16/amd64", Day: 5, Hour: 5, Jobs: 1, Limit: 1},
+		{Pool: "claim:serverless-ci/aws/4.16/amd64", Day: 6, Hour: 0, Jobs: 1, Limit: 1},
+		{Pool: "claim:serverless-ci/aws/4.16/amd64", Day: 6, Hour: 1, Jobs: 2, Limit: 1},
+	}
+	if diff := cmp.Diff(wantLoad, report.Load); diff != "" {
+		t.Error("load (-want, +got):", diff)
+	}
+
+	// Schedules are stable when configurations are scheduled again, in any order.
+	again := newConfigs()
+	again[0], again[1] = again[1], again[0]
+	againReport, err := ScheduleConfigs(schedule, again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(report, againReport); diff != "" {
+		t.Error("schedule is not stable (-first, +second):", diff)
+	}
+}
+
+func TestScheduleConfigsStableSlots(t *testing.T) {
+	newConfig := func(tests ...string) *ReleaseBuildConfiguration {
+		cfg := &ReleaseBuildConfiguration{
+			ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+				Metadata: cioperatorapi.Metadata{Org: "openshift-knative", Repo: "eventing", Branch: "release-next", Variant: "416"},
+			},
+		}
+		for _, as := range tests {
+			cfg.Tests = append(cfg.Tests, cioperatorapi.TestStepConfiguration{As: as, Cron: pointer.String("0 0 * * 2,6")})
+			cfg.ScheduledTests = append(cfg.ScheduledTests, as)
+		}
+		return cfg
+	}
+
+	before := newConfig("test-e2e-c", "test-reconciler-c")
+	if _, err := ScheduleConfigs(Schedule{}, []*ReleaseBuildConfiguration{before}); err != nil {
+		t.Fatal(err)
+	}
+	// Adding a test earlier in sort order doesn't change existing schedules.
+	after := newConfig("test-a-c", "test-e2e-c", "test-reconciler-c")
+	if _, err := ScheduleConfigs(Schedule{}, []*ReleaseBuildConfiguration{after}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(before.Tests, after.Tests[1:]); diff != "" {
+		t.Error("schedules changed (-before, +after):", diff)
+	}
+	for _, test := range after.Tests {
+		if hour := hourOf(t, *test.Cron); hour < 0 || hour >= 6 {
+			t.Errorf("test %s scheduled outside the default window: %q", test.As, *test.Cron)
+		}
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	tcs := []struct {
+		name     string
+		schedule Schedule
+		wantErr  bool
+	}{
+		{name: "default", schedule: Schedule{}},
+		{name: "invalid day", schedule: Schedule{Windows: []ScheduleWindow{{Days: []int{7}, EndHour: 6}}}, wantErr: true},
+		{name: "invalid hours", schedule: Schedule{Windows: []ScheduleWindow{{Days: []int{1}, StartHour: 6, EndHour: 6}}}, wantErr: true},
+		{name: "invalid limit", schedule: Schedule{Limits: []ScheduleLimit{{Pool: "claim:*"}}}, wantErr: true},
+		{name: "invalid pool pattern", schedule: Schedule{Limits: []ScheduleLimit{{Pool: "[", Max: 1}}}, wantErr: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.schedule.validate()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("wantErr %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func hourOf(t *testing.T, cron string) int {
+	t.Helper()
+	var minute, hour int
+	var rest string
+	if _, err := fmt.Sscanf(cron, "%d %d %s", &minute, &hour, &rest); err != nil {
+		t.Fatalf("invalid cron %q: %v", cron, err)
+	}
+	return hour
+}
@@ -0,0 +1,115 @@
+package prowgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns the JSON Schema of the configuration file (see Config), it can be used
+// for editor completion and validation.
+func JSONSchema() ([]byte, error) {
+	g := &schemaGenerator{
+		defs:  map[string]interface{}{},
+		names: map[reflect.Type]string{},
+	}
+	root := g.schema(reflect.TypeOf(Config{}))
+
+	schema := map[string]interface{}{
+		"$schema": jsonSchemaDraft,
+		"title":   "prowgen configuration",
+		"$ref":    root["$ref"],
+		"$defs":   g.defs,
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+type schemaGenerator struct {
+	defs  map[string]interface{}
+	names map[reflect.Type]string
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeOf(prowapi.Duration{}):
+		return map[string]interface{}{"type": "string", "pattern": "^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"}
+	}
+	if hasCustomUnmarshaler(t) {
+		// Custom representations are not known, accept any value.
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.defName(t)
+			g.names[t] = name
+			// Register the name before generating the schema to handle recursive types.
+			g.defs[name] = nil
+			g.defs[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, f := range jsonFields(t) {
+		properties[f.name] = g.schema(f.typ)
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// defName returns a unique definition name for the named type t, for example, "prowgen.Repository".
+func (g *schemaGenerator) defName(t reflect.Type) string {
+	base := path.Base(t.PkgPath()) + "." + t.Name()
+	name := base
+	for i := 2; g.hasName(name); i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	return name
+}
+
+func (g *schemaGenerator) hasName(name string) bool {
+	for _, n := range g.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package prowgen
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+const slackReportTemplate = `{{if eq .Status.State "success"}} :rainbow: Job *{{.Spec.Job}}* ended with *{{.Status.State}}*. <{{.Status.URL}}|View logs> :rainbow: {{else}} :volcano: Job *{{.Spec.Job}}* ended with *{{.Status.State}}*. <{{.Status.URL}}|View logs> :volcano: {{end}}`
+
+var (
+	defaultJobStatesToReport = []prowapi.ProwJobState{prowapi.SuccessState, prowapi.FailureState, prowapi.ErrorState}
+
+	jobStates = sets.NewString(
+		string(prowapi.TriggeredState),
+		string(prowapi.PendingState),
+		string(prowapi.SuccessState),
+		string(prowapi.FailureState),
+		string(prowapi.AbortedState),
+		string(prowapi.ErrorState),
+	)
+)
+
+// SlackReporter configures the Slack notifications of the generated jobs, only periodic jobs
+// are reported by default.
+//
+// The repository, branch and e2e test reporters are merged in this order, fields set in a
+// later reporter override the earlier ones.
+type SlackReporter struct {
+	// Channel is the Slack channel, it defaults to Repository.SlackChannel.
+	Channel string `json:"channel,omitempty" yaml:"channel,omitempty"`
+	// JobStatesToReport are the job states reported, success, failure and error by default.
+	JobStatesToReport []prowapi.ProwJobState `json:"jobStatesToReport,omitempty" yaml:"jobStatesToReport,omitempty"`
+	// ReportTemplate is the Go template of the message, for example, using .Spec.Job and
+	// .Status.State.
+	ReportTemplate string `json:"reportTemplate,omitempty" yaml:"reportTemplate,omitempty"`
+	// Presubmits also reports presubmit jobs.
+	Presubmits *bool `json:"presubmits,omitempty" yaml:"presubmits,omitempty"`
+	// Disabled disables the Slack notifications.
+	Disabled *bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	// Routes report tests matching a route to a different channel, the first matching route
+	// applies.
+	Routes []SlackRoute `json:"routes,omitempty" yaml:"routes,omitempty"`
+}
+
+// SlackRoute overrides the reporter for the tests matching Match.
+type SlackRoute struct {
+	// Match is a regular expression matched against the test name, for example, kafka.
+	Match             string                 `json:"match,omitempty" yaml:"match,omitempty"`
+	Channel           string                 `json:"channel,omitempty" yaml:"channel,omitempty"`
+	JobStatesToReport []prowapi.ProwJobState `json:"jobStatesToReport,omitempty" yaml:"jobStatesToReport,omitempty"`
+	ReportTemplate    string                 `json:"reportTemplate,omitempty" yaml:"reportTemplate,omitempty"`
+}
+
+// ReporterConfig is the reporter_config of a test in ci-operator configurations.
+type ReporterConfig struct {
+	Channel           string                 `json:"channel"`
+	JobStatesToReport []prowapi.ProwJobState `json:"job_states_to_report,omitempty"`
+	ReportTemplate    string                 `json:"report_template,omitempty"`
+}
+
+func (s *SlackReporter) validate() error {
+	if err := validateSlackReport(s.JobStatesToReport, s.ReportTemplate); err != nil {
+		return err
+	}
+	for i, route := range s.Routes {
+		if route.Match == "" {
+			return fmt.Errorf("routes[%d]: match is required", i)
+		}
+		if _, err := regexp.Compile(route.Match); err != nil {
+			return fmt.Errorf("routes[%d]: %w", i, err)
+		}
+		if err := validateSlackReport(route.JobStatesToReport, route.ReportTemplate); err != nil {
+			return fmt.Errorf("routes[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateSlackReport(states []prowapi.ProwJobState, reportTemplate string) error {
+	for _, state := range states {
+		if !jobStates.Has(string(state)) {
+			return fmt.Errorf("unknown job state %q, supported states: %v", state, jobStates.List())
+		}
+	}
+	if reportTemplate != "" {
+		if _, err := template.New("report").Parse(reportTemplate); err != nil {
+			return fmt.Errorf("invalid report template: %w", err)
+		}
+	}
+	return nil
+}
+
+// slackReporter returns the reporter of the tests of the branch, nil when the branch isn't
+// reported.
+func slackReporter(r Repository, branch Branch) *SlackReporter {
+	if r.SlackChannel == "" && r.SlackReporter == nil && branch.SlackReporter == nil {
+		return nil
+	}
+	reporter := SlackReporter{Channel: r.SlackChannel}
+	for _, s := range []*SlackReporter{r.SlackReporter, branch.SlackReporter} {
+		if s != nil {
+			mergeInto(reflect.ValueOf(&reporter).Elem(), reflect.ValueOf(*s))
+		}
+	}
+	return &reporter
+}
+
+// reporterConfigs returns the reporter config of each reported test, by test name, e2eReporters
+// are the reporters of the e2e tests by test name (without the periodic suffix).
+func reporterConfigs(reporter *SlackReporter, tests []cioperatorapi.TestStepConfiguration, e2eReporters map[string]*SlackReporter) (map[string]ReporterConfig, error) {
+	if reporter == nil {
+		return nil, nil
+	}
+	var configs map[string]ReporterConfig
+	for _, test := range tests {
+		periodic := test.Cron != nil
+		name := test.As
+		if periodic {
+			name = strings.TrimSuffix(name, "-c")
+		}
+		testReporter := *reporter
+		if e2e := e2eReporters[name]; e2e != nil {
+			mergeInto(reflect.ValueOf(&testReporter).Elem(), reflect.ValueOf(*e2e))
+		}
+
+		if ptr.Deref(testReporter.Disabled, false) || (!periodic && !ptr.Deref(testReporter.Presubmits, false)) {
+			continue
+		}
+
+		cfg := ReporterConfig{
+			Channel:           testReporter.Channel,
+			JobStatesToReport: testReporter.JobStatesToReport,
+			ReportTemplate:    testReporter.ReportTemplate,
+		}
+		for _, route := range testReporter.Routes {
+			matches, err := regexp.MatchString(route.Match, test.As)
+			if err != nil {
+				return nil, fmt.Errorf("failed to match Slack route %q: %w", route.Match, err)
+			}
+			if !matches {
+				continue
+			}
+			if route.Channel != "" {
+				cfg.Channel = route.Channel
+			}
+			if len(route.JobStatesToReport) > 0 {
+				cfg.JobStatesToReport = route.JobStatesToReport
+			}
+			if route.ReportTemplate != "" {
+				cfg.ReportTemplate = route.ReportTemplate
+			}
+			break
+		}
+		if cfg.Channel == "" {
+			continue
+		}
+		if len(cfg.JobStatesToReport) == 0 {
+			cfg.JobStatesToReport = defaultJobStatesToReport
+		}
+		if cfg.ReportTemplate == "" {
+			cfg.ReportTemplate = slackReportTemplate
+		}
+
+		if configs == nil {
+			configs = make(map[string]ReporterConfig)
+		}
+		configs[test.As] = cfg
+	}
+	return configs, nil
+}
+
+// e2eSlackReporters returns the reporters of the e2e tests discovered in the checked out branch,
+// by test name.
+func e2eSlackReporters(ctx context.Context, r Repository) (map[string]*SlackReporter, error) {
+	if !slices.ContainsFunc(r.E2ETests, func(e2e E2ETest) bool { return e2e.SlackReporter != nil }) {
+		return nil, nil
+	}
+	tests, err := discoverE2ETests(ctx, r, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	reporters := make(map[string]*SlackReporter)
+	for i := range tests {
+		if tests[i].SlackReporter != nil {
+			reporters[ToName(r, &tests[i])] = tests[i].SlackReporter
+		}
+	}
+	return reporters, nil
+}
+
+// reportedReleaseBuildConfiguration is the ci-operator configuration with the reporter config of
+// each reported test.
+type reportedReleaseBuildConfiguration struct {
+	cioperatorapi.ReleaseBuildConfiguration `json:",inline"`
+
+	Tests []reportedTestStepConfiguration `json:"tests,omitempty"`
+}
+
+type reportedTestStepConfiguration struct {
+	cioperatorapi.TestStepConfiguration `json:",inline"`
+
+	ReporterConfig *ReporterConfig `json:"reporter_config,omitempty"`
+}
+
+func withReporterConfigs(cfg cioperatorapi.ReleaseBuildConfiguration, configs map[string]ReporterConfig) reportedReleaseBuildConfiguration {
+	reported := reportedReleaseBuildConfiguration{ReleaseBuildConfiguration: cfg}
+	for _, test := range cfg.Tests {
+		t := reportedTestStepConfiguration{TestStepConfiguration: test}
+		if rc, ok := configs[test.As]; ok {
+			t.ReporterConfig = &rc
+		}
+		reported.Tests = append(reported.Tests, t)
+	}
+	return reported
+}
@@ -0,0 +1,153 @@
+package prowgen
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/ptr"
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestReporterConfigs(t *testing.T) {
+	tests := []cioperatorapi.TestStepConfiguration{
+		{As: "test-e2e"},
+		{As: "test-e2e-c", Cron: ptr.To("23 1 * * 2,6")},
+		{As: "test-kafka-e2e"},
+		{As: "test-kafka-e2e-c", Cron: ptr.To("23 2 * * 2,6")},
+		{As: "test-upgrade-c", Cron: ptr.To("23 3 * * 2,6")},
+	}
+	defaultReport := func(channel string) ReporterConfig {
+		return ReporterConfig{
+			Channel:           channel,
+			JobStatesToReport: defaultJobStatesToReport,
+			ReportTemplate:    slackReportTemplate,
+		}
+	}
+
+	for _, tt := range []struct {
+		name         string
+		repository   Repository
+		branch       Branch
+		e2eReporters map[string]*SlackReporter
+		want         map[string]ReporterConfig
+	}{
+		{
+			name: "not reported",
+		},
+		{
+			name:       "repository channel reports periodics",
+			repository: Repository{SlackChannel: "#knative-eventing-ci"},
+			want: map[string]ReporterConfig{
+				"test-e2e-c":       defaultReport("#knative-eventing-ci"),
+				"test-kafka-e2e-c": defaultReport("#knative-eventing-ci"),
+				"test-upgrade-c":   defaultReport("#knative-eventing-ci"),
+			},
+		},
+		{
+			name: "routes, states and templates",
+			repository: Repository{
+				SlackChannel: "#knative-eventing-ci",
+				SlackReporter: &SlackReporter{
+					JobStatesToReport: []prowapi.ProwJobState{prowapi.FailureState},
+					Routes: []SlackRoute{
+						{Match: "kafka", Channel: "#knative-kafka-ci", ReportTemplate: "Kafka {{.Spec.Job}}"},
+						{Match: ".*", JobStatesToReport: []prowapi.ProwJobState{prowapi.FailureState, prowapi.ErrorState}},
+					},
+				},
+			},
+			want: map[string]ReporterConfig{
+				"test-e2e-c":       {Channel: "#knative-eventing-ci", JobStatesToReport: []prowapi.ProwJobState{prowapi.FailureState, prowapi.ErrorState}, ReportTemplate: slackReportTemplate},
+				"test-kafka-e2e-c": {Channel: "#knative-kafka-ci", JobStatesToReport: []prowapi.ProwJobState{prowapi.FailureState}, ReportTemplate: "Kafka {{.Spec.Job}}"},
+				"test-upgrade-c":   {Channel: "#knative-eventing-ci", JobStatesToReport: []prowapi.ProwJobState{prowapi.FailureState, prowapi.ErrorState}, ReportTemplate: slackReportTemplate},
+			},
+		},
+		{
+			name:       "branch presubmits and e2e test overrides",
+			repository: Repository{SlackChannel: "#serverless-ci"},
+			branch:     Branch{SlackReporter: &SlackReporter{Presubmits: ptr.To(true)}},
+			e2eReporters: map[string]*SlackReporter{
+				"test-kafka-e2e": {Channel: "#knative-kafka-ci"},
+				"test-upgrade":   {Disabled: ptr.To(true)},
+			},
+			want: map[string]ReporterConfig{
+				"test-e2e":         defaultReport("#serverless-ci"),
+				"test-e2e-c":       defaultReport("#serverless-ci"),
+				"test-kafka-e2e":   defaultReport("#knative-kafka-ci"),
+				"test-kafka-e2e-c": defaultReport("#knative-kafka-ci"),
+			},
+		},
+		{
+			name:       "disabled branch",
+			repository: Repository{SlackChannel: "#serverless-ci"},
+			branch:     Branch{SlackReporter: &SlackReporter{Disabled: ptr.To(true)}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reporterConfigs(slackReporter(tt.repository, tt.branch), tests, tt.e2eReporters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("(-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestMarshalReleaseBuildConfigurationReporterConfig(t *testing.T) {
+	cfg := ReleaseBuildConfiguration{
+		ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+			Tests: []cioperatorapi.TestStepConfiguration{
+				{As: "test-e2e"},
+				{As: "test-e2e-c", Cron: ptr.To("23 1 * * 2,6")},
+			},
+		},
+		ReporterConfigs: map[string]ReporterConfig{
+			"test-e2e-c": {
+				Channel:           "#knative-eventing-ci",
+				JobStatesToReport: defaultJobStatesToReport,
+				ReportTemplate:    slackReportTemplate,
+			},
+		},
+	}
+	out, err := marshalReleaseBuildConfiguration(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result map[string]interface{}
+	if err := yaml.Unmarshal(out, &result); err != nil {
+		t.Fatalf("failed to unmarshal output: %v", err)
+	}
+	want := []interface{}{
+		map[string]interface{}{"as": "test-e2e"},
+		map[string]interface{}{
+			"as":   "test-e2e-c",
+			"cron": "23 1 * * 2,6",
+			"reporter_config": map[string]interface{}{
+				"channel":              "#knative-eventing-ci",
+				"job_states_to_report": []interface{}{"success", "failure", "error"},
+				"report_template":      slackReportTemplate,
+			},
+		},
+	}
+	if diff := cmp.Diff(want, result["tests"]); diff != "" {
+		t.Error("tests (-want, +got):", diff)
+	}
+
+	// Configurations without reported tests are unchanged.
+	cfg.ReporterConfigs = nil
+	out, err = marshalReleaseBuildConfiguration(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := yaml.Marshal(cfg.ReleaseBuildConfiguration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(plain), string(out)); diff != "" {
+		t.Error("(-want, +got):", diff)
+	}
+}
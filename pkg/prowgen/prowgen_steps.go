@@ -0,0 +1,213 @@
+package prowgen
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+// Steps are the pre and post steps of generated e2e tests, they can be configured for a
+// repository, a branch or a single test (in increasing order of precedence).
+type Steps struct {
+	// Pre steps replace the pre steps of the workflow.
+	Pre []Step `json:"pre,omitempty" yaml:"pre,omitempty"`
+	// Post steps run before the deprovision step of the selected cluster profile.
+	Post []Step `json:"post,omitempty" yaml:"post,omitempty"`
+	// Periodic overrides Pre and/or Post for periodic tests, by default periodic tests run
+	// the presubmit steps with gather steps running on success too.
+	Periodic *PeriodicSteps `json:"periodic,omitempty" yaml:"periodic,omitempty"`
+}
+
+type PeriodicSteps struct {
+	Pre  []Step `json:"pre,omitempty" yaml:"pre,omitempty"`
+	Post []Step `json:"post,omitempty" yaml:"post,omitempty"`
+}
+
+// Step is either a reference to a step or a chain in the openshift/release step registry
+// or a literal step.
+type Step struct {
+	StepReference `json:",inline" yaml:",inline"`
+
+	As string `json:"as,omitempty" yaml:"as,omitempty"`
+	// From is the image the step runs in, it defaults to the repository source image.
+	From              string                             `json:"from,omitempty" yaml:"from,omitempty"`
+	Commands          string                             `json:"commands,omitempty" yaml:"commands,omitempty"`
+	Resources         cioperatorapi.ResourceRequirements `json:"resources,omitempty" yaml:"resources,omitempty"`
+	Timeout           *prowapi.Duration                  `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	GracePeriod       *prowapi.Duration                  `json:"gracePeriod,omitempty" yaml:"gracePeriod,omitempty"`
+	BestEffort        *bool                              `json:"bestEffort,omitempty" yaml:"bestEffort,omitempty"`
+	OptionalOnSuccess *bool                              `json:"optionalOnSuccess,omitempty" yaml:"optionalOnSuccess,omitempty"`
+	Cli               string                             `json:"cli,omitempty" yaml:"cli,omitempty"`
+}
+
+// defaultSteps gathers test logs and cluster state after each test.
+var defaultSteps = Steps{
+	Post: []Step{
+		{
+			As:                "testlog-gather",
+			Commands:          `cp -v ${SHARED_DIR}/debuglog-*.log ${SHARED_DIR}/stdout-*.log ${SHARED_DIR}/stderr-*.log "${ARTIFACT_DIR}/" || true`,
+			Resources:         cioperatorapi.ResourceRequirements{Requests: cioperatorapi.ResourceList{"cpu": "100m"}},
+			Timeout:           &prowapi.Duration{Duration: 1 * time.Minute},
+			BestEffort:        pointer.Bool(true),
+			OptionalOnSuccess: pointer.Bool(true),
+			Cli:               "latest",
+		},
+		{
+			As:                "knative-must-gather",
+			Commands:          `oc adm must-gather --image=quay.io/openshift-knative/must-gather --dest-dir "${ARTIFACT_DIR}/gather-knative"`,
+			Resources:         cioperatorapi.ResourceRequirements{Requests: cioperatorapi.ResourceList{"cpu": "100m"}},
+			Timeout:           &prowapi.Duration{Duration: 20 * time.Minute},
+			BestEffort:        pointer.Bool(true),
+			OptionalOnSuccess: pointer.Bool(true),
+			Cli:               "latest",
+		},
+		{
+			As:                "openshift-must-gather",
+			Commands:          `oc adm must-gather --dest-dir "${ARTIFACT_DIR}/gather-openshift"`,
+			Resources:         cioperatorapi.ResourceRequirements{Requests: cioperatorapi.ResourceList{"cpu": "100m"}},
+			Timeout:           &prowapi.Duration{Duration: 20 * time.Minute},
+			BestEffort:        pointer.Bool(true),
+			OptionalOnSuccess: pointer.Bool(true),
+			Cli:               "latest",
+		},
+		{
+			As:          "openshift-gather-extra",
+			Commands:    `curl -skSL https://raw.githubusercontent.com/openshift/release/main/ci-operator/step-registry/gather/extra/gather-extra-commands.sh | /bin/bash -s`,
+			GracePeriod: &prowapi.Duration{Duration: 60 * time.Second},
+			Resources: cioperatorapi.ResourceRequirements{Requests: cioperatorapi.ResourceList{
+				"cpu":    "300m",
+				"memory": "300Mi",
+			}},
+			Timeout:           &prowapi.Duration{Duration: 20 * time.Minute},
+			BestEffort:        pointer.Bool(true),
+			OptionalOnSuccess: pointer.Bool(true),
+			Cli:               "latest",
+		},
+	},
+}
+
+// selectSteps returns the first configured steps, by precedence, or the default ones.
+func selectSteps(steps ...*Steps) Steps {
+	for _, s := range steps {
+		if s != nil {
+			return *s
+		}
+	}
+	return defaultSteps
+}
+
+// presubmit returns the pre and post steps for presubmit tests.
+func (s Steps) presubmit(sourceImageName string) (pre []cioperatorapi.TestStep, post []cioperatorapi.TestStep) {
+	return testSteps(s.Pre, sourceImageName), testSteps(s.Post, sourceImageName)
+}
+
+// periodic returns the pre and post steps for periodic tests.
+func (s Steps) periodic(sourceImageName string) (pre []cioperatorapi.TestStep, post []cioperatorapi.TestStep) {
+	if s.Periodic == nil {
+		pre, post = s.presubmit(sourceImageName)
+		// Periodic jobs gather artifacts on both failure/success.
+		for _, step := range post {
+			if step.LiteralTestStep != nil && strings.Contains(step.LiteralTestStep.As, "gather") {
+				step.OptionalOnSuccess = pointer.Bool(false)
+			}
+		}
+		return pre, post
+	}
+	preSteps, postSteps := s.Pre, s.Post
+	if s.Periodic.Pre != nil {
+		preSteps = s.Periodic.Pre
+	}
+	if s.Periodic.Post != nil {
+		postSteps = s.Periodic.Post
+	}
+	return testSteps(preSteps, sourceImageName), testSteps(postSteps, sourceImageName)
+}
+
+func (s Steps) validate() error {
+	lists := map[string][]Step{"pre": s.Pre, "post": s.Post}
+	if s.Periodic != nil {
+		lists["periodic.pre"] = s.Periodic.Pre
+		lists["periodic.post"] = s.Periodic.Post
+	}
+	for _, name := range sortedKeys(lists) {
+		names := make(map[string]struct{}, len(lists[name]))
+		for i, step := range lists[name] {
+			if err := step.validate(); err != nil {
+				return fmt.Errorf("%s[%d]: %w", name, i, err)
+			}
+			if step.As == "" {
+				continue
+			}
+			if _, ok := names[step.As]; ok {
+				return fmt.Errorf("%s[%d]: duplicate step %q", name, i, step.As)
+			}
+			names[step.As] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func (s Step) validate() error {
+	if s.Ref != "" || s.Chain != "" {
+		if s.Ref != "" && s.Chain != "" {
+			return fmt.Errorf("step must have exactly one of ref or chain")
+		}
+		if s.isLiteral() {
+			return fmt.Errorf("step %s cannot have literal step fields", s.referenceName())
+		}
+		return nil
+	}
+	if s.As == "" || s.Commands == "" {
+		return fmt.Errorf("literal step requires as and commands")
+	}
+	return nil
+}
+
+func (s Step) isLiteral() bool {
+	return s.As != "" || s.From != "" || s.Commands != "" ||
+		len(s.Resources.Requests) > 0 || len(s.Resources.Limits) > 0 ||
+		s.Timeout != nil || s.GracePeriod != nil || s.BestEffort != nil || s.OptionalOnSuccess != nil || s.Cli != ""
+}
+
+func (s Step) referenceName() string {
+	if s.Chain != "" {
+		return fmt.Sprintf("chain %q", s.Chain)
+	}
+	return fmt.Sprintf("ref %q", s.Ref)
+}
+
+func (s Step) testStep(sourceImageName string) cioperatorapi.TestStep {
+	if s.Ref != "" || s.Chain != "" {
+		return s.StepReference.testStep()
+	}
+	from := s.From
+	if from == "" {
+		from = sourceImageName
+	}
+	return cioperatorapi.TestStep{LiteralTestStep: &cioperatorapi.LiteralTestStep{
+		As:                s.As,
+		From:              from,
+		Commands:          s.Commands,
+		Resources:         s.Resources,
+		Timeout:           s.Timeout,
+		GracePeriod:       s.GracePeriod,
+		BestEffort:        s.BestEffort,
+		OptionalOnSuccess: s.OptionalOnSuccess,
+		Cli:               s.Cli,
+	}}
+}
+
+func testSteps(steps []Step, sourceImageName string) []cioperatorapi.TestStep {
+	if len(steps) == 0 {
+		return nil
+	}
+	result := make([]cioperatorapi.TestStep, 0, len(steps))
+	for _, s := range steps {
+		result = append(result, s.testStep(sourceImageName))
+	}
+	return result
+}
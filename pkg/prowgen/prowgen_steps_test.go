@@ -0,0 +1,180 @@
+package prowgen
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+func TestDiscoverTestsSteps(t *testing.T) {
+	ctx := context.Background()
+
+	const sourceImage = "knative-serving-source-image"
+
+	repositorySteps := &Steps{
+		Post: []Step{{StepReference: StepReference{Ref: "gather-extra"}}},
+	}
+	branchSteps := &Steps{
+		Pre: []Step{{StepReference: StepReference{Chain: "ipi-aws-pre"}}},
+		Post: []Step{{
+			As:        "knative-must-gather",
+			Commands:  "oc adm must-gather",
+			Resources: cioperatorapi.ResourceRequirements{Requests: cioperatorapi.ResourceList{"cpu": "100m"}},
+			Timeout:   &prowapi.Duration{Duration: 10 * time.Minute},
+		}},
+		Periodic: &PeriodicSteps{
+			Post: []Step{{StepReference: StepReference{Chain: "gather-network"}}},
+		},
+	}
+	e2eSteps := &Steps{
+		Post: []Step{{As: "dump", From: "tools", Commands: "./dump.sh", OptionalOnSuccess: pointer.Bool(true)}},
+	}
+	deprovision := cioperatorapi.TestStep{Reference: pointer.String(ipiDeprovisionStep)}
+
+	type steps struct {
+		Pre  []cioperatorapi.TestStep
+		Post []cioperatorapi.TestStep
+	}
+
+	tcs := []struct {
+		name        string
+		e2e         E2ETest
+		repository  *Steps
+		branch      *Steps
+		presubmit   steps
+		periodic    steps
+		useDefaults bool
+	}{
+		{
+			name:        "default steps",
+			e2e:         E2ETest{Match: "perf-tests$"},
+			useDefaults: true,
+		},
+		{
+			name:       "repository steps",
+			e2e:        E2ETest{Match: "perf-tests$"},
+			repository: repositorySteps,
+			presubmit:  steps{Post: []cioperatorapi.TestStep{{Reference: pointer.String("gather-extra")}, deprovision}},
+			periodic:   steps{Post: []cioperatorapi.TestStep{{Reference: pointer.String("gather-extra")}, deprovision}},
+		},
+		{
+			name:       "branch steps override repository steps",
+			e2e:        E2ETest{Match: "perf-tests$"},
+			repository: repositorySteps,
+			branch:     branchSteps,
+			presubmit: steps{
+				Pre: []cioperatorapi.TestStep{{Chain: pointer.String("ipi-aws-pre")}},
+				Post: []cioperatorapi.TestStep{
+					{LiteralTestStep: &cioperatorapi.LiteralTestStep{
+						As:        "knative-must-gather",
+						From:      sourceImage,
+						Commands:  "oc adm must-gather",
+						Resources: cioperatorapi.ResourceRequirements{Requests: cioperatorapi.ResourceList{"cpu": "100m"}},
+						Timeout:   &prowapi.Duration{Duration: 10 * time.Minute},
+					}},
+					deprovision,
+				},
+			},
+			periodic: steps{
+				Pre:  []cioperatorapi.TestStep{{Chain: pointer.String("ipi-aws-pre")}},
+				Post: []cioperatorapi.TestStep{{Chain: pointer.String("gather-network")}, deprovision},
+			},
+		},
+		{
+			name:       "test steps override branch steps",
+			e2e:        E2ETest{Match: "perf-tests$", Steps: e2eSteps},
+			repository: repositorySteps,
+			branch:     branchSteps,
+			presubmit: steps{Post: []cioperatorapi.TestStep{
+				{LiteralTestStep: &cioperatorapi.LiteralTestStep{As: "dump", From: "tools", Commands: "./dump.sh", OptionalOnSuccess: pointer.Bool(true)}},
+				deprovision,
+			}},
+			periodic: steps{Post: []cioperatorapi.TestStep{
+				{LiteralTestStep: &cioperatorapi.LiteralTestStep{As: "dump", From: "tools", Commands: "./dump.sh", OptionalOnSuccess: pointer.Bool(true)}},
+				deprovision,
+			}},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := Repository{
+				Org:         "testdata",
+				Repo:        "serving",
+				ImagePrefix: "knative-serving",
+				E2ETests:    []E2ETest{tc.e2e},
+				Steps:       tc.repository,
+			}
+			if tc.useDefaults {
+				tc.presubmit = steps{Post: append(mustGatherSteps(sourceImage, true), deprovision)}
+				tc.periodic = steps{Post: append(mustGatherSteps(sourceImage, false), deprovision)}
+			}
+
+			option := DiscoverTestsForArchitecture(ctx, r, OpenShift{Version: "4.16"}, defaultArchitecture, nil, tc.branch, sourceImage, nil, rand.New(rand.NewSource(seed)))
+			cfg := cioperatorapi.ReleaseBuildConfiguration{}
+			if err := applyOptions(&cfg, option); err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string]steps{}
+			for _, test := range cfg.Tests {
+				got[test.As] = steps{Pre: test.MultiStageTestConfiguration.Pre, Post: test.MultiStageTestConfiguration.Post}
+			}
+			want := map[string]steps{"perf-tests": tc.presubmit, "perf-tests-c": tc.periodic}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Error("steps (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestStepsValidate(t *testing.T) {
+	tcs := []struct {
+		name    string
+		steps   Steps
+		wantErr bool
+	}{
+		{
+			name:  "default",
+			steps: defaultSteps,
+		},
+		{
+			name:  "references",
+			steps: Steps{Pre: []Step{{StepReference: StepReference{Chain: "ipi-aws-pre"}}}, Post: []Step{{StepReference: StepReference{Ref: "gather-extra"}}}},
+		},
+		{
+			name:    "ref and chain",
+			steps:   Steps{Post: []Step{{StepReference: StepReference{Ref: "gather-extra", Chain: "gather"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "reference with literal fields",
+			steps:   Steps{Post: []Step{{StepReference: StepReference{Ref: "gather-extra"}, Commands: "true"}}},
+			wantErr: true,
+		},
+		{
+			name:    "literal without commands",
+			steps:   Steps{Post: []Step{{As: "gather"}}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate periodic steps",
+			steps:   Steps{Periodic: &PeriodicSteps{Post: []Step{{As: "gather", Commands: "true"}, {As: "gather", Commands: "true"}}}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.steps.validate()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("wantErr %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
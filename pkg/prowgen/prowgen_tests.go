@@ -1,6 +1,7 @@
 package prowgen
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
@@ -35,227 +37,204 @@ const (
 	// Name of the owner for the existing cluster pool.
 	// Introduced in https://github.com/openshift/release/pull/49904
 	clusterPoolOwner = "serverless-ci"
-	// Phony targets in Makefile that should be skipped.
-	makefilePhonyTarget = ".PHONY"
 	// Files which do not require to run builds on Prow
 	prowSkipIfOnlyChangedFiles = "^.tekton/.*|^.konflux.*|^.github/.*|^rpms.lock.yaml$|^hack/(lib$|[^l].*|l[^i].*|li[^b].*|lib[^/].*)|^OWNERS.*|.*\\.md"
 	prowNotExisting            = "^non-existing$"
 )
 
-// Makefile targets can be defined in multiple ways:
-// - as a single target: "operator-e2e:"
-// - as a target with dependencies: "test-upstream-e2e-no-upgrade: upstream-e2e"
-// this matches the target in the first capturing group
-var makefileTargetPattern = regexp.MustCompile("^(\\S+):\\s*(.*)$")
+func DiscoverTests(ctx context.Context, r Repository, openShift OpenShift, sourceImageName string, skipE2ETestMatch []string, random *rand.Rand) ReleaseBuildConfigurationOption {
+	return DiscoverTestsForArchitecture(ctx, r, openShift, defaultArchitecture, nil, nil, sourceImageName, skipE2ETestMatch, random)
+}
 
-func DiscoverTests(r Repository, openShift OpenShift, sourceImageName string, skipE2ETestMatch []string, random *rand.Rand) ReleaseBuildConfigurationOption {
+// DiscoverTestsForArchitecture is like DiscoverTests, tests run on clusters with the given architecture
+// provisioned according to the selected cluster profile in clusterProfiles, branchSteps are the
+// branch pre and post steps, if any.
+func DiscoverTestsForArchitecture(ctx context.Context, r Repository, openShift OpenShift, arch Architecture, clusterProfiles map[string]ClusterProfile, branchSteps *Steps, sourceImageName string, skipE2ETestMatch []string, random *rand.Rand) ReleaseBuildConfigurationOption {
 	return func(cfg *cioperatorapi.ReleaseBuildConfiguration) error {
 		combinedSkip := append(append([]string(nil), skipE2ETestMatch...), openShift.SkipE2EMatches...)
-		tests, err := discoverE2ETests(r, combinedSkip, openShift.IncludeE2EMatches)
+		tests, err := discoverE2ETests(ctx, r, combinedSkip, openShift.IncludeE2EMatches)
 		if err != nil {
 			return err
 		}
+		return addTests(cfg, r, openShift, arch, clusterProfiles, branchSteps, sourceImageName, tests, random)
+	}
+}
+
+// WithTests is like DiscoverTestsForArchitecture for the given tests instead of the tests
+// discovered from the Makefile.
+func WithTests(r Repository, openShift OpenShift, arch Architecture, clusterProfiles map[string]ClusterProfile, branchSteps *Steps, sourceImageName string, tests []Test, random *rand.Rand) ReleaseBuildConfigurationOption {
+	return func(cfg *cioperatorapi.ReleaseBuildConfiguration) error {
+		return addTests(cfg, r, openShift, arch, clusterProfiles, branchSteps, sourceImageName, tests, random)
+	}
+}
+
+func addTests(cfg *cioperatorapi.ReleaseBuildConfiguration, r Repository, openShift OpenShift, arch Architecture, clusterProfiles map[string]ClusterProfile, branchSteps *Steps, sourceImageName string, tests []Test, random *rand.Rand) error {
+	for i := range tests {
+		test := &tests[i]
+		as := ToName(r, test)
+
+		var testTimeout *prowapi.Duration
+		var jobTimeout *prowapi.Duration
+
+		// Use 4h test timeout by default
+		testTimeout = &prowapi.Duration{Duration: 4 * time.Hour}
+		if test.Timeout != nil {
+			testTimeout = test.Timeout
+		}
+		jobTimeout = &prowapi.Duration{Duration: testTimeout.Duration + time.Hour} // test time + 3 * 20m must-gathers
+		if test.JobTimeout != nil {
+			jobTimeout = test.JobTimeout
+		}
 
-		for i := range tests {
-			test := &tests[i]
-			as := ToName(r, test)
+		// Per https://issues.redhat.com/browse/DPTP-4603 , we don't set any job-level timeout <8h
+		if jobTimeout != nil && jobTimeout.Duration < 8*time.Hour {
+			jobTimeout = nil
+		}
 
-			var testTimeout *prowapi.Duration
-			var jobTimeout *prowapi.Duration
+		var (
+			clusterClaim   *cioperatorapi.ClusterClaim
+			clusterProfile cioperatorapi.ClusterProfile
+			workflow       *string
+			env            cioperatorapi.TestEnvironment
+		)
 
-			// Use 4h test timeout by default
-			testTimeout = &prowapi.Duration{Duration: 4 * time.Hour}
-			if test.Timeout != nil {
-				testTimeout = test.Timeout
+		profileName, profile, err := selectClusterProfile(clusterProfiles, test.ClusterProfile, arch.ClusterProfile, openShift.ClusterProfile)
+		if err != nil {
+			return fmt.Errorf("[%s] test %q: %w", r.RepositoryDirectory(), as, err)
+		}
+
+		// Make sure to use the existing cluster pool if available for the given OpenShift version.
+		useClusterPool := arch.useClusterPool(openShift)
+		if useClusterPool {
+			if profile.Cloud == "" || profile.ClaimOwner == "" {
+				return fmt.Errorf("[%s] test %q: cluster profile %q has no cluster pool, cloud and claimOwner are required", r.RepositoryDirectory(), as, profileName)
 			}
-			jobTimeout = &prowapi.Duration{Duration: testTimeout.Duration + time.Hour} // test time + 3 * 20m must-gathers
-			if test.JobTimeout != nil {
-				jobTimeout = test.JobTimeout
+			// ClusterClaim references the existing cluster pool.
+			// Mutually exclusive with ClusterProfile.
+			version := openShift.Version
+			if test.OpenShiftVersion != "" {
+				version = test.OpenShiftVersion
 			}
-
-			// Per https://issues.redhat.com/browse/DPTP-4603 , we don't set any job-level timeout <8h
-			if jobTimeout != nil && jobTimeout.Duration < 8*time.Hour {
-				jobTimeout = nil
+			clusterClaim = &cioperatorapi.ClusterClaim{
+				Product:      cioperatorapi.ReleaseProductOCP,
+				Version:      version,
+				Architecture: cioperatorapi.ReleaseArchitecture(arch.Name),
+				Cloud:        cioperatorapi.Cloud(profile.Cloud),
+				Owner:        profile.ClaimOwner,
 			}
-
-			var (
-				clusterClaim   *cioperatorapi.ClusterClaim
-				clusterProfile cioperatorapi.ClusterProfile
-				workflow       *string
-				env            cioperatorapi.TestEnvironment
-			)
-
-			// Make sure to use the existing cluster pool if available for the given OpenShift version.
-			if openShift.UseClusterPool {
-				// ClusterClaim references the existing cluster pool.
-				// Mutually exclusive with ClusterProfile.
-				clusterClaim = &cioperatorapi.ClusterClaim{
-					Product:      cioperatorapi.ReleaseProductOCP,
-					Version:      openShift.Version,
-					Architecture: cioperatorapi.ReleaseArchitectureAMD64,
-					Cloud:        cioperatorapi.CloudAWS,
-					Owner:        clusterPoolOwner,
-					Timeout:      &prowapi.Duration{Duration: 2 * time.Hour},
-				}
-				workflow = pointer.String("generic-claim")
-			} else {
-				// References the existing cluster profile in CI.
-				clusterProfile = serverlessClusterProfile
-				env = map[string]string{
-					"BASE_DOMAIN": devclusterBaseDomain,
-					// Use single zone to save costs. See https://red.ht/3Y8g7Ar
-					"ZONES_COUNT":    "1",
-					"SPOT_INSTANCES": "true",
-				}
-				workflow = pointer.String("ipi-aws")
+			if test.ClaimTimeout != nil {
+				clusterClaim.Timeout = &prowapi.Duration{Duration: test.ClaimTimeout.Duration}
+			} else if profile.ClaimTimeout != nil {
+				clusterClaim.Timeout = &prowapi.Duration{Duration: profile.ClaimTimeout.Duration}
+			}
+			workflow = pointer.String("generic-claim")
+		} else {
+			if test.OpenShiftVersion != "" && test.OpenShiftVersion != openShift.Version {
+				return fmt.Errorf("[%s] test %q: starting OpenShift %s clusters requires a cluster pool", r.RepositoryDirectory(), as, test.OpenShiftVersion)
+			}
+			// References the existing cluster profile in CI.
+			clusterProfile = cioperatorapi.ClusterProfile(profile.ClusterProfile)
+			env = cioperatorapi.TestEnvironment{}
+			for k, v := range profile.Env {
+				env[k] = v
 			}
+			for k, v := range arch.env() {
+				env[k] = v
+			}
+			workflow = pointer.String(profile.Workflow)
+		}
 
-			testCommand := fmt.Sprintf("GOPATH=/tmp/go PATH=$PATH:/tmp/go/bin SKIP_MESH_AUTH_POLICY_GENERATION=true make %s", test.Command)
-			testConfiguration := cioperatorapi.TestStepConfiguration{
-				As:           as,
-				ClusterClaim: clusterClaim,
-				Timeout:      jobTimeout,
-				MultiStageTestConfiguration: &cioperatorapi.MultiStageTestConfiguration{
-					ClusterProfile:           clusterProfile,
-					AllowBestEffortPostSteps: pointer.Bool(true),
-					AllowSkipOnSuccess:       pointer.Bool(true),
-					Environment:              env,
-					Test: []cioperatorapi.TestStep{
-						{
-							LiteralTestStep: &cioperatorapi.LiteralTestStep{
-								As:       "test",
-								From:     sourceImageName,
-								Commands: testCommand,
-								Resources: cioperatorapi.ResourceRequirements{
-									Requests: cioperatorapi.ResourceList{
-										"cpu": "100m",
-									},
-								},
-								Timeout:      testTimeout,
-								Dependencies: dependenciesFromImages(cfg.Images.Items, test.SkipImages),
-								Cli:          "latest",
-							},
-						},
-					},
-					Post: []cioperatorapi.TestStep{
-						{
-							LiteralTestStep: &cioperatorapi.LiteralTestStep{
-								As:       "testlog-gather",
-								From:     sourceImageName,
-								Commands: `cp -v ${SHARED_DIR}/debuglog-*.log ${SHARED_DIR}/stdout-*.log ${SHARED_DIR}/stderr-*.log "${ARTIFACT_DIR}/" || true`,
-								Resources: cioperatorapi.ResourceRequirements{
-									Requests: cioperatorapi.ResourceList{
-										"cpu": "100m",
-									},
-								},
-								Timeout:           &prowapi.Duration{Duration: 1 * time.Minute},
-								BestEffort:        pointer.Bool(true),
-								OptionalOnSuccess: pointer.Bool(true),
-								Cli:               "latest",
-							},
-						},
-						{
-							LiteralTestStep: &cioperatorapi.LiteralTestStep{
-								As:       "knative-must-gather",
-								From:     sourceImageName,
-								Commands: `oc adm must-gather --image=quay.io/openshift-knative/must-gather --dest-dir "${ARTIFACT_DIR}/gather-knative"`,
-								Resources: cioperatorapi.ResourceRequirements{
-									Requests: cioperatorapi.ResourceList{
-										"cpu": "100m",
-									},
-								},
-								Timeout:           &prowapi.Duration{Duration: 20 * time.Minute},
-								BestEffort:        pointer.Bool(true),
-								OptionalOnSuccess: pointer.Bool(true),
-								Cli:               "latest",
-							},
-						},
-						{
-							LiteralTestStep: &cioperatorapi.LiteralTestStep{
-								As:       "openshift-must-gather",
-								From:     sourceImageName,
-								Commands: `oc adm must-gather --dest-dir "${ARTIFACT_DIR}/gather-openshift"`,
-								Resources: cioperatorapi.ResourceRequirements{
-									Requests: cioperatorapi.ResourceList{
-										"cpu": "100m",
-									},
-								},
-								Timeout:           &prowapi.Duration{Duration: 20 * time.Minute},
-								BestEffort:        pointer.Bool(true),
-								OptionalOnSuccess: pointer.Bool(true),
-								Cli:               "latest",
-							},
-						},
-						{
-							LiteralTestStep: &cioperatorapi.LiteralTestStep{
-								As:          "openshift-gather-extra",
-								From:        sourceImageName,
-								Commands:    `curl -skSL https://raw.githubusercontent.com/openshift/release/main/ci-operator/step-registry/gather/extra/gather-extra-commands.sh | /bin/bash -s`,
-								GracePeriod: &prowapi.Duration{Duration: 60 * time.Second},
-								Resources: cioperatorapi.ResourceRequirements{
-									Requests: cioperatorapi.ResourceList{
-										"cpu":    "300m",
-										"memory": "300Mi",
-									},
-								},
-								Timeout:           &prowapi.Duration{Duration: 20 * time.Minute},
-								BestEffort:        pointer.Bool(true),
-								OptionalOnSuccess: pointer.Bool(true),
-								Cli:               "latest",
-							},
+		command, err := testCommand(test, openShift, arch)
+		if err != nil {
+			return fmt.Errorf("[%s] test %q: %w", r.RepositoryDirectory(), as, err)
+		}
+		from := sourceImageName
+		if test.From != "" {
+			from = test.From
+		}
+		resources := cioperatorapi.ResourceRequirements{
+			Requests: cioperatorapi.ResourceList{
+				"cpu": "100m",
+			},
+		}
+		if test.Resources != nil {
+			resources = *test.Resources
+		}
+		testConfiguration := cioperatorapi.TestStepConfiguration{
+			As:           as,
+			ClusterClaim: clusterClaim,
+			Timeout:      jobTimeout,
+			MultiStageTestConfiguration: &cioperatorapi.MultiStageTestConfiguration{
+				ClusterProfile:           clusterProfile,
+				AllowBestEffortPostSteps: pointer.Bool(true),
+				AllowSkipOnSuccess:       pointer.Bool(true),
+				Environment:              env,
+				Test: []cioperatorapi.TestStep{
+					{
+						LiteralTestStep: &cioperatorapi.LiteralTestStep{
+							As:           "test",
+							From:         from,
+							Commands:     command,
+							Resources:    resources,
+							Environment:  testEnvironment(test.Env),
+							Timeout:      testTimeout,
+							Dependencies: dependenciesFromImages(cfg.Images.Items, test.SkipImages),
+							Cli:          "latest",
 						},
 					},
-					Workflow: workflow,
 				},
-			}
+				Workflow: workflow,
+			},
+		}
 
-			if !openShift.UseClusterPool {
-				testConfiguration.MultiStageTestConfiguration.Post =
-					append(testConfiguration.MultiStageTestConfiguration.Post,
-						cioperatorapi.TestStep{
-							Reference: pointer.String("ipi-deprovision-deprovision"),
-						},
-					)
+		var deprovision []cioperatorapi.TestStep
+		if !useClusterPool {
+			step, err := profile.deprovisionStep()
+			if err != nil {
+				return fmt.Errorf("[%s] test %q: cluster profile %q: %w", r.RepositoryDirectory(), as, profileName, err)
 			}
+			deprovision = append(deprovision, step)
+		}
 
-			preSubmitConfiguration := testConfiguration.DeepCopy()
-			preSubmitConfiguration.Optional = test.IgnoreError
-			preSubmitConfiguration.RunIfChanged = test.RunIfChanged
-			cfg.Tests = append(cfg.Tests, *preSubmitConfiguration)
-
-			// This condition allows skipping generation of periodic jobs either
-			// for individual tests or, for all tests running on the given
-			// OpenShift version. Periodic tests are also not generated for candidate
-			// versions.
-			if !test.SkipCron && !openShift.SkipCron && !openShift.CandidateRelease {
-				cronTestConfiguration := testConfiguration.DeepCopy()
-				cronTestConfiguration.As += "-c"
-				if openShift.Cron == "" {
-					cronTemplate := midstreamCronTemplate
-					// Run s-o tests on other days to prevent hitting limits in AWS.
-					if strings.Contains(r.RepositoryDirectory(), "serverless-operator") {
-						cronTemplate = serverlessCronTemplate
-					}
-					// Make sure jobs start between 00:00 and 06:00 UTC by default.
-					r := random.Intn(360)
-					minute, hour := r%60, r/60
-					nightlyCron := fmt.Sprintf(cronTemplate, minute, hour)
-					cronTestConfiguration.Cron = pointer.String(nightlyCron)
-				} else {
-					cronTestConfiguration.Cron = &openShift.Cron
+		steps := selectSteps(test.Steps, branchSteps, r.Steps)
+
+		preSubmitConfiguration := testConfiguration.DeepCopy()
+		pre, post := steps.presubmit(sourceImageName)
+		preSubmitConfiguration.MultiStageTestConfiguration.Pre = pre
+		preSubmitConfiguration.MultiStageTestConfiguration.Post = append(post, deprovision...)
+		preSubmitConfiguration.Optional = test.IgnoreError
+		preSubmitConfiguration.RunIfChanged = test.RunIfChanged
+		cfg.Tests = append(cfg.Tests, *preSubmitConfiguration)
+
+		// This condition allows skipping generation of periodic jobs either
+		// for individual tests or, for all tests running on the given
+		// OpenShift version. Periodic tests are also not generated for candidate
+		// versions.
+		if !test.SkipCron && !openShift.SkipCron && !openShift.CandidateRelease {
+			cronTestConfiguration := testConfiguration.DeepCopy()
+			cronTestConfiguration.As += "-c"
+			if openShift.Cron == "" {
+				cronTemplate := midstreamCronTemplate
+				// Run s-o tests on other days to prevent hitting limits in AWS.
+				if strings.Contains(r.RepositoryDirectory(), "serverless-operator") {
+					cronTemplate = serverlessCronTemplate
 				}
-				// Periodic jobs gather artifacts on both failure/success.
-				for _, postStep := range cronTestConfiguration.MultiStageTestConfiguration.Post {
-					if postStep.LiteralTestStep != nil && strings.Contains(postStep.LiteralTestStep.As, "gather") {
-						postStep.OptionalOnSuccess = pointer.Bool(false)
-					}
-				}
-				cfg.Tests = append(cfg.Tests, *cronTestConfiguration)
+				// Make sure jobs start between 00:00 and 06:00 UTC by default.
+				r := random.Intn(360)
+				minute, hour := r%60, r/60
+				nightlyCron := fmt.Sprintf(cronTemplate, minute, hour)
+				cronTestConfiguration.Cron = pointer.String(nightlyCron)
+			} else {
+				cronTestConfiguration.Cron = &openShift.Cron
 			}
+			pre, post := steps.periodic(sourceImageName)
+			cronTestConfiguration.MultiStageTestConfiguration.Pre = pre
+			cronTestConfiguration.MultiStageTestConfiguration.Post = append(post, deprovision...)
+			cfg.Tests = append(cfg.Tests, *cronTestConfiguration)
 		}
-
-		return nil
 	}
+
+	return nil
 }
 
 func DependenciesForTestSteps() ReleaseBuildConfigurationOption {
@@ -309,47 +288,107 @@ type Test struct {
 	SkipImages   []string
 	Timeout      *prowapi.Duration
 	JobTimeout   *prowapi.Duration
+	// ClusterProfile is the name of the selected cluster profile, if any.
+	ClusterProfile string
+	// Steps are the test pre and post steps, if any.
+	Steps *Steps
+	// Source is where the make target is defined, for example, Makefile:12.
+	Source string
+
+	Env             map[string]string
+	CommandTemplate string
+	Resources       *cioperatorapi.ResourceRequirements
+	From            string
+	ClaimTimeout    *prowapi.Duration
+	// Name is the test name, it defaults to the make target.
+	Name string
+	// SlackReporter overrides the repository and branch Slack notifications, if any.
+	SlackReporter *SlackReporter
+	// OpenShiftVersion is the version of the claimed cluster, it defaults to the OpenShift
+	// version of the configuration.
+	OpenShiftVersion string
+}
+
+// TestCommandData are the fields available in E2ETest.CommandTemplate.
+type TestCommandData struct {
+	// Target is the make target.
+	Target string
+	// OpenShiftVersion is the OpenShift version of the cluster, for example, 4.16.
+	OpenShiftVersion string
+	// Architecture is the architecture of the cluster, for example, amd64.
+	Architecture string
+}
+
+const defaultTestCommandTemplate = "GOPATH=/tmp/go PATH=$PATH:/tmp/go/bin SKIP_MESH_AUTH_POLICY_GENERATION=true make {{.Target}}"
+
+// testCommand returns the command of the test step.
+func testCommand(test *Test, openShift OpenShift, arch Architecture) (string, error) {
+	commandTemplate := defaultTestCommandTemplate
+	if test.CommandTemplate != "" {
+		commandTemplate = test.CommandTemplate
+	}
+	tmpl, err := template.New(test.Command).Option("missingkey=error").Parse(commandTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse command template: %w", err)
+	}
+	sb := strings.Builder{}
+	data := TestCommandData{Target: test.Command, OpenShiftVersion: openShift.Version, Architecture: arch.Name}
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to execute command template: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// testEnvironment returns the test step parameters setting the additional environment variables.
+func testEnvironment(env map[string]string) []cioperatorapi.StepParameter {
+	if len(env) == 0 {
+		return nil
+	}
+	params := make([]cioperatorapi.StepParameter, 0, len(env))
+	for _, name := range sortedKeys(env) {
+		params = append(params, cioperatorapi.StepParameter{Name: name, Default: pointer.String(env[name])})
+	}
+	return params
+}
+
+// name returns the test name, see Test.Name.
+func (t *Test) name() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Command
 }
 
 func (t *Test) HexSha() string {
 	h := sha1.New()
-	h.Write([]byte(t.Command))
+	h.Write([]byte(t.name()))
 	return hex.EncodeToString(h.Sum(nil))[:shaLength]
 }
 
-func discoverE2ETests(r Repository, skipE2ETestMatch []string, includeE2ETestMatch []string) ([]Test, error) {
-	makefilePath := filepath.Join(r.RepositoryDirectory(), "Makefile")
+func discoverE2ETests(ctx context.Context, r Repository, skipE2ETestMatch []string, includeE2ETestMatch []string) ([]Test, error) {
+	makefilePath := filepath.Join(r.LocalDirectory(ctx), "Makefile")
 	if _, err := os.Stat(makefilePath); err != nil && os.IsNotExist(err) {
 		return nil, nil
 	}
 
-	mc, err := os.ReadFile(makefilePath)
+	makefileTargets, err := MakefileTargets(r.LocalDirectory(ctx), "Makefile")
 	if err != nil {
-		return nil, fmt.Errorf("[%s] failed to read file %s: %w", r.RepositoryDirectory(), "Makefile", err)
+		return nil, fmt.Errorf("[%s] failed to discover Makefile targets: %w", r.RepositoryDirectory(), err)
 	}
 
-	mcStr := string(mc)
-	lines := strings.Split(mcStr, "\n")
-	targets := make([]Test, 0, len(lines)/2)
+	targets := make([]Test, 0, len(makefileTargets))
 	commands := sets.NewString()
 
-	for _, l := range lines {
-		if makefileTargetPattern.MatchString(l) {
-			target := makefileTargetPattern.FindStringSubmatch(l)[1]
-			// Skip phony targets
-			if strings.HasPrefix(target, makefilePhonyTarget) {
+	for _, target := range makefileTargets {
+		for _, e2e := range r.E2ETests {
+			if slices.Contains(skipE2ETestMatch, e2e.Match) {
 				continue
 			}
-			for _, e2e := range r.E2ETests {
-				if slices.Contains(skipE2ETestMatch, e2e.Match) {
-					continue
-				}
-				if len(includeE2ETestMatch) > 0 && !slices.Contains(includeE2ETestMatch, e2e.Match) {
-					continue
-				}
-				if err := createTest(r, target, e2e, &targets, commands); err != nil {
-					return nil, err
-				}
+			if len(includeE2ETestMatch) > 0 && !slices.Contains(includeE2ETestMatch, e2e.Match) {
+				continue
+			}
+			if err := createTest(r, target, e2e, &targets, commands); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -361,16 +400,17 @@ func discoverE2ETests(r Repository, skipE2ETestMatch []string, includeE2ETestMat
 	return targets, nil
 }
 
-func createTest(r Repository, target string, e2e E2ETest, tests *[]Test, commands sets.String) error {
-	log.Println(r.RepositoryDirectory(), "Comparing", target, "to match", e2e.Match)
+func createTest(r Repository, target MakefileTarget, e2e E2ETest, tests *[]Test, commands sets.String) error {
+	log.Println(r.RepositoryDirectory(), "Comparing", target.Name, "to match", e2e.Match)
 
-	matches, err := regexp.Match(e2e.Match, []byte(target))
+	matches, err := regexp.Match(e2e.Match, []byte(target.Name))
 	if err != nil {
 		return fmt.Errorf("[%s] failed to match test %s: %w", r.RepositoryDirectory(), e2e.Match, err)
 	}
-	if matches && !commands.Has(target) {
-		*tests = append(*tests, Test{Command: target, OnDemand: e2e.OnDemand, IgnoreError: e2e.IgnoreError, RunIfChanged: e2e.RunIfChanged, SkipCron: e2e.SkipCron, SkipImages: e2e.SkipImages, Timeout: e2e.Timeout, JobTimeout: e2e.JobTimeout})
-		commands.Insert(target)
+	if matches && !commands.Has(target.Name) {
+		log.Println(r.RepositoryDirectory(), "Generating test for target", target.Name, "defined at", target.Source(), "matching", e2e.Match)
+		*tests = append(*tests, Test{Command: target.Name, Source: target.Source(), OnDemand: e2e.OnDemand, IgnoreError: e2e.IgnoreError, RunIfChanged: e2e.RunIfChanged, SkipCron: e2e.SkipCron, SkipImages: e2e.SkipImages, Timeout: e2e.Timeout, JobTimeout: e2e.JobTimeout, ClusterProfile: e2e.ClusterProfile, Steps: e2e.Steps, Env: e2e.Env, CommandTemplate: e2e.CommandTemplate, Resources: e2e.Resources, From: e2e.From, ClaimTimeout: e2e.ClaimTimeout, SlackReporter: e2e.SlackReporter})
+		commands.Insert(target.Name)
 	}
 	return nil
 }
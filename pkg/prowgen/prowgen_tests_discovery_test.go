@@ -1,6 +1,7 @@
 package prowgen
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -14,6 +15,8 @@ import (
 )
 
 func TestDiscoverTestsServing(t *testing.T) {
+	ctx := context.Background()
+
 	r := Repository{
 		Org:                   "testdata",
 		Repo:                  "serving",
@@ -66,8 +69,8 @@ func TestDiscoverTestsServing(t *testing.T) {
 	random := rand.New(rand.NewSource(1))
 	servingSourceImage := "knative-serving-source-image"
 	options := []ReleaseBuildConfigurationOption{
-		DiscoverImages(r, []string{"skip-images/.*"}),
-		DiscoverTests(r, OpenShift{Version: "4.12", Cron: *cron}, servingSourceImage, []string{"skip-e2e$"}, random),
+		DiscoverImages(ctx, r, []string{"skip-images/.*"}),
+		DiscoverTests(ctx, r, OpenShift{Version: "4.12", Cron: *cron}, servingSourceImage, []string{"skip-e2e$"}, random),
 	}
 
 	dependencies := []cioperatorapi.StepDependency{
@@ -287,6 +290,8 @@ func TestDiscoverTestsServing(t *testing.T) {
 // TestDiscoverTestsServingClusterClaim verifies that clusters with version equal to
 // clusterPoolVersion const will use the existing cluster pool.
 func TestDiscoverTestsServingClusterClaim(t *testing.T) {
+	ctx := context.Background()
+
 	r := Repository{
 		Org:                   "testdata",
 		Repo:                  "serving",
@@ -313,8 +318,8 @@ func TestDiscoverTestsServingClusterClaim(t *testing.T) {
 	random := rand.New(rand.NewSource(1))
 	servingSourceImage := "knative-serving-source-image"
 	options := []ReleaseBuildConfigurationOption{
-		DiscoverImages(r, []string{"skip-images/.*"}),
-		DiscoverTests(r, OpenShift{Version: "4.16", UseClusterPool: true, Cron: *cron}, servingSourceImage, []string{"skip-e2e$"}, random),
+		DiscoverImages(ctx, r, []string{"skip-images/.*"}),
+		DiscoverTests(ctx, r, OpenShift{Version: "4.16", UseClusterPool: true, Cron: *cron}, servingSourceImage, []string{"skip-e2e$"}, random),
 	}
 
 	perfDependencies := []cioperatorapi.StepDependency{
@@ -389,6 +394,7 @@ func TestDiscoverTestsServingClusterClaim(t *testing.T) {
 }
 
 func TestDiscoverTestsEventing(t *testing.T) {
+	ctx := context.Background()
 
 	r := Repository{
 		Org:                   "testdata",
@@ -418,8 +424,8 @@ func TestDiscoverTestsEventing(t *testing.T) {
 
 	eventingSourceImage := "knative-eventing-source-image"
 	options := []ReleaseBuildConfigurationOption{
-		DiscoverImages(r, nil),
-		DiscoverTests(r, OpenShift{Version: "4.12"}, eventingSourceImage, nil, random),
+		DiscoverImages(ctx, r, nil),
+		DiscoverTests(ctx, r, OpenShift{Version: "4.12"}, eventingSourceImage, nil, random),
 	}
 
 	dependencies := []cioperatorapi.StepDependency{
@@ -749,3 +755,78 @@ func mustGatherSteps(sourceImage string, optionalOnSuccess bool) []cioperatorapi
 func formatCommand(cmd string) string {
 	return fmt.Sprintf("GOPATH=/tmp/go PATH=$PATH:/tmp/go/bin SKIP_MESH_AUTH_POLICY_GENERATION=true %s", cmd)
 }
+
+func TestDiscoverTestsCustomization(t *testing.T) {
+	ctx := context.Background()
+
+	r := Repository{
+		Org:         "testdata",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests: []E2ETest{
+			{
+				Match:           "perf-tests$",
+				SkipCron:        true,
+				Env:             map[string]string{"SYSTEM_NAMESPACE": "knative-serving", "E2E_FLAGS": "-v"},
+				CommandTemplate: "make {{.Target}} OCP_VERSION={{.OpenShiftVersion}} ARCH={{.Architecture}}",
+				Resources: &cioperatorapi.ResourceRequirements{
+					Requests: cioperatorapi.ResourceList{"cpu": "2", "memory": "4Gi"},
+					Limits:   cioperatorapi.ResourceList{"memory": "8Gi"},
+				},
+				From:         "knative-serving-test-runner",
+				ClaimTimeout: &prowapi.Duration{Duration: 3 * time.Hour},
+			},
+		},
+	}
+
+	option := DiscoverTests(ctx, r, OpenShift{Version: "4.16", UseClusterPool: true}, "knative-serving-source-image", nil, rand.New(rand.NewSource(seed)))
+	cfg := cioperatorapi.ReleaseBuildConfiguration{}
+	if err := applyOptions(&cfg, option); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Tests) != 1 {
+		t.Fatalf("want 1 test, got %d", len(cfg.Tests))
+	}
+	test := cfg.Tests[0]
+
+	if diff := cmp.Diff(&prowapi.Duration{Duration: 3 * time.Hour}, test.ClusterClaim.Timeout); diff != "" {
+		t.Error("claim timeout (-want, +got):", diff)
+	}
+
+	step := test.MultiStageTestConfiguration.Test[0].LiteralTestStep
+	want := &cioperatorapi.LiteralTestStep{
+		As:       "test",
+		From:     "knative-serving-test-runner",
+		Commands: "make perf-tests OCP_VERSION=4.16 ARCH=amd64",
+		Resources: cioperatorapi.ResourceRequirements{
+			Requests: cioperatorapi.ResourceList{"cpu": "2", "memory": "4Gi"},
+			Limits:   cioperatorapi.ResourceList{"memory": "8Gi"},
+		},
+		Environment: []cioperatorapi.StepParameter{
+			{Name: "E2E_FLAGS", Default: pointer.String("-v")},
+			{Name: "SYSTEM_NAMESPACE", Default: pointer.String("knative-serving")},
+		},
+		Timeout: &prowapi.Duration{Duration: 4 * time.Hour},
+		Cli:     "latest",
+	}
+	if diff := cmp.Diff(want, step); diff != "" {
+		t.Error("test step (-want, +got):", diff)
+	}
+}
+
+func TestDiscoverTestsInvalidCommandTemplate(t *testing.T) {
+	ctx := context.Background()
+
+	r := Repository{
+		Org:         "testdata",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests:    []E2ETest{{Match: "perf-tests$", CommandTemplate: "make {{.Unknown}}"}},
+	}
+
+	option := DiscoverTests(ctx, r, OpenShift{Version: "4.16"}, "knative-serving-source-image", nil, rand.New(rand.NewSource(seed)))
+	cfg := cioperatorapi.ReleaseBuildConfiguration{}
+	if err := applyOptions(&cfg, option); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,245 @@
+package prowgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+
+	"github.com/openshift-knative/hack/pkg/project"
+	"github.com/openshift-knative/hack/pkg/soversion"
+)
+
+const defaultUpgradeProjectFile = "olm-catalog/serverless-operator/project.yaml"
+
+// UpgradeMatrix generates Serverless Operator upgrade tests running the same make target for each
+// (from Serverless Operator version, to Serverless Operator version, OpenShift version) tuple.
+//
+// The branch Serverless Operator version and the supported OpenShift versions are read from the
+// project file, the Serverless Operator version of upstream release branches without version in
+// the project file is the corresponding Serverless Operator version. Upgrade tests are generated
+// only for the default variant of OpenShift versions supported by the branch.
+//
+// The test step gets the upgrade tuple in the UPGRADE_FROM_SO_VERSION, UPGRADE_FROM_SO_CHANNEL,
+// UPGRADE_TO_SO_VERSION, UPGRADE_TO_KNATIVE_VERSION, UPGRADE_FROM_OCP_VERSION and
+// UPGRADE_TO_OCP_VERSION environment variables.
+type UpgradeMatrix struct {
+	// Target is the make target running the upgrade test, for example, test-upgrade.
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+	// ProjectFile is the project metadata file (see project.Metadata) relative to the repository
+	// root, it defaults to olm-catalog/serverless-operator/project.yaml.
+	ProjectFile string `json:"projectFile,omitempty" yaml:"projectFile,omitempty"`
+	// PreviousMinors is the number of previous Serverless Operator minor versions upgraded from,
+	// the latest z-stream of each minor version is installed from the <channel> channel. It
+	// defaults to 1.
+	PreviousMinors *int `json:"previousMinors,omitempty" yaml:"previousMinors,omitempty"`
+	// ZStream also upgrades from the released version the branch version replaces (olm.replaces
+	// in the project file), if any, for example, from 1.35.2 to 1.36.0 or, when 1.36.2 is
+	// skipped, from 1.36.1 to 1.36.3.
+	ZStream bool `json:"zStream,omitempty" yaml:"zStream,omitempty"`
+	// OpenShift also upgrades clusters from the previous supported OpenShift minor version with
+	// the branch Serverless Operator version installed, it requires cluster pools.
+	OpenShift bool `json:"openShift,omitempty" yaml:"openShift,omitempty"`
+	// Channel is the template of the channel previous minor versions are installed from,
+	// ${version} is replaced with the minor version, for example, 1.35. It defaults to
+	// stable-${version}.
+	Channel string `json:"channel,omitempty" yaml:"channel,omitempty"`
+
+	SkipCron       bool              `json:"skipCron,omitempty" yaml:"skipCron,omitempty"`
+	IgnoreError    bool              `json:"ignoreError,omitempty" yaml:"ignoreError,omitempty"`
+	Timeout        *prowapi.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	JobTimeout     *prowapi.Duration `json:"jobTimeout,omitempty" yaml:"jobTimeout,omitempty"`
+	ClusterProfile string            `json:"clusterProfile,omitempty" yaml:"clusterProfile,omitempty"`
+	// Env are additional environment variables for the test step.
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+}
+
+// upgrade is an upgrade test tuple.
+type upgrade struct {
+	// name is the test name suffix, stable across generations.
+	name           string
+	fromSOVersion  string
+	fromSOChannel  string
+	fromOCPVersion string
+}
+
+// upgradeMatrix is the UpgradeMatrix of a branch resolved against the project file.
+type upgradeMatrix struct {
+	UpgradeMatrix
+
+	soVersion *semver.Version
+	// replaces is the released version soVersion replaces, nil when the project file doesn't
+	// have it.
+	replaces *semver.Version
+	// ocpVersions are the supported OpenShift versions, sorted, empty when the project file
+	// doesn't restrict them.
+	ocpVersions []string
+}
+
+func (m UpgradeMatrix) validate() error {
+	if m.Target == "" {
+		return errors.New("target is required")
+	}
+	if m.PreviousMinors != nil && *m.PreviousMinors < 0 {
+		return fmt.Errorf("previousMinors must not be negative, got %d", *m.PreviousMinors)
+	}
+	for _, match := range promotionTemplateVariableRegex.FindAllStringSubmatch(m.Channel, -1) {
+		if match[1] != "version" {
+			return fmt.Errorf("unknown variable %s in channel %q, the supported variable is ${version}", match[0], m.Channel)
+		}
+	}
+	return nil
+}
+
+// loadUpgradeMatrix resolves the upgrade matrix against the project file of the checked out
+// branch.
+func loadUpgradeMatrix(ctx context.Context, r Repository, branchName string, m UpgradeMatrix) (*upgradeMatrix, error) {
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("[%s] branch %s: invalid upgrade matrix: %w", r.RepositoryDirectory(), branchName, err)
+	}
+	projectFile := m.ProjectFile
+	if projectFile == "" {
+		projectFile = defaultUpgradeProjectFile
+	}
+
+	metadata := project.DefaultMetadata()
+	path := filepath.Join(r.LocalDirectory(ctx), projectFile)
+	if _, err := os.Stat(path); err == nil {
+		metadata, err = project.ReadMetadataFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] branch %s: failed to read %s: %w", r.RepositoryDirectory(), branchName, projectFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("[%s] branch %s: %w", r.RepositoryDirectory(), branchName, err)
+	}
+
+	resolved := &upgradeMatrix{UpgradeMatrix: m}
+	switch {
+	case metadata.Project.Version != "":
+		v, err := semver.NewVersion(strings.TrimPrefix(metadata.Project.Version, "v"))
+		if err != nil {
+			return nil, fmt.Errorf("[%s] branch %s: invalid version in %s: %w", r.RepositoryDirectory(), branchName, projectFile, err)
+		}
+		resolved.soVersion = v
+	case r.Repo != "serverless-operator" && upstreamReleaseBranchRegex.MatchString(branchName):
+		resolved.soVersion = soversion.FromUpstreamVersion(branchName)
+	default:
+		return nil, fmt.Errorf("[%s] branch %s: unknown Serverless Operator version, %s has no version", r.RepositoryDirectory(), branchName, projectFile)
+	}
+
+	if metadata.Olm != nil && metadata.Olm.Replaces != "" {
+		v, err := semver.NewVersion(strings.TrimPrefix(metadata.Olm.Replaces, "v"))
+		if err != nil {
+			return nil, fmt.Errorf("[%s] branch %s: invalid olm.replaces in %s: %w", r.RepositoryDirectory(), branchName, projectFile, err)
+		}
+		resolved.replaces = v
+	}
+
+	if metadata.Requirements != nil {
+		resolved.ocpVersions = slices.Clone(metadata.Requirements.OcpVersion.List)
+		slices.SortFunc(resolved.ocpVersions, compareOpenShiftVersions)
+	}
+	return resolved, nil
+}
+
+// upgrades returns the upgrade tuples to the given OpenShift version, none when the branch
+// doesn't support it.
+func (m *upgradeMatrix) upgrades(ocpVersion string) []upgrade {
+	ocpIndex := slices.Index(m.ocpVersions, ocpVersion)
+	if len(m.ocpVersions) > 0 && ocpIndex < 0 {
+		return nil
+	}
+
+	var upgrades []upgrade
+	previousMinors := 1
+	if m.PreviousMinors != nil {
+		previousMinors = *m.PreviousMinors
+	}
+	channel := m.Channel
+	if channel == "" {
+		channel = "stable-${version}"
+	}
+	for i := 1; i <= previousMinors && int64(i) <= m.soVersion.Minor; i++ {
+		from := fmt.Sprintf("%d.%d", m.soVersion.Major, m.soVersion.Minor-int64(i))
+		upgrades = append(upgrades, upgrade{
+			name:           "so" + strings.ReplaceAll(from, ".", ""),
+			fromSOVersion:  from,
+			fromSOChannel:  expandPromotionTemplate(channel, map[string]string{"version": from}),
+			fromOCPVersion: ocpVersion,
+		})
+	}
+	if m.ZStream && m.replaces != nil {
+		from := m.replaces.String()
+		upgrades = append(upgrades, upgrade{
+			name:           "so" + strings.ReplaceAll(from, ".", ""),
+			fromSOVersion:  from,
+			fromSOChannel:  expandPromotionTemplate(channel, map[string]string{"version": fmt.Sprintf("%d.%d", m.replaces.Major, m.replaces.Minor)}),
+			fromOCPVersion: ocpVersion,
+		})
+	}
+	if m.OpenShift && ocpIndex > 0 {
+		from := m.ocpVersions[ocpIndex-1]
+		upgrades = append(upgrades, upgrade{
+			name:           "ocp" + strings.ReplaceAll(from, ".", ""),
+			fromSOVersion:  m.soVersion.String(),
+			fromOCPVersion: from,
+		})
+	}
+	return upgrades
+}
+
+// tests returns the upgrade tests to the given OpenShift version.
+func (m *upgradeMatrix) tests(ocpVersion string) []Test {
+	upstream := soversion.ToUpstreamVersion(fmt.Sprintf("%d.%d", m.soVersion.Major, m.soVersion.Minor))
+
+	upgrades := m.upgrades(ocpVersion)
+	tests := make([]Test, 0, len(upgrades))
+	for _, u := range upgrades {
+		env := make(map[string]string, len(m.Env)+6)
+		for k, v := range m.Env {
+			env[k] = v
+		}
+		env["UPGRADE_FROM_SO_VERSION"] = u.fromSOVersion
+		env["UPGRADE_FROM_SO_CHANNEL"] = u.fromSOChannel
+		env["UPGRADE_TO_SO_VERSION"] = m.soVersion.String()
+		env["UPGRADE_TO_KNATIVE_VERSION"] = fmt.Sprintf("%d.%d", upstream.Major, upstream.Minor)
+		env["UPGRADE_FROM_OCP_VERSION"] = u.fromOCPVersion
+		env["UPGRADE_TO_OCP_VERSION"] = ocpVersion
+		if u.fromSOChannel == "" {
+			delete(env, "UPGRADE_FROM_SO_CHANNEL")
+		}
+
+		tests = append(tests, Test{
+			Command:          m.Target,
+			Name:             m.Target + "-" + u.name,
+			IgnoreError:      m.IgnoreError,
+			SkipCron:         m.SkipCron,
+			Timeout:          m.Timeout,
+			JobTimeout:       m.JobTimeout,
+			ClusterProfile:   m.ClusterProfile,
+			Env:              env,
+			OpenShiftVersion: u.fromOCPVersion,
+		})
+	}
+	return tests
+}
+
+// compareOpenShiftVersions compares OpenShift versions like 4.9 and 4.16 numerically.
+func compareOpenShiftVersions(a, b string) int {
+	var aMajor, aMinor, bMajor, bMinor int
+	_, aErr := fmt.Sscanf(a, "%d.%d", &aMajor, &aMinor)
+	_, bErr := fmt.Sscanf(b, "%d.%d", &bMajor, &bMinor)
+	if aErr != nil || bErr != nil {
+		return strings.Compare(a, b)
+	}
+	if aMajor != bMajor {
+		return aMajor - bMajor
+	}
+	return aMinor - bMinor
+}
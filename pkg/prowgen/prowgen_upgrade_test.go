@@ -0,0 +1,249 @@
+package prowgen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+)
+
+func TestUpgradeMatrixTests(t *testing.T) {
+	m := &upgradeMatrix{
+		UpgradeMatrix: UpgradeMatrix{
+			Target:         "test-upgrade",
+			PreviousMinors: ptr.To(2),
+			ZStream:        true,
+			OpenShift:      true,
+			Env:            map[string]string{"SKIP_DOWNGRADE": "true"},
+		},
+		soVersion:   semver.New("1.36.1"),
+		replaces:    semver.New("1.36.0"),
+		ocpVersions: []string{"4.14", "4.16", "4.17"},
+	}
+
+	type upgradeTest struct {
+		Name             string
+		OpenShiftVersion string
+		Env              map[string]string
+	}
+	got := func(ocpVersion string) []upgradeTest {
+		var tests []upgradeTest
+		for _, test := range m.tests(ocpVersion) {
+			if test.Command != "test-upgrade" {
+				t.Errorf("%s: unexpected command %q", test.Name, test.Command)
+			}
+			tests = append(tests, upgradeTest{Name: test.Name, OpenShiftVersion: test.OpenShiftVersion, Env: test.Env})
+		}
+		return tests
+	}
+
+	want := []upgradeTest{
+		{
+			Name:             "test-upgrade-so135",
+			OpenShiftVersion: "4.16",
+			Env: map[string]string{
+				"SKIP_DOWNGRADE":             "true",
+				"UPGRADE_FROM_SO_VERSION":    "1.35",
+				"UPGRADE_FROM_SO_CHANNEL":    "stable-1.35",
+				"UPGRADE_TO_SO_VERSION":      "1.36.1",
+				"UPGRADE_TO_KNATIVE_VERSION": "1.16",
+				"UPGRADE_FROM_OCP_VERSION":   "4.16",
+				"UPGRADE_TO_OCP_VERSION":     "4.16",
+			},
+		},
+		{
+			Name:             "test-upgrade-so134",
+			OpenShiftVersion: "4.16",
+			Env: map[string]string{
+				"SKIP_DOWNGRADE":             "true",
+				"UPGRADE_FROM_SO_VERSION":    "1.34",
+				"UPGRADE_FROM_SO_CHANNEL":    "stable-1.34",
+				"UPGRADE_TO_SO_VERSION":      "1.36.1",
+				"UPGRADE_TO_KNATIVE_VERSION": "1.16",
+				"UPGRADE_FROM_OCP_VERSION":   "4.16",
+				"UPGRADE_TO_OCP_VERSION":     "4.16",
+			},
+		},
+		{
+			Name:             "test-upgrade-so1360",
+			OpenShiftVersion: "4.16",
+			Env: map[string]string{
+				"SKIP_DOWNGRADE":             "true",
+				"UPGRADE_FROM_SO_VERSION":    "1.36.0",
+				"UPGRADE_FROM_SO_CHANNEL":    "stable-1.36",
+				"UPGRADE_TO_SO_VERSION":      "1.36.1",
+				"UPGRADE_TO_KNATIVE_VERSION": "1.16",
+				"UPGRADE_FROM_OCP_VERSION":   "4.16",
+				"UPGRADE_TO_OCP_VERSION":     "4.16",
+			},
+		},
+		{
+			Name:             "test-upgrade-ocp414",
+			OpenShiftVersion: "4.14",
+			Env: map[string]string{
+				"SKIP_DOWNGRADE":             "true",
+				"UPGRADE_FROM_SO_VERSION":    "1.36.1",
+				"UPGRADE_TO_SO_VERSION":      "1.36.1",
+				"UPGRADE_TO_KNATIVE_VERSION": "1.16",
+				"UPGRADE_FROM_OCP_VERSION":   "4.14",
+				"UPGRADE_TO_OCP_VERSION":     "4.16",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got("4.16")); diff != "" {
+		t.Error("4.16 (-want, +got):", diff)
+	}
+
+	// The oldest supported OpenShift version has no OpenShift upgrade.
+	if diff := cmp.Diff([]string{"test-upgrade-so135", "test-upgrade-so134", "test-upgrade-so1360"}, testNamesOf(m.tests("4.14"))); diff != "" {
+		t.Error("4.14 (-want, +got):", diff)
+	}
+	// Unsupported OpenShift versions have no upgrades.
+	if tests := m.tests("4.15"); len(tests) != 0 {
+		t.Errorf("unexpected upgrade tests for unsupported OpenShift 4.15: %+v", tests)
+	}
+}
+
+func TestUpgradeMatrixZStream(t *testing.T) {
+	tests := []struct {
+		name      string
+		soVersion string
+		replaces  string
+		want      map[string]string
+	}{
+		{
+			// The first version of a minor upgrades from the latest z-stream of the previous
+			// minor.
+			name:      "patch 0",
+			soVersion: "1.36.0",
+			replaces:  "1.35.2",
+			want: map[string]string{
+				"test-upgrade-so1352": "1.35.2 stable-1.35",
+			},
+		},
+		{
+			name:      "skipped patch",
+			soVersion: "1.36.3",
+			replaces:  "1.36.1",
+			want: map[string]string{
+				"test-upgrade-so1361": "1.36.1 stable-1.36",
+			},
+		},
+		{
+			name:      "no replaces",
+			soVersion: "1.36.1",
+			want:      map[string]string{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &upgradeMatrix{
+				UpgradeMatrix: UpgradeMatrix{Target: "test-upgrade", PreviousMinors: ptr.To(0), ZStream: true},
+				soVersion:     semver.New(tc.soVersion),
+			}
+			if tc.replaces != "" {
+				m.replaces = semver.New(tc.replaces)
+			}
+			got := make(map[string]string)
+			for _, test := range m.tests("4.16") {
+				got[test.Name] = test.Env["UPGRADE_FROM_SO_VERSION"] + " " + test.Env["UPGRADE_FROM_SO_CHANNEL"]
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error("upgrades (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestLoadUpgradeMatrixReplaces(t *testing.T) {
+	r := Repository{Org: "testorg", Repo: "serverless-operator"}
+	ctx := withWorkspace(t, LocalSource{Root: t.TempDir()}, t.TempDir())
+
+	path := filepath.Join(r.LocalDirectory(ctx), defaultUpgradeProjectFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	projectFile := "project:\n  version: 1.36.3\nolm:\n  replaces: 1.36.1\n"
+	if err := os.WriteFile(path, []byte(projectFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := loadUpgradeMatrix(ctx, r, "main", UpgradeMatrix{Target: "test-upgrade", ZStream: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("1.36.3", m.soVersion.String()); diff != "" {
+		t.Error("version (-want, +got):", diff)
+	}
+	if m.replaces == nil || m.replaces.String() != "1.36.1" {
+		t.Errorf("want replaces 1.36.1, got %v", m.replaces)
+	}
+}
+
+func testNamesOf(tests []Test) []string {
+	names := make([]string, 0, len(tests))
+	for _, test := range tests {
+		names = append(names, test.Name)
+	}
+	return names
+}
+
+func TestNewGenerateConfigsUpgradeMatrix(t *testing.T) {
+	r := Repository{
+		Org:         "testorg",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests:    []E2ETest{{Match: "test-e2e$"}},
+	}
+
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, r.Org, r.Repo+".git"), "release-v1.15")
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	cc := CommonConfig{
+		Branches: map[string]Branch{
+			"release-v1.15": {
+				OpenShiftVersions: []OpenShift{{
+					Version:        "4.16",
+					UseClusterPool: true,
+					SkipCron:       true,
+					Architectures:  []Architecture{{Name: "arm64"}},
+				}},
+				UpgradeMatrix: &UpgradeMatrix{Target: "test-upgrade"},
+			},
+		},
+	}
+	cfgs, err := NewGenerateConfigs(ctx, r, cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, test := range cfgs[0].Tests {
+		names = append(names, test.As)
+	}
+	if diff := cmp.Diff([]string{"test-e2e", "test-upgrade-so134"}, names); diff != "" {
+		t.Error("tests (-want, +got):", diff)
+	}
+	upgrade := cfgs[0].Tests[1]
+	if diff := cmp.Diff("4.16", upgrade.ClusterClaim.Version); diff != "" {
+		t.Error("cluster claim version (-want, +got):", diff)
+	}
+	env := make(map[string]string)
+	for _, param := range upgrade.MultiStageTestConfiguration.Test[0].Environment {
+		env[param.Name] = *param.Default
+	}
+	if got := env["UPGRADE_TO_SO_VERSION"]; got != "1.35.0" {
+		t.Errorf("want UPGRADE_TO_SO_VERSION=1.35.0, got %q", got)
+	}
+
+	// Upgrade tests run only on the default variant.
+	for _, test := range cfgs[1].Tests {
+		if test.As != "test-e2e" {
+			t.Errorf("%s: unexpected test %q", cfgs[1].Path, test.As)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+package prowgen
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	"gopkg.in/robfig/cron.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is a single configuration error at a given position of a configuration file.
+type ValidationError struct {
+	File string `json:"file,omitempty"`
+	// Line and Column are 1-based, 0 means that the position is unknown.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Path is the field path, for example, repositories[0].e2e[1].match.
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	sb := strings.Builder{}
+	sb.WriteString(e.File)
+	if e.Line > 0 {
+		_, _ = fmt.Fprintf(&sb, ":%d:%d", e.Line, e.Column)
+	}
+	if sb.Len() > 0 {
+		sb.WriteString(": ")
+	}
+	if e.Path != "" {
+		sb.WriteString(e.Path)
+		sb.WriteString(": ")
+	}
+	sb.WriteString(e.Message)
+	return sb.String()
+}
+
+// ValidationErrors is the list of errors returned by ValidateConfig.
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+var openShiftVersionPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+
+// ValidateMain is the entry point of `prowgen validate [-schema] [config file or directory...]`.
+func ValidateMain(args []string) {
+	fset := flag.NewFlagSet("validate", flag.ExitOnError)
+	schema := fset.Bool("schema", false, "Print the JSON Schema of the configuration file and exit")
+	_ = fset.Parse(args)
+
+	if *schema {
+		out, err := JSONSchema()
+		if err != nil {
+			log.Fatalln("Failed to generate JSON Schema", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	paths := fset.Args()
+	if len(paths) == 0 {
+		paths = []string{"config"}
+	}
+
+	failed := false
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".yaml") {
+				return nil
+			}
+			if err := ValidateConfigFile(path); err != nil {
+				failed = true
+				fmt.Fprintln(os.Stderr, err)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatalln("Failed to read", p, err)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// ValidateConfigFile validates the configuration file at the given path, see ValidateConfig.
+func ValidateConfigFile(path string) error {
+	y, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return ValidateConfig(path, y)
+}
+
+// ValidateConfig strictly validates a configuration file:
+//   - unknown fields are rejected,
+//   - regular expressions must compile,
+//   - OpenShift versions must be in the <major>.<minor> form,
+//   - e2e tests match must be unique in a repository,
+//   - cron expressions must be valid,
+//   - image mappings must be valid (see ImageMapping),
+//   - promotions must be valid and each repository must promote to distinct targets,
+//   - branches must resolve (see CommonConfig.Resolve).
+//
+// The returned error, if any, is of type ValidationErrors.
+func ValidateConfig(file string, rawYaml []byte) error {
+	v := &configValidator{
+		file:      file,
+		positions: map[string]*yaml.Node{},
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(rawYaml, doc); err != nil {
+		v.errorf("", "%v", err)
+		return v.errs
+	}
+	if len(doc.Content) > 0 {
+		v.walk(doc.Content[0], reflect.TypeOf(Config{}), "")
+	}
+
+	// Validate the configuration as written, profiles and version sets are validated on their own.
+	cfg, err := unmarshalConfig(rawYaml)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			v.errorf(typeErr.Field, "cannot use %s value as %s", typeErr.Value, typeErr.Type)
+		} else {
+			v.errorf("", "%v", err)
+		}
+		return v.errs
+	}
+	raw, err := unmarshalRawCommonConfig(rawYaml)
+	if err != nil {
+		v.errorf("", "%v", err)
+		return v.errs
+	}
+	v.validate(cfg, raw)
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		return v.errs[i].Line < v.errs[j].Line
+	})
+	return v.errs
+}
+
+type configValidator struct {
+	file string
+	// positions maps field paths to the corresponding YAML node.
+	positions map[string]*yaml.Node
+	// clusterProfiles are the cluster profiles defined in the configuration.
+	clusterProfiles map[string]ClusterProfile
+	errs            ValidationErrors
+}
+
+func (v *configValidator) errorf(path string, format string, args ...interface{}) {
+	v.errorAt(v.positions[path], path, format, args...)
+}
+
+func (v *configValidator) errorAt(node *yaml.Node, path string, format string, args ...interface{}) {
+	err := ValidationError{
+		File:    v.file,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	}
+	if node != nil {
+		err.Line = node.Line
+		err.Column = node.Column
+	}
+	v.errs = append(v.errs, err)
+}
+
+// walk records the position of every field and reports unknown fields by following
+// the JSON decoding rules of the given type.
+func (v *configValidator) walk(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if hasCustomUnmarshaler(t) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := jsonFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := joinFieldPath(path, key.Value)
+			f, ok := fieldByName(fields, key.Value)
+			if !ok {
+				if similar, ok := fieldByFoldedName(fields, key.Value); ok {
+					v.errorAt(key, childPath, "unknown field %q, did you mean %q?", key.Value, similar.name)
+				} else {
+					v.errorAt(key, childPath, "unknown field %q", key.Value)
+				}
+				continue
+			}
+			v.positions[childPath] = value
+			v.walk(value, f.typ, childPath)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := fmt.Sprintf("%s[%s]", path, key.Value)
+			v.positions[childPath] = value
+			v.walk(value, t.Elem(), childPath)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, value := range node.Content {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			v.positions[childPath] = value
+			v.walk(value, t.Elem(), childPath)
+		}
+	}
+}
+
+func (v *configValidator) validate(cfg *Config, raw *rawCommonConfig) {
+	if cfg.Schedule != nil {
+		if err := cfg.Schedule.validate(); err != nil {
+			v.errorf("schedule", "%v", err)
+		}
+	}
+
+	v.clusterProfiles = cfg.Config.ClusterProfiles
+	for _, name := range sortedKeys(cfg.Config.ClusterProfiles) {
+		if err := cfg.Config.ClusterProfiles[name].validate(name); err != nil {
+			v.errorf(fmt.Sprintf("config.clusterProfiles[%s]", name), "%v", err)
+		}
+	}
+
+	for i, r := range cfg.Repositories {
+		path := fmt.Sprintf("repositories[%d]", i)
+
+		matches := make(map[string]int, len(r.E2ETests))
+		for j, e2e := range r.E2ETests {
+			e2ePath := fmt.Sprintf("%s.e2e[%d]", path, j)
+			v.regexp(e2ePath+".match", e2e.Match)
+			if e2e.RunIfChanged != "" {
+				v.regexp(e2ePath+".runIfChanged", e2e.RunIfChanged)
+			}
+			v.clusterProfile(e2ePath+".clusterProfile", e2e.ClusterProfile)
+			v.steps(e2ePath+".steps", e2e.Steps)
+			v.slackReporter(e2ePath+".slackReporter", e2e.SlackReporter)
+			if e2e.CommandTemplate != "" {
+				test := &Test{Command: "target", CommandTemplate: e2e.CommandTemplate}
+				if _, err := testCommand(test, OpenShift{}, defaultArchitecture); err != nil {
+					v.errorf(e2ePath+".commandTemplate", "%v", err)
+				}
+			}
+			if k, ok := matches[e2e.Match]; ok {
+				v.errorf(e2ePath+".match", "duplicate match %q, already defined at %s.e2e[%d]", e2e.Match, path, k)
+			} else {
+				matches[e2e.Match] = j
+			}
+		}
+		v.regexps(path+".dockerfiles.matches", r.Dockerfiles.Matches)
+		v.regexps(path+".dockerfiles.excludes", r.Dockerfiles.Excludes)
+		v.regexps(path+".ignoreConfigs.matches", r.IgnoreConfigs.Matches)
+		v.steps(path+".steps", r.Steps)
+		if r.Prow != nil {
+			if err := r.Prow.validate(); err != nil {
+				v.errorf(path+".prow", "%v", err)
+			}
+		}
+		for j, m := range r.ImageMappings {
+			if _, err := parseImageMapping(m); err != nil {
+				v.errorf(fmt.Sprintf("%s.imageMappings[%d]", path, j), "%v", err)
+			}
+		}
+		v.slackReporter(path+".slackReporter", r.SlackReporter)
+	}
+
+	for _, name := range sortedKeys(cfg.Config.Profiles) {
+		v.branch(fmt.Sprintf("config.profiles[%s]", name), cfg.Config.Profiles[name])
+	}
+	for _, name := range sortedKeys(cfg.Config.OpenShiftVersionSets) {
+		v.openShiftVersions(fmt.Sprintf("config.openShiftVersionSets[%s]", name), cfg.Config.OpenShiftVersionSets[name])
+	}
+	resolved := make(map[string]Branch, len(cfg.Config.Branches))
+	for _, name := range sortedKeys(cfg.Config.Branches) {
+		path := fmt.Sprintf("config.branches[%s]", name)
+		v.branch(path, cfg.Config.Branches[name])
+
+		b, err := raw.resolveBranch(name)
+		if err != nil {
+			v.errorf(path, "%v", err)
+			continue
+		}
+		resolved[name] = b
+	}
+	for i, r := range cfg.Repositories {
+		v.promotionTargets(fmt.Sprintf("repositories[%d]", i), r, resolved)
+	}
+}
+
+// promotionTargets reports OpenShift versions of different branches, or of the same branch,
+// promoting the images of the repository to the same target.
+func (v *configValidator) promotionTargets(path string, r Repository, branches map[string]Branch) {
+	used := make(map[string]string)
+	for _, name := range sortedKeys(branches) {
+		b := branches[name]
+		if b.Prowgen != nil && b.Prowgen.Disabled {
+			continue
+		}
+		targets, err := branchPromotionTargets(r, name, b)
+		if err != nil {
+			// Invalid promotions are reported with the OpenShift versions.
+			continue
+		}
+		for i, target := range targets {
+			if target == nil {
+				continue
+			}
+			key := fmt.Sprintf("%s/%s:*", target.Namespace, target.Name)
+			if target.Name == "" {
+				key = fmt.Sprintf("%s/*:%s", target.Namespace, target.Tag)
+			}
+			where := fmt.Sprintf("branch %s OpenShift %s", name, b.OpenShiftVersions[i].Version)
+			if other, ok := used[key]; ok {
+				v.errorf(path, "promotion target %s of %s is also used by %s", key, where, other)
+				continue
+			}
+			used[key] = where
+		}
+	}
+}
+
+func (v *configValidator) branch(path string, b Branch) {
+	v.regexps(path+".skipDockerFilesMatches", b.SkipDockerFilesMatches)
+	if b.Konflux != nil {
+		v.regexps(path+".konflux.excludes", b.Konflux.Excludes)
+		v.regexps(path+".konflux.excludesImages", b.Konflux.ExcludesImages)
+	}
+	v.openShiftVersions(path+".openShiftVersions", b.OpenShiftVersions)
+	v.steps(path+".steps", b.Steps)
+	v.slackReporter(path+".slackReporter", b.SlackReporter)
+	if b.UpgradeMatrix != nil {
+		if err := b.UpgradeMatrix.validate(); err != nil {
+			v.errorf(path+".upgradeMatrix", "%v", err)
+		}
+		v.clusterProfile(path+".upgradeMatrix.clusterProfile", b.UpgradeMatrix.ClusterProfile)
+	}
+}
+
+func (v *configValidator) openShiftVersions(path string, ovs []OpenShift) {
+	for i, ov := range ovs {
+		ovPath := fmt.Sprintf("%s[%d]", path, i)
+		if ov.VersionSet == "" {
+			v.openShiftVersion(ovPath+".version", ov.Version)
+		}
+		v.cron(ovPath+".cron", ov.Cron)
+		if _, err := ov.architectures(); err != nil {
+			v.errorf(ovPath+".architectures", "%v", err)
+		} else if _, err := ov.configVariants(); err != nil {
+			v.errorf(ovPath+".variants", "%v", err)
+		}
+		v.clusterProfile(ovPath+".clusterProfile", ov.ClusterProfile)
+		for j, a := range ov.Architectures {
+			v.clusterProfile(fmt.Sprintf("%s.architectures[%d].clusterProfile", ovPath, j), a.ClusterProfile)
+		}
+		for j, variant := range ov.Variants {
+			variantPath := fmt.Sprintf("%s.variants[%d]", ovPath, j)
+			v.clusterProfile(variantPath+".clusterProfile", variant.ClusterProfile)
+			v.cron(variantPath+".cron", variant.Cron)
+		}
+		if ov.CustomConfigs != nil {
+			v.regexps(ovPath+".customConfigs.includes", ov.CustomConfigs.Includes)
+			v.regexps(ovPath+".customConfigs.excludes", ov.CustomConfigs.Excludes)
+		}
+		if ov.Promotion != nil {
+			if err := ov.Promotion.validate(); err != nil {
+				v.errorf(ovPath+".promotion", "%v", err)
+			}
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *configValidator) clusterProfile(path string, name string) {
+	if name == "" {
+		return
+	}
+	if _, _, err := selectClusterProfile(v.clusterProfiles, name); err != nil {
+		v.errorf(path, "%v, defined cluster profiles: %v", err, sortedKeys(v.clusterProfiles))
+	}
+}
+
+func (v *configValidator) steps(path string, s *Steps) {
+	if s == nil {
+		return
+	}
+	if err := s.validate(); err != nil {
+		v.errorf(path, "%v", err)
+	}
+}
+
+func (v *configValidator) slackReporter(path string, s *SlackReporter) {
+	if s == nil {
+		return
+	}
+	if err := s.validate(); err != nil {
+		v.errorf(path, "%v", err)
+	}
+}
+
+func (v *configValidator) regexps(path string, exprs []string) {
+	for i, expr := range exprs {
+		v.regexp(fmt.Sprintf("%s[%d]", path, i), expr)
+	}
+}
+
+func (v *configValidator) regexp(path string, expr string) {
+	if _, err := regexp.Compile(expr); err != nil {
+		v.errorf(path, "invalid regular expression %q: %v", expr, err)
+	}
+}
+
+func (v *configValidator) openShiftVersion(path string, version string) {
+	if !openShiftVersionPattern.MatchString(version) {
+		v.errorf(path, "invalid OpenShift version %q, expected <major>.<minor>", version)
+		return
+	}
+	if _, err := semver.NewVersion(version + ".0"); err != nil {
+		v.errorf(path, "invalid OpenShift version %q: %v", version, err)
+	}
+}
+
+func (v *configValidator) cron(path string, expr string) {
+	if expr == "" {
+		return
+	}
+	if _, err := cron.Parse(expr); err != nil {
+		v.errorf(path, "invalid cron expression %q: %v", expr, err)
+	}
+}
+
+type jsonField struct {
+	name string
+	typ  reflect.Type
+}
+
+// jsonFields returns the fields of the struct type t as seen by encoding/json, fields of
+// embedded structs without a name are promoted.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, jsonFields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: f.Type})
+	}
+	return fields
+}
+
+func fieldByName(fields []jsonField, name string) (jsonField, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+func fieldByFoldedName(fields []jsonField, name string) (jsonField, bool) {
+	for _, f := range fields {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+func joinFieldPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// hasCustomUnmarshaler returns true when t defines its own JSON representation.
+func hasCustomUnmarshaler(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
@@ -0,0 +1,299 @@
+package prowgen
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift-knative/hack/config"
+)
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want ValidationErrors
+	}{
+		{
+			name: "valid",
+			yaml: `
+config:
+  branches:
+    release-next:
+      openShiftVersions:
+      - version: "4.14"
+        cron: "0 5 * * 1"
+repositories:
+- org: openshift-knative
+  repo: serving
+  e2e:
+  - match: test-e2e$
+`,
+		},
+		{
+			name: "unknown fields",
+			yaml: `
+config:
+  branches:
+    release-next:
+      skipE2EMatch:
+      - test-e2e$
+repositories:
+- org: openshift-knative
+  Repo: serving
+`,
+			want: ValidationErrors{
+				{File: "test.yaml", Line: 5, Column: 7, Path: "config.branches[release-next].skipE2EMatch", Message: `unknown field "skipE2EMatch"`},
+				{File: "test.yaml", Line: 9, Column: 3, Path: "repositories[0].Repo", Message: `unknown field "Repo", did you mean "repo"?`},
+			},
+		},
+		{
+			name: "semantic errors",
+			yaml: `
+config:
+  branches:
+    release-next:
+      openShiftVersions:
+      - version: "4.x"
+      - version: "4.15"
+        cron: "0 5 * *"
+      konflux:
+        excludes:
+        - "(foo"
+repositories:
+- org: openshift-knative
+  repo: serving
+  e2e:
+  - match: test-e2e$
+  - match: test-e2e$
+  dockerfiles:
+    matches:
+    - "[a-"
+`,
+			want: ValidationErrors{
+				{File: "test.yaml", Line: 6, Column: 18, Path: "config.branches[release-next].openShiftVersions[0].version", Message: `invalid OpenShift version "4.x", expected <major>.<minor>`},
+				{File: "test.yaml", Line: 8, Column: 15, Path: "config.branches[release-next].openShiftVersions[1].cron", Message: `invalid cron expression "0 5 * *": Expected 5 or 6 fields, found 4: 0 5 * *`},
+				{File: "test.yaml", Line: 11, Column: 11, Path: "config.branches[release-next].konflux.excludes[0]", Message: "invalid regular expression \"(foo\": error parsing regexp: missing closing ): `(foo`"},
+				{File: "test.yaml", Line: 17, Column: 12, Path: "repositories[0].e2e[1].match", Message: `duplicate match "test-e2e$", already defined at repositories[0].e2e[0]`},
+				{File: "test.yaml", Line: 20, Column: 7, Path: "repositories[0].dockerfiles.matches[0]", Message: "invalid regular expression \"[a-\": error parsing regexp: missing closing ]: `[a-`"},
+			},
+		},
+		{
+			name: "profiles",
+			yaml: `
+config:
+  openShiftVersionSets:
+    supported:
+    - version: "4"
+  profiles:
+    base:
+      openShiftVersions:
+      - versionSet: supported
+  branches:
+    main:
+      extends: unknown
+`,
+			want: ValidationErrors{
+				{File: "test.yaml", Line: 5, Column: 16, Path: "config.openShiftVersionSets[supported][0].version", Message: `invalid OpenShift version "4", expected <major>.<minor>`},
+				{File: "test.yaml", Line: 12, Column: 7, Path: "config.branches[main]", Message: `failed to resolve branch "main": profile "unknown" not found`},
+			},
+		},
+		{
+			name: "cluster profiles",
+			yaml: `
+config:
+  clusterProfiles:
+    hypershift:
+      workflow: hypershift-hostedcluster-workflow
+  branches:
+    main:
+      openShiftVersions:
+      - version: "4.16"
+        clusterProfile: gcp
+repositories:
+- org: openshift-knative
+  repo: serving
+  e2e:
+  - match: test-e2e$
+    clusterProfile: aws
+`,
+			want: ValidationErrors{
+				{File: "test.yaml", Line: 5, Column: 7, Path: "config.clusterProfiles[hypershift]", Message: `cluster profile "hypershift": deprovision is required for workflow "hypershift-hostedcluster-workflow"`},
+				{File: "test.yaml", Line: 10, Column: 25, Path: "config.branches[main].openShiftVersions[0].clusterProfile", Message: `cluster profile "gcp" not found, defined cluster profiles: [hypershift]`},
+			},
+		},
+		{
+			name: "prow config",
+			yaml: `
+repositories:
+- org: openshift-knative
+  repo: serving
+  prow:
+    mergeMethod: fast-forward
+`,
+			want: ValidationErrors{
+				{File: "test.yaml", Line: 6, Column: 5, Path: "repositories[0].prow", Message: `invalid merge method "fast-forward"`},
+			},
+		},
+		{
+			name: "promotions",
+			yaml: `
+config:
+  branches:
+    release-v1.15:
+      openShiftVersions:
+      - version: "4.15"
+        promotion:
+          name: knative-${version}
+          tag: knative-${version}
+      - version: "4.16"
+        promotion:
+          name: knative-${release}
+    release-v1.16:
+      promotion:
+        template: knative-next
+      openShiftVersions:
+      - version: "4.16"
+    release-v1.17:
+      openShiftVersions:
+      - version: "4.16"
+        promotion:
+          name: knative-next
+repositories:
+- org: openshift-knative
+  repo: serving
+`,
+			want: ValidationErrors{
+				{File: "test.yaml", Line: 8, Column: 11, Path: "config.branches[release-v1.15].openShiftVersions[0].promotion", Message: "exactly one of name or tag is required"},
+				{File: "test.yaml", Line: 12, Column: 11, Path: "config.branches[release-v1.15].openShiftVersions[1].promotion", Message: "unknown variable ${release} in \"knative-${release}\", supported variables are ${version}, ${ocpVersion}, ${branch} and ${soVersion}"},
+				{File: "test.yaml", Line: 24, Column: 3, Path: "repositories[0]", Message: "promotion target openshift/knative-next:* of branch release-v1.17 OpenShift 4.16 is also used by branch release-v1.16 OpenShift 4.16"},
+			},
+		},
+		{
+			name: "upgrade matrix",
+			yaml: `
+config:
+  branches:
+    release-1.36:
+      upgradeMatrix:
+        channel: stable-${branch}
+repositories:
+- org: openshift-knative
+  repo: serverless-operator
+`,
+			want: ValidationErrors{
+				{File: "test.yaml", Line: 6, Column: 9, Path: "config.branches[release-1.36].upgradeMatrix", Message: "target is required"},
+			},
+		},
+		{
+			name: "slack reporter",
+			yaml: `
+config:
+  branches:
+    main:
+      slackReporter:
+        presubmits: true
+        jobStatesToReport:
+        - failed
+repositories:
+- org: openshift-knative
+  repo: eventing
+  slackChannel: '#knative-eventing-ci'
+  slackReporter:
+    routes:
+    - match: kafka(
+      channel: '#knative-kafka-ci'
+  e2e:
+  - match: test-e2e$
+    slackReporter:
+      reportTemplate: '{{.Spec.Job'
+`,
+			want: ValidationErrors{
+				{File: "test.yaml", Line: 6, Column: 9, Path: "config.branches[main].slackReporter", Message: `unknown job state "failed", supported states: [aborted error failure pending success triggered]`},
+				{File: "test.yaml", Line: 14, Column: 5, Path: "repositories[0].slackReporter", Message: "routes[0]: error parsing regexp: missing closing ): `kafka(`"},
+				{File: "test.yaml", Line: 20, Column: 7, Path: "repositories[0].e2e[0].slackReporter", Message: "invalid report template: template: report:1: unclosed action"},
+			},
+		},
+		{
+			name: "image mappings",
+			yaml: `
+repositories:
+- org: openshift-knative
+  repo: serving
+  imageMappings:
+  - from: registry.access.redhat.com/ubi9/nodejs-22:*
+    to: ocp/ubi-nodejs-22:*
+  - from: ubi9-minimal
+    to: ocp/ubi-minimal:9
+  - from: registry.access.redhat.com/ubi9/ubi:9.4
+    to: ocp/ubi
+`,
+			want: ValidationErrors{
+				{File: "test.yaml", Line: 8, Column: 5, Path: "repositories[0].imageMappings[1]", Message: `invalid image mapping from "ubi9-minimal", expected <registry>/<repository>[:<tag>]`},
+				{File: "test.yaml", Line: 10, Column: 5, Path: "repositories[0].imageMappings[2]", Message: `invalid image mapping to "ocp/ubi", expected <namespace>/<name>:<tag>`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig("test.yaml", []byte(tt.yaml))
+			var got ValidationErrors
+			if err != nil && !errors.As(err, &got) {
+				t.Fatalf("unexpected error type %T: %v", err, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error("ValidateConfig() (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestValidateConfigRepositoryConfigs(t *testing.T) {
+	files, err := fs.Glob(config.Configs, "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		t.Run(f, func(t *testing.T) {
+			y, err := fs.ReadFile(config.Configs, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ValidateConfig(f, y); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	out, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := map[string]interface{}{}
+	if err := json.Unmarshal(out, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if got := schema["$ref"]; got != "#/$defs/prowgen.Config" {
+		t.Errorf("unexpected root $ref %v", got)
+	}
+	defs := schema["$defs"].(map[string]interface{})
+	e2e, ok := defs["prowgen.E2ETest"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("missing prowgen.E2ETest definition")
+	}
+	if e2e["additionalProperties"] != false {
+		t.Error("expected additionalProperties to be false")
+	}
+	properties := e2e["properties"].(map[string]interface{})
+	if diff := cmp.Diff(map[string]interface{}{"type": "string"}, properties["match"]); diff != "" {
+		t.Error("match (-want, +got):", diff)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"type": "boolean"}, properties["onDemand"]); diff != "" {
+		t.Error("onDemand (-want, +got):", diff)
+	}
+}
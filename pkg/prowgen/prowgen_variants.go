@@ -0,0 +1,82 @@
+package prowgen
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+var variantNameRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// configVariant is a configuration generated for an OpenShift version, the default one, one
+// for each additional architecture and one for each variant.
+type configVariant struct {
+	// openShift is the OpenShift version with the variant overrides applied.
+	openShift OpenShift
+	arch      Architecture
+	// name is the ci-operator variant, for example, 414, 414-arm64 or 414-fips.
+	name string
+	// primary is true only for the default configuration of the OpenShift version, the only
+	// one participating in promotion and generating custom configs.
+	primary bool
+}
+
+// configVariants returns the default configuration of the OpenShift version followed by the
+// additional architectures and the variants.
+func (ov OpenShift) configVariants() ([]configVariant, error) {
+	archs, err := ov.architectures()
+	if err != nil {
+		return nil, err
+	}
+	cvs := make([]configVariant, 0, len(archs)+len(ov.Variants))
+	for _, arch := range archs {
+		cvs = append(cvs, configVariant{
+			openShift: ov,
+			arch:      arch,
+			name:      arch.variant(ov),
+			primary:   arch.isDefault(),
+		})
+	}
+
+	for _, v := range ov.Variants {
+		if !variantNameRegex.MatchString(v.Name) {
+			return nil, fmt.Errorf("OpenShift %s: invalid variant name %q, expected lowercase alphanumeric words separated by '-'", ov.Version, v.Name)
+		}
+		if _, ok := architectureImages[v.Name]; ok {
+			return nil, fmt.Errorf("OpenShift %s: variant name %q collides with the architecture %q", ov.Version, v.Name, v.Name)
+		}
+		name := openShiftVariant(ov) + "-" + v.Name
+		if slices.ContainsFunc(cvs, func(other configVariant) bool { return other.name == name }) {
+			return nil, fmt.Errorf("OpenShift %s: duplicate variant %q", ov.Version, v.Name)
+		}
+		cvs = append(cvs, configVariant{
+			openShift: v.apply(ov),
+			arch: Architecture{
+				Name:           defaultArchitecture.Name,
+				UseClusterPool: v.UseClusterPool,
+				ClusterProfile: v.ClusterProfile,
+				Env:            v.Env,
+			},
+			name: name,
+		})
+	}
+	return cvs, nil
+}
+
+// apply returns the OpenShift version with the variant overrides.
+func (v Variant) apply(ov OpenShift) OpenShift {
+	ov.Architectures = nil
+	ov.Variants = nil
+	ov.Promotion = nil
+	ov.SkipPromotion = true
+	ov.CustomConfigs = nil
+	ov.SkipE2EMatches = append(slices.Clone(ov.SkipE2EMatches), v.SkipE2EMatches...)
+	if len(v.IncludeE2EMatches) > 0 {
+		ov.IncludeE2EMatches = v.IncludeE2EMatches
+	}
+	if v.Cron != "" {
+		ov.Cron = v.Cron
+	}
+	ov.SkipCron = ov.SkipCron || v.SkipCron
+	return ov
+}
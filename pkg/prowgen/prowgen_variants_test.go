@@ -0,0 +1,174 @@
+package prowgen
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+)
+
+func TestNewGenerateConfigsVariants(t *testing.T) {
+	r := Repository{
+		Org:         "testorg",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests:    []E2ETest{{Match: "test-e2e$"}, {Match: "test-e2e-tls$"}},
+	}
+
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, r.Org, r.Repo+".git"), "release-next")
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	cc := CommonConfig{
+		Branches: map[string]Branch{
+			"release-next": {
+				OpenShiftVersions: []OpenShift{{
+					Version:        "4.17",
+					UseClusterPool: true,
+					SkipCron:       true,
+					Variants: []Variant{
+						{
+							Name:              "fips",
+							UseClusterPool:    ptr.To(false),
+							Env:               map[string]string{"FIPS_ENABLED": "true"},
+							IncludeE2EMatches: []string{"test-e2e$"},
+						},
+						{
+							Name:           "proxy",
+							SkipE2EMatches: []string{"test-e2e-tls$"},
+						},
+					},
+				}},
+			},
+		},
+	}
+	cfgs, err := NewGenerateConfigs(ctx, r, cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, cfg := range cfgs {
+		paths = append(paths, cfg.Path)
+	}
+	if diff := cmp.Diff([]string{
+		filepath.Join("testorg", "serving", "testorg-serving-release-next__417.yaml"),
+		filepath.Join("testorg", "serving", "testorg-serving-release-next__417-fips.yaml"),
+		filepath.Join("testorg", "serving", "testorg-serving-release-next__417-proxy.yaml"),
+	}, paths); diff != "" {
+		t.Fatal("paths (-want, +got):", diff)
+	}
+
+	def, fips, proxy := cfgs[0], cfgs[1], cfgs[2]
+
+	if def.PromotionConfiguration == nil {
+		t.Error("expected promotion for the default variant")
+	}
+	for _, cfg := range []ReleaseBuildConfiguration{fips, proxy} {
+		if cfg.PromotionConfiguration != nil {
+			t.Errorf("%s: unexpected promotion %+v", cfg.Path, cfg.PromotionConfiguration)
+		}
+		if diff := cmp.Diff(len(def.Images.Items), len(cfg.Images.Items)); diff != "" {
+			t.Errorf("%s: images (-want, +got): %s", cfg.Path, diff)
+		}
+	}
+	if diff := cmp.Diff("417-fips", fips.Metadata.Variant); diff != "" {
+		t.Error("variant (-want, +got):", diff)
+	}
+
+	testNames := func(cfg ReleaseBuildConfiguration) []string {
+		var names []string
+		for _, test := range cfg.Tests {
+			if test.MultiStageTestConfiguration != nil {
+				names = append(names, test.As)
+			}
+		}
+		return names
+	}
+	if diff := cmp.Diff([]string{"test-e2e", "test-e2e-tls"}, testNames(def)); diff != "" {
+		t.Error("default tests (-want, +got):", diff)
+	}
+	if diff := cmp.Diff([]string{"test-e2e"}, testNames(fips)); diff != "" {
+		t.Error("fips tests (-want, +got):", diff)
+	}
+	if diff := cmp.Diff([]string{"test-e2e"}, testNames(proxy)); diff != "" {
+		t.Error("proxy tests (-want, +got):", diff)
+	}
+
+	fipsTest := fips.Tests[0]
+	if fipsTest.ClusterClaim != nil {
+		t.Errorf("unexpected cluster claim for fips %+v", fipsTest.ClusterClaim)
+	}
+	if got := fipsTest.MultiStageTestConfiguration.Environment["FIPS_ENABLED"]; got != "true" {
+		t.Errorf("want FIPS_ENABLED=true, got %q", got)
+	}
+	if proxy.Tests[0].ClusterClaim == nil {
+		t.Error("expected cluster claim for proxy")
+	}
+}
+
+func TestOpenShiftVariantsErrors(t *testing.T) {
+	for _, ov := range []OpenShift{
+		{Version: "4.17", Variants: []Variant{{Name: ""}}},
+		{Version: "4.17", Variants: []Variant{{Name: "FIPS"}}},
+		{Version: "4.17", Variants: []Variant{{Name: "arm64"}}},
+		{Version: "4.17", Variants: []Variant{{Name: "fips"}, {Name: "fips"}}},
+	} {
+		if _, err := ov.configVariants(); err == nil {
+			t.Errorf("expected error for %+v", ov.Variants)
+		}
+	}
+}
+
+func TestNewGenerateConfigsVariantsCron(t *testing.T) {
+	r := Repository{
+		Org:         "testorg",
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests:    []E2ETest{{Match: "test-e2e$"}, {Match: "test-e2e-tls$"}},
+	}
+
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, r.Org, r.Repo+".git"), "release-next")
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	crons := func(variants ...Variant) map[string]string {
+		cc := CommonConfig{
+			Branches: map[string]Branch{
+				"release-next": {
+					OpenShiftVersions: []OpenShift{{Version: "4.17", Variants: variants}, {Version: "4.18"}},
+				},
+			},
+		}
+		cfgs, err := NewGenerateConfigs(ctx, r, cc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, cfg := range cfgs {
+			for _, test := range cfg.Tests {
+				if test.Cron != nil {
+					got[cfg.Metadata.Variant+"/"+test.As] = *test.Cron
+				}
+			}
+		}
+		return got
+	}
+
+	want := crons(Variant{Name: "proxy"})
+	if len(want) == 0 {
+		t.Fatal("expected periodic tests")
+	}
+	// Adding a variant, even before the existing ones, only adds jobs, the schedules of the
+	// existing ones don't change.
+	got := crons(Variant{Name: "fips"}, Variant{Name: "proxy"})
+	if len(got) <= len(want) {
+		t.Errorf("expected periodic tests for fips, got %v", got)
+	}
+	for k, cron := range want {
+		if diff := cmp.Diff(cron, got[k]); diff != "" {
+			t.Errorf("%s: cron (-want, +got): %s", k, diff)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package prowgen
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/openshift-knative/hack/pkg/dependabotgen"
+	"github.com/openshift-knative/hack/pkg/ownersfilegen"
+	"github.com/openshift-knative/hack/pkg/verify"
+)
+
+// Verify generates all configurations into a temporary copy of the openshift/release clone and
+// returns the unified diff of the ci-operator configurations, jobs and prow configuration of the
+// generated repositories with the existing ones, without modifying the clone, the diff is empty
+// when they are up-to-date, see generateDeterminized.
+func Verify(ctx context.Context, openShiftRelease Repository, inConfigs []*Config, outputConfig string) (string, error) {
+	dirs, cleanup, err := generateDeterminized(ctx, openShiftRelease, inConfigs, outputConfig)
+	defer cleanup()
+	if err != nil {
+		return "", err
+	}
+	var diff string
+	for _, d := range dirs {
+		dirDiff, err := verify.DirsRelativeTo(openShiftRelease.LocalDirectory(ctx), d.existing, d.generated, func(string) bool { return true })
+		if err != nil {
+			return "", err
+		}
+		diff += dirDiff
+	}
+	return diff, nil
+}
+
+// VerifyOwners returns the unified diff between the generated OWNERS files and the existing
+// ones of the branches updated by GenerateOwners.
+func VerifyOwners(ctx context.Context, configs []*Config) (string, error) {
+	var diff string
+	for _, config := range configs {
+		for _, r := range config.Repositories {
+			branchesInGit, err := Branches(ctx, r, "*")
+			if err != nil {
+				return "", fmt.Errorf("could not get branches for %q: %w", r.Repo, err)
+			}
+			for _, branchName := range ownersBranches(r, config.Config, branchesInGit) {
+				if err := GitCheckout(ctx, r, branchName); err != nil {
+					return "", err
+				}
+				d, err := ownersfilegen.VerifyOwnersFile(r.LocalDirectory(ctx), r.Owners.Reviewers, r.Owners.Approvers)
+				if err != nil {
+					return "", fmt.Errorf("[%s][%s] failed to verify OWNERS file: %w", r.RepositoryDirectory(), branchName, err)
+				}
+				diff += branchDiff(r, branchName, d)
+			}
+		}
+	}
+	return diff, nil
+}
+
+// VerifyDependabot returns the unified diff between the generated dependabot configurations
+// and the existing ones, see GenerateKonflux.
+func VerifyDependabot(ctx context.Context, configs []*Config) (string, error) {
+	var diff string
+	for _, config := range configs {
+		for _, r := range config.Repositories {
+			if r.IsServerlessOperator() {
+				// Dependabot configurations aren't generated for serverless-operator.
+				continue
+			}
+			dependabotConfig := repositoryDependabotConfig(r, config.Config)
+			if dependabotConfig.Updates == nil || len(*dependabotConfig.Updates) == 0 {
+				continue
+			}
+			if err := GitMirror(ctx, r); err != nil {
+				return "", err
+			}
+			if err := GitCheckout(ctx, r, dependabotgen.DefaultTargetBranch); err != nil {
+				return "", err
+			}
+			d, err := dependabotConfig.Verify(r.LocalDirectory(ctx), r.RunCodegenCommand())
+			if err != nil {
+				return "", fmt.Errorf("[%s] failed to verify dependabot configuration: %w", r.RepositoryDirectory(), err)
+			}
+			diff += branchDiff(r, dependabotgen.DefaultTargetBranch, d)
+		}
+	}
+	return diff, nil
+}
+
+// branchDiff prefixes a non-empty diff of a repository branch with the repository and the branch
+// since paths don't include the branch.
+func branchDiff(r Repository, branch string, diff string) string {
+	if diff == "" {
+		return ""
+	}
+	log.Println("Generated files are out of date in", r.RepositoryDirectory(), branch)
+	return fmt.Sprintf("# %s (%s)\n%s", r.RepositoryDirectory(), branch, diff)
+}
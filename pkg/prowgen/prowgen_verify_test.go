@@ -0,0 +1,183 @@
+package prowgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift-knative/hack/pkg/ownersfilegen"
+)
+
+func TestVerify(t *testing.T) {
+	withExecutor(t, releaseGeneratorExecutor{})
+
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, "testorg", "serving.git"), "release-next")
+
+	existing := filepath.Join("ci-operator", "config", "testorg", "serving")
+	release := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(release, existing), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"testorg-serving-release-next__414.yaml", "testorg-serving-release-v1.0__414.yaml"} {
+		if err := os.WriteFile(filepath.Join(release, existing, name), []byte("{}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seedBareRepository(t, release, filepath.Join(sourceRoot, "openshift", "release.git"), "main")
+
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	inConfigs := []*Config{
+		{
+			Repositories: []Repository{
+				{Org: "testorg", Repo: "serving", ImagePrefix: "knative-serving", E2ETests: []E2ETest{{Match: "test-e2e$"}}},
+			},
+			Config: CommonConfig{
+				Branches: map[string]Branch{
+					"release-next": {
+						OpenShiftVersions: []OpenShift{{Version: "4.14", SkipCron: true}},
+					},
+					"release-v1.0": {
+						OpenShiftVersions: []OpenShift{{Version: "4.14"}},
+						Prowgen:           &Prowgen{Disabled: true},
+					},
+				},
+			},
+		},
+	}
+
+	openShiftRelease := Repository{Org: "openshift", Repo: "release"}
+	outputConfig := filepath.Join(openShiftRelease.LocalDirectory(ctx), "ci-operator", "config")
+	diff, err := Verify(ctx, openShiftRelease, inConfigs, outputConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generatedPath := filepath.ToSlash(filepath.Join(existing, "testorg-serving-release-next__414.yaml"))
+	removedPath := filepath.ToSlash(filepath.Join(existing, "testorg-serving-release-v1.0__414.yaml"))
+	for _, line := range []string{
+		"--- a/" + generatedPath,
+		"+++ b/" + generatedPath,
+		"-{}",
+		"+# determinized",
+		"+- as: test-e2e",
+		"--- /dev/null\n+++ b/core-services/prow/02_config/testorg/serving/_prowconfig.yaml",
+		"--- a/" + removedPath + "\n+++ /dev/null",
+		"--- /dev/null\n+++ b/ci-operator/jobs/testorg/serving/testorg-serving-release-next__414-presubmits.yaml",
+	} {
+		if !strings.Contains(diff, line+"\n") {
+			t.Errorf("expected diff to contain %q, got:\n%s", line, diff)
+		}
+	}
+
+	// Nothing is written to the openshift/release clone.
+	content, err := os.ReadFile(filepath.Join(outputConfig, "testorg", "serving", "testorg-serving-release-next__414.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "{}\n" {
+		t.Errorf("expected existing configuration to be unchanged, got:\n%s", content)
+	}
+}
+
+func TestVerifyAfterGenerate(t *testing.T) {
+	withExecutor(t, releaseGeneratorExecutor{})
+
+	sourceRoot := t.TempDir()
+	seedBareRepository(t, filepath.Join("testdata", "serving"), filepath.Join(sourceRoot, "testorg", "serving.git"), "release-next")
+
+	release := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(release, "ci-operator", "config"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(release, "Makefile"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	seedBareRepository(t, release, filepath.Join(sourceRoot, "openshift", "release.git"), "main")
+
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	inConfigs := []*Config{
+		{
+			Repositories: []Repository{
+				{Org: "testorg", Repo: "serving", ImagePrefix: "knative-serving", E2ETests: []E2ETest{{Match: "test-e2e$"}}},
+			},
+			Config: CommonConfig{
+				Branches: map[string]Branch{
+					"release-next": {
+						OpenShiftVersions: []OpenShift{{Version: "4.14", SkipCron: true}},
+					},
+				},
+			},
+		},
+	}
+
+	if _, err := NewGenerator(inConfigs, WithBuild(true)).Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	openShiftRelease := Repository{Org: "openshift", Repo: "release"}
+	outputConfig := filepath.Join(openShiftRelease.LocalDirectory(ctx), "ci-operator", "config")
+	diff, err := Verify(ctx, openShiftRelease, inConfigs, outputConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Errorf("expected no diff after generate, got:\n%s", diff)
+	}
+}
+
+func TestVerifyOwners(t *testing.T) {
+	owners := Owners{Reviewers: []string{"reviewer"}, Approvers: []string{"approver"}}
+
+	sourceRoot := t.TempDir()
+	upToDate := t.TempDir()
+	if err := ownersfilegen.WriteOwnersFile(upToDate, owners.Reviewers, owners.Approvers); err != nil {
+		t.Fatal(err)
+	}
+	seedBareRepository(t, upToDate, filepath.Join(sourceRoot, "testorg", "uptodate.git"), "main")
+
+	outdated := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outdated, "OWNERS"), []byte("approvers:\n- someone\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	seedBareRepository(t, outdated, filepath.Join(sourceRoot, "testorg", "outdated.git"), "main")
+
+	ctx := withWorkspace(t, LocalSource{Root: sourceRoot}, t.TempDir())
+
+	inConfigs := []*Config{
+		{
+			Repositories: []Repository{
+				{Org: "testorg", Repo: "uptodate", Owners: owners},
+				{Org: "testorg", Repo: "outdated", Owners: owners},
+			},
+			Config: CommonConfig{
+				Branches: map[string]Branch{
+					"release-next": {},
+					"release-v1.0": {},
+				},
+			},
+		},
+	}
+
+	diff, err := VerifyOwners(ctx, inConfigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(diff, "uptodate") {
+		t.Errorf("expected no diff for the up-to-date repository, got:\n%s", diff)
+	}
+	for _, line := range []string{
+		"# testorg/outdated (main)",
+		"-- someone",
+		"+- approver",
+		"+- reviewer",
+	} {
+		if !strings.Contains(diff, line+"\n") {
+			t.Errorf("expected diff to contain %q, got:\n%s", line, diff)
+		}
+	}
+}
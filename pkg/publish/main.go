@@ -0,0 +1,63 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+)
+
+// Main is the entry point of `prowgen publish`, it pushes a local branch of the repository cloned
+// in the workspace to the fork and creates or updates the pull request, the pull request URL is
+// printed to stdout.
+func Main(args []string) {
+	fset := flag.NewFlagSet("publish", flag.ExitOnError)
+	org := fset.String("org", "openshift-knative", "GH organization name")
+	repo := fset.String("repo", "", "GH repository name")
+	branch := fset.String("branch", "", "Local branch to publish, pushed to the fork with the same name")
+	base := fset.String("base", "main", "Branch the pull request is merged into")
+	title := fset.String("title", "", "Pull request title (default the subject of the last commit)")
+	body := fset.String("body", "", "Pull request body (default the body of the last commit)")
+	forkOwner := fset.String("fork-owner", DefaultForkOwner, "Owner of the fork the branch is pushed to")
+	report := fset.String("report", "", "Write the JSON result to the given file")
+	var labels []string
+	fset.Func("label", "Label added to the pull request, can be repeated", func(label string) error {
+		labels = append(labels, label)
+		return nil
+	})
+	workspace := prowgen.RegisterWorkspaceFlags(fset)
+	_ = fset.Parse(args)
+
+	if *repo == "" || *branch == "" {
+		log.Fatalln("-repo and -branch are required")
+	}
+
+	// Standard output of commands isn't copied, it's reserved to the pull request URL.
+	prowgen.SetExecutor(&prowgen.OSExecutor{Stderr: os.Stderr})
+
+	ctx := prowgen.WithWorkspace(context.Background(), workspace)
+	r := prowgen.Repository{Org: *org, Repo: *repo}
+	p := NewPublisher(WithForkOwner(*forkOwner))
+	result, err := p.PublishBranch(ctx, r, *branch, *base, *title, *body, labels...)
+	if err != nil {
+		log.Fatalln("Failed to publish pull request", err)
+	}
+	if *report != "" {
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			log.Fatalln("Failed to marshal result", err)
+		}
+		if err := os.WriteFile(*report, out, os.ModePerm); err != nil {
+			log.Fatalln("Failed to write result", err)
+		}
+	}
+	if result.URL == "" {
+		log.Printf("No changes between %s and %s, no pull request published", *base, *branch)
+		return
+	}
+	fmt.Println(result.URL)
+}
@@ -0,0 +1,430 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+)
+
+const (
+	// DefaultForkOwner is the owner of the forks pull requests are opened from.
+	DefaultForkOwner = "serverless-qe"
+
+	defaultAPIURL = "https://api.github.com"
+	defaultGitURL = "https://github.com"
+)
+
+// Publisher publishes local branches as pull requests: the branch is pushed to the fork of the
+// repository and a pull request is created, or updated when one is already open for the branch,
+// through the GitHub REST API.
+type Publisher struct {
+	apiURL     string
+	gitURL     string
+	token      string
+	forkOwner  string
+	httpClient *http.Client
+	executor   prowgen.Executor
+
+	mu      sync.Mutex
+	results []Result
+}
+
+// Option configures a Publisher.
+type Option func(p *Publisher)
+
+// WithToken sets the GitHub token, by default the GH_TOKEN or GITHUB_TOKEN environment variable.
+func WithToken(token string) Option {
+	return func(p *Publisher) {
+		p.token = token
+	}
+}
+
+// WithForkOwner sets the owner of the forks branches are pushed to, DefaultForkOwner by default.
+func WithForkOwner(owner string) Option {
+	return func(p *Publisher) {
+		p.forkOwner = owner
+	}
+}
+
+// WithAPIURL sets the GitHub REST API URL, https://api.github.com by default.
+func WithAPIURL(apiURL string) Option {
+	return func(p *Publisher) {
+		p.apiURL = strings.TrimSuffix(apiURL, "/")
+	}
+}
+
+// WithGitURL sets the base URL of the git repositories, https://github.com by default, forks are
+// at <gitURL>/<fork owner>/<repo>.git.
+func WithGitURL(gitURL string) Option {
+	return func(p *Publisher) {
+		p.gitURL = strings.TrimSuffix(gitURL, "/")
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the GitHub REST API.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Publisher) {
+		p.httpClient = c
+	}
+}
+
+// WithExecutor sets the executor of git commands, by default the executor configured with
+// prowgen.SetExecutor.
+func WithExecutor(e prowgen.Executor) Option {
+	return func(p *Publisher) {
+		p.executor = e
+	}
+}
+
+// NewPublisher returns a Publisher for github.com pushing to the DefaultForkOwner forks.
+func NewPublisher(opts ...Option) *Publisher {
+	p := &Publisher{
+		apiURL:     defaultAPIURL,
+		gitURL:     defaultGitURL,
+		forkOwner:  DefaultForkOwner,
+		httpClient: http.DefaultClient,
+	}
+	if token := os.Getenv("GH_TOKEN"); token != "" {
+		p.token = token
+	} else {
+		p.token = os.Getenv("GITHUB_TOKEN")
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// PullRequest is a local branch to publish.
+type PullRequest struct {
+	// Org and Repo are the upstream repository the pull request is opened against.
+	Org  string
+	Repo string
+	// Dir is the local clone of the repository.
+	Dir string
+	// Branch is the local branch, it is rebased onto the local Base branch and pushed to the
+	// fork with the same name.
+	Branch string
+	// Base is the branch the pull request is merged into, it must exist in the local clone.
+	Base string
+	// Title and Body of the pull request, by default the subject and the body of the last
+	// commit of Branch.
+	Title string
+	Body  string
+	// Labels are added to the pull request.
+	Labels []string
+}
+
+// Result is the outcome of publishing a pull request.
+type Result struct {
+	// Repository is <org>/<repo>.
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+	Base       string `json:"base"`
+	// Number and URL are empty when the branch has no changes compared to Base.
+	Number int    `json:"number,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// Created is true when the pull request is new, false when an open pull request is updated.
+	Created bool `json:"created,omitempty"`
+	// Pushed is true when the fork branch is created or updated.
+	Pushed bool `json:"pushed,omitempty"`
+}
+
+// Results returns the pull requests published so far.
+func (p *Publisher) Results() []Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Result(nil), p.results...)
+}
+
+// PublishBranch publishes the local branch of the repository cloned in the workspace as a pull
+// request to base, see Publish.
+func (p *Publisher) PublishBranch(ctx context.Context, r prowgen.Repository, branch string, base string, title string, body string, labels ...string) (*Result, error) {
+	return p.Publish(ctx, PullRequest{
+		Org:    r.Org,
+		Repo:   r.Repo,
+		Dir:    r.LocalDirectory(ctx),
+		Branch: branch,
+		Base:   base,
+		Title:  title,
+		Body:   body,
+		Labels: labels,
+	})
+}
+
+// Publish rebases the branch onto the base branch and pushes it to the fork, unless the fork
+// branch is already up to date, then it creates the pull request or updates the title and body
+// of the open pull request of the branch.
+func (p *Publisher) Publish(ctx context.Context, pr PullRequest) (*Result, error) {
+	repository := pr.Org + "/" + pr.Repo
+	result := &Result{Repository: repository, Branch: pr.Branch, Base: pr.Base}
+
+	if pr.Title == "" {
+		out, err := p.git(ctx, pr.Dir, "log", "-1", "--format=%s", pr.Branch)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] failed to get the commit subject of %s: %w", repository, pr.Branch, err)
+		}
+		pr.Title = strings.TrimSpace(string(out))
+		if pr.Body == "" {
+			out, err = p.git(ctx, pr.Dir, "log", "-1", "--format=%b", pr.Branch)
+			if err != nil {
+				return nil, fmt.Errorf("[%s] failed to get the commit body of %s: %w", repository, pr.Branch, err)
+			}
+			pr.Body = strings.TrimSpace(string(out))
+		}
+	}
+
+	pushed, err := p.push(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	result.Pushed = pushed
+
+	head := p.forkOwner + ":" + pr.Branch
+	existing, err := p.openPullRequest(ctx, pr, head)
+	if err != nil {
+		return nil, err
+	}
+
+	var published *pullRequest
+	switch {
+	case existing != nil && existing.Title == pr.Title && existing.Body == pr.Body:
+		published = existing
+	case existing != nil:
+		path := fmt.Sprintf("/repos/%s/pulls/%d", repository, existing.Number)
+		published = &pullRequest{}
+		if err := p.do(ctx, http.MethodPatch, path, map[string]string{"title": pr.Title, "body": pr.Body}, published); err != nil {
+			return nil, fmt.Errorf("[%s] failed to update pull request #%d: %w", repository, existing.Number, err)
+		}
+	default:
+		published = &pullRequest{}
+		in := map[string]interface{}{
+			"title":                 pr.Title,
+			"body":                  pr.Body,
+			"head":                  head,
+			"base":                  pr.Base,
+			"maintainer_can_modify": true,
+		}
+		if err := p.do(ctx, http.MethodPost, "/repos/"+repository+"/pulls", in, published); err != nil {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.noCommits() {
+				log.Printf("[%s] No changes between %s and %s, skipping pull request", repository, pr.Base, head)
+				p.record(*result)
+				return result, nil
+			}
+			return nil, fmt.Errorf("[%s] failed to create pull request from %s to %s: %w", repository, head, pr.Base, err)
+		}
+		result.Created = true
+	}
+	result.Number = published.Number
+	result.URL = published.HTMLURL
+
+	if len(pr.Labels) > 0 {
+		path := fmt.Sprintf("/repos/%s/issues/%d/labels", repository, published.Number)
+		if err := p.do(ctx, http.MethodPost, path, map[string][]string{"labels": pr.Labels}, nil); err != nil {
+			return nil, fmt.Errorf("[%s] failed to label pull request #%d: %w", repository, published.Number, err)
+		}
+	}
+
+	log.Printf("[%s] Published pull request %s", repository, result.URL)
+	p.record(*result)
+	return result, nil
+}
+
+func (p *Publisher) record(result Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.results = append(p.results, result)
+}
+
+// push rebases the branch onto the base branch, as the base may have moved since the branch was
+// created, and force pushes it to the fork when the fork branch differs, it returns true when the
+// branch is pushed.
+func (p *Publisher) push(ctx context.Context, pr PullRequest) (bool, error) {
+	repository := pr.Org + "/" + pr.Repo
+
+	if _, err := p.git(ctx, pr.Dir, "rebase", "--quiet", pr.Base, pr.Branch); err != nil {
+		if _, abortErr := p.git(ctx, pr.Dir, "rebase", "--abort"); abortErr != nil {
+			log.Printf("[%s] Failed to abort rebase: %v", repository, abortErr)
+		}
+		return false, fmt.Errorf("[%s] failed to rebase %s onto %s: %w", repository, pr.Branch, pr.Base, err)
+	}
+
+	out, err := p.git(ctx, pr.Dir, "rev-parse", pr.Branch)
+	if err != nil {
+		return false, fmt.Errorf("[%s] failed to resolve branch %s: %w", repository, pr.Branch, err)
+	}
+	local := strings.TrimSpace(string(out))
+
+	ref := &gitRef{}
+	path := fmt.Sprintf("/repos/%s/%s/git/ref/heads/%s", p.forkOwner, pr.Repo, url.PathEscape(pr.Branch))
+	if err := p.do(ctx, http.MethodGet, path, nil, ref); err != nil {
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+			return false, fmt.Errorf("[%s] failed to get branch %s of fork %s: %w", repository, pr.Branch, p.forkOwner, err)
+		}
+	}
+	if ref.Object.SHA == local {
+		log.Printf("[%s] Fork branch %s/%s is up to date", repository, p.forkOwner, pr.Branch)
+		return false, nil
+	}
+
+	remote, err := p.remoteURL(pr.Repo)
+	if err != nil {
+		return false, err
+	}
+	if _, err := p.git(ctx, pr.Dir, "push", remote, pr.Branch+":refs/heads/"+pr.Branch, "--force"); err != nil {
+		// The remote URL contains the token, don't include it in the error.
+		if p.token != "" {
+			err = errors.New(strings.ReplaceAll(err.Error(), p.token, "<token>"))
+		}
+		return false, fmt.Errorf("[%s] failed to push branch %s to fork %s: %w", repository, pr.Branch, p.forkOwner, err)
+	}
+	return true, nil
+}
+
+func (p *Publisher) remoteURL(repo string) (string, error) {
+	u, err := url.Parse(p.gitURL + "/" + p.forkOwner + "/" + repo + ".git")
+	if err != nil {
+		return "", fmt.Errorf("invalid git URL %q: %w", p.gitURL, err)
+	}
+	if p.token != "" && (u.Scheme == "https" || u.Scheme == "http") {
+		u.User = url.UserPassword(p.forkOwner, p.token)
+	}
+	return u.String(), nil
+}
+
+// openPullRequest returns the open pull request from head to the base branch, nil when there is
+// none.
+func (p *Publisher) openPullRequest(ctx context.Context, pr PullRequest, head string) (*pullRequest, error) {
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set("head", head)
+	query.Set("base", pr.Base)
+
+	var prs []pullRequest
+	path := fmt.Sprintf("/repos/%s/%s/pulls?%s", pr.Org, pr.Repo, query.Encode())
+	if err := p.do(ctx, http.MethodGet, path, nil, &prs); err != nil {
+		return nil, fmt.Errorf("[%s/%s] failed to list pull requests from %s: %w", pr.Org, pr.Repo, head, err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return &prs[0], nil
+}
+
+type pullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+}
+
+type gitRef struct {
+	Object struct {
+		SHA string `json:"sha"`
+	} `json:"object"`
+}
+
+// APIError is an unsuccessful response of the GitHub REST API.
+type APIError struct {
+	StatusCode int
+	Message    string `json:"message"`
+	Errors     []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("GitHub API returned %d: %s", e.StatusCode, e.Message)
+	for _, err := range e.Errors {
+		if err.Message != "" {
+			msg += ", " + err.Message
+		}
+	}
+	return msg
+}
+
+// noCommits returns true when the pull request can't be created because the head branch has no
+// commits compared to the base branch.
+func (e *APIError) noCommits() bool {
+	if e.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	for _, err := range e.Errors {
+		if strings.HasPrefix(err.Message, "No commits between") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Publisher) do(ctx context.Context, method, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.apiURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(respBody, apiErr)
+		return apiErr
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to unmarshal response of %s %s: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+// git runs git in dir and returns its standard output, errors don't include the arguments since
+// they may contain the token.
+func (p *Publisher) git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	execute := prowgen.Execute
+	if p.executor != nil {
+		execute = p.executor.Execute
+	}
+	out, err := execute(ctx, prowgen.Command{Name: "git", Args: args, Dir: dir})
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,407 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+)
+
+// fakeGitHub is a stand-in for the GitHub REST API, fork branches are read from bare
+// repositories in forksDir.
+type fakeGitHub struct {
+	t        *testing.T
+	forksDir string
+
+	mu       sync.Mutex
+	pulls    []fakePull
+	requests []string
+}
+
+type fakePull struct {
+	Number  int      `json:"number"`
+	HTMLURL string   `json:"html_url"`
+	Title   string   `json:"title"`
+	Body    string   `json:"body"`
+	Head    string   `json:"-"`
+	Base    string   `json:"-"`
+	Labels  []string `json:"-"`
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/repos/"), "/")
+	switch {
+	case r.Method == http.MethodGet && len(parts) >= 6 && parts[2] == "git" && parts[3] == "ref":
+		out, err := exec.Command("git", "-C", filepath.Join(f.forksDir, parts[0], parts[1]+".git"), "rev-parse", "--verify", "-q", strings.Join(parts[5:], "/")).Output()
+		if err != nil {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			return
+		}
+		f.write(w, http.StatusOK, map[string]interface{}{"object": map[string]string{"sha": strings.TrimSpace(string(out))}})
+
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[2] == "pulls":
+		q := r.URL.Query()
+		if q.Get("state") != "open" {
+			f.t.Errorf("unexpected state %q", q.Get("state"))
+		}
+		pulls := []fakePull{}
+		for _, pr := range f.pulls {
+			if pr.Head == q.Get("head") && pr.Base == q.Get("base") {
+				pulls = append(pulls, pr)
+			}
+		}
+		f.write(w, http.StatusOK, pulls)
+
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "pulls":
+		in := map[string]interface{}{}
+		f.read(r, &in)
+		if in["base"] == "no-commits" {
+			f.write(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"message": "Validation Failed",
+				"errors":  []map[string]string{{"message": fmt.Sprintf("No commits between %s and %s", in["base"], in["head"])}},
+			})
+			return
+		}
+		if in["base"] == "already-exists" {
+			f.write(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"message": "Validation Failed",
+				"errors":  []map[string]string{{"message": fmt.Sprintf("A pull request already exists for %s.", in["head"])}},
+			})
+			return
+		}
+		n := len(f.pulls) + 1
+		pr := fakePull{
+			Number:  n,
+			HTMLURL: fmt.Sprintf("https://github.com/%s/%s/pull/%d", parts[0], parts[1], n),
+			Title:   in["title"].(string),
+			Body:    in["body"].(string),
+			Head:    in["head"].(string),
+			Base:    in["base"].(string),
+		}
+		f.pulls = append(f.pulls, pr)
+		f.write(w, http.StatusCreated, pr)
+
+	case r.Method == http.MethodPatch && len(parts) == 4 && parts[2] == "pulls":
+		in := map[string]string{}
+		f.read(r, &in)
+		pr := &f.pulls[f.index(parts[3])]
+		pr.Title, pr.Body = in["title"], in["body"]
+		f.write(w, http.StatusOK, pr)
+
+	case r.Method == http.MethodPost && len(parts) == 5 && parts[2] == "issues" && parts[4] == "labels":
+		in := map[string][]string{}
+		f.read(r, &in)
+		pr := &f.pulls[f.index(parts[3])]
+		pr.Labels = append(pr.Labels, in["labels"]...)
+		f.write(w, http.StatusOK, []interface{}{})
+
+	default:
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+	}
+}
+
+func (f *fakeGitHub) index(number string) int {
+	for i := range f.pulls {
+		if fmt.Sprint(f.pulls[i].Number) == number {
+			return i
+		}
+	}
+	f.t.Fatalf("unknown pull request %s", number)
+	return -1
+}
+
+func (f *fakeGitHub) read(r *http.Request, in interface{}) {
+	if err := json.NewDecoder(r.Body).Decode(in); err != nil {
+		f.t.Error(err)
+	}
+}
+
+func (f *fakeGitHub) write(w http.ResponseWriter, status int, out interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		f.t.Error(err)
+	}
+}
+
+func (f *fakeGitHub) takeRequests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestPublish(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ctx := context.Background()
+
+	forksDir := t.TempDir()
+	fork := filepath.Join(forksDir, "serverless-qe", "serving.git")
+	if err := os.MkdirAll(fork, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	git(t, fork, "init", "-q", "--bare")
+
+	local := t.TempDir()
+	git(t, local, "init", "-q", "-b", "main")
+	// The publisher rebases the branch, committing with the repository identity.
+	git(t, local, "config", "user.name", "test")
+	git(t, local, "config", "user.email", "test@example.com")
+	git(t, local, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	git(t, local, "checkout", "-q", "-b", "sync-konflux-main")
+	git(t, local, "commit", "-q", "--allow-empty", "-m", "[main] Sync Konflux configurations", "-m", "Generated by prowgen")
+
+	gh := &fakeGitHub{t: t, forksDir: forksDir}
+	server := httptest.NewServer(gh)
+	defer server.Close()
+
+	p := NewPublisher(
+		WithToken("token"),
+		WithAPIURL(server.URL),
+		WithGitURL("file://"+forksDir),
+		WithHTTPClient(server.Client()),
+	)
+	pr := PullRequest{
+		Org:    "openshift-knative",
+		Repo:   "serving",
+		Dir:    local,
+		Branch: "sync-konflux-main",
+		Base:   "main",
+		Labels: []string{"do-not-merge/hold"},
+	}
+
+	t.Run("create", func(t *testing.T) {
+		got, err := p.Publish(ctx, pr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &Result{
+			Repository: "openshift-knative/serving",
+			Branch:     "sync-konflux-main",
+			Base:       "main",
+			Number:     1,
+			URL:        "https://github.com/openshift-knative/serving/pull/1",
+			Created:    true,
+			Pushed:     true,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Error("result (-want, +got):", diff)
+		}
+		if diff := cmp.Diff(git(t, local, "rev-parse", "sync-konflux-main"), git(t, fork, "rev-parse", "sync-konflux-main")); diff != "" {
+			t.Error("fork branch (-want, +got):", diff)
+		}
+		wantPull := fakePull{
+			Number:  1,
+			HTMLURL: "https://github.com/openshift-knative/serving/pull/1",
+			Title:   "[main] Sync Konflux configurations",
+			Body:    "Generated by prowgen",
+			Head:    "serverless-qe:sync-konflux-main",
+			Base:    "main",
+			Labels:  []string{"do-not-merge/hold"},
+		}
+		if diff := cmp.Diff([]fakePull{wantPull}, gh.pulls); diff != "" {
+			t.Error("pull requests (-want, +got):", diff)
+		}
+		gh.takeRequests()
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		got, err := p.Publish(ctx, PullRequest{Org: pr.Org, Repo: pr.Repo, Dir: pr.Dir, Branch: pr.Branch, Base: pr.Base})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &Result{
+			Repository: "openshift-knative/serving",
+			Branch:     "sync-konflux-main",
+			Base:       "main",
+			Number:     1,
+			URL:        "https://github.com/openshift-knative/serving/pull/1",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Error("result (-want, +got):", diff)
+		}
+		wantRequests := []string{
+			"GET /repos/serverless-qe/serving/git/ref/heads/sync-konflux-main",
+			"GET /repos/openshift-knative/serving/pulls",
+		}
+		if diff := cmp.Diff(wantRequests, gh.takeRequests()); diff != "" {
+			t.Error("requests (-want, +got):", diff)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		git(t, local, "commit", "-q", "--allow-empty", "-m", "Regenerate")
+		updated := pr
+		updated.Title = "[main] Update Konflux configurations"
+		updated.Body = "Update Konflux configurations"
+		got, err := p.Publish(ctx, updated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &Result{
+			Repository: "openshift-knative/serving",
+			Branch:     "sync-konflux-main",
+			Base:       "main",
+			Number:     1,
+			URL:        "https://github.com/openshift-knative/serving/pull/1",
+			Pushed:     true,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Error("result (-want, +got):", diff)
+		}
+		if diff := cmp.Diff(git(t, local, "rev-parse", "sync-konflux-main"), git(t, fork, "rev-parse", "sync-konflux-main")); diff != "" {
+			t.Error("fork branch (-want, +got):", diff)
+		}
+		if len(gh.pulls) != 1 {
+			t.Fatalf("want 1 pull request, got %d", len(gh.pulls))
+		}
+		if diff := cmp.Diff(updated.Title, gh.pulls[0].Title); diff != "" {
+			t.Error("title (-want, +got):", diff)
+		}
+		gh.takeRequests()
+	})
+
+	t.Run("rebase", func(t *testing.T) {
+		git(t, local, "checkout", "-q", "main")
+		git(t, local, "commit", "-q", "--allow-empty", "-m", "Update main")
+		got, err := p.Publish(ctx, pr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Pushed {
+			t.Error("want rebased branch pushed")
+		}
+		if diff := cmp.Diff(git(t, local, "rev-parse", "main"), git(t, fork, "rev-parse", "sync-konflux-main~2")); diff != "" {
+			t.Error("fork branch base (-want, +got):", diff)
+		}
+		gh.takeRequests()
+	})
+
+	t.Run("no commits", func(t *testing.T) {
+		git(t, local, "branch", "no-commits", "sync-konflux-main")
+		noCommits := pr
+		noCommits.Base = "no-commits"
+		got, err := p.Publish(ctx, noCommits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &Result{Repository: "openshift-knative/serving", Branch: "sync-konflux-main", Base: "no-commits"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Error("result (-want, +got):", diff)
+		}
+		gh.takeRequests()
+	})
+
+	t.Run("results", func(t *testing.T) {
+		var urls []string
+		for _, r := range p.Results() {
+			urls = append(urls, r.URL)
+		}
+		want := []string{
+			"https://github.com/openshift-knative/serving/pull/1",
+			"https://github.com/openshift-knative/serving/pull/1",
+			"https://github.com/openshift-knative/serving/pull/1",
+			"https://github.com/openshift-knative/serving/pull/1",
+			"",
+		}
+		if diff := cmp.Diff(want, urls); diff != "" {
+			t.Error("results (-want, +got):", diff)
+		}
+	})
+
+	t.Run("rebase conflict", func(t *testing.T) {
+		executor := prowgen.NewFakeExecutor().
+			On("", errors.New("exit status 1"), "git", "rebase", "--quiet")
+		_, err := NewPublisher(WithToken("token"), WithAPIURL(server.URL), WithExecutor(executor)).Publish(ctx, pr)
+		if err == nil || !strings.Contains(err.Error(), "failed to rebase sync-konflux-main onto main") {
+			t.Errorf("want rebase error, got %v", err)
+		}
+		want := []string{
+			"git log -1 --format=%s sync-konflux-main",
+			"git log -1 --format=%b sync-konflux-main",
+			"git rebase --quiet main sync-konflux-main",
+			"git rebase --abort",
+		}
+		if diff := cmp.Diff(want, executor.Commands()); diff != "" {
+			t.Error("commands (-want, +got):", diff)
+		}
+		if diff := cmp.Diff([]string(nil), gh.takeRequests()); diff != "" {
+			t.Error("requests (-want, +got):", diff)
+		}
+	})
+
+	t.Run("configured executor", func(t *testing.T) {
+		executor := prowgen.NewFakeExecutor().
+			On("", errors.New("exit status 1"), "git", "rebase", "--quiet")
+		prowgen.SetExecutor(executor)
+		t.Cleanup(func() {
+			prowgen.SetExecutor(nil)
+		})
+		_, err := NewPublisher(WithToken("token"), WithAPIURL(server.URL)).Publish(ctx, pr)
+		if err == nil || !strings.Contains(err.Error(), "failed to rebase sync-konflux-main onto main") {
+			t.Errorf("want rebase error, got %v", err)
+		}
+		if len(executor.Commands()) == 0 {
+			t.Error("want git commands executed by the configured executor")
+		}
+		gh.takeRequests()
+	})
+
+	t.Run("create error", func(t *testing.T) {
+		// Unlike a branch without commits, other validation errors aren't ignored.
+		git(t, local, "branch", "already-exists", "main")
+		failing := pr
+		failing.Base = "already-exists"
+		_, err := p.Publish(ctx, failing)
+		if err == nil || !strings.Contains(err.Error(), "failed to create pull request from serverless-qe:sync-konflux-main to already-exists: GitHub API returned 422: Validation Failed, A pull request already exists") {
+			t.Errorf("want create error, got %v", err)
+		}
+		gh.takeRequests()
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := NewPublisher(WithToken("wrong"), WithAPIURL(server.URL), WithGitURL("file://"+forksDir)).Publish(ctx, pr)
+		if err == nil || !strings.Contains(err.Error(), "GitHub API returned 401: Bad credentials") {
+			t.Errorf("want bad credentials error, got %v", err)
+		}
+	})
+}
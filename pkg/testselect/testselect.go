@@ -41,7 +41,9 @@ func Main() {
 	// Clonerefs options as defined in https://github.com/kubernetes/test-infra/blob/master/prow/clonerefs/options.go
 	refs := flag.String("clonerefs", "clonerefs.json", "Specify json file with clonerefs")
 	outFile := flag.String("output", "tests.txt", "Specify name of output file")
+	workspace := prowgen.RegisterWorkspaceFlags(flag.CommandLine)
 	flag.Parse()
+	ctx = prowgen.WithWorkspace(ctx, workspace)
 
 	log.Println(*ts, *refs, *outFile)
 
@@ -0,0 +1,230 @@
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// ErrDrift is returned when generated files differ from the existing ones.
+var ErrDrift = errors.New("generated files are out of date")
+
+// Diff returns the unified diff between the existing and the generated content of the file at
+// path, nil content means that the file doesn't exist, the diff is empty when they are equal.
+func Diff(path string, existing []byte, generated []byte) string {
+	if existing != nil && generated != nil && bytes.Equal(existing, generated) {
+		return ""
+	}
+	if existing == nil && generated == nil {
+		return ""
+	}
+	from, to := "a/"+path, "b/"+path
+	if existing == nil {
+		from = "/dev/null"
+	}
+	if generated == nil {
+		to = "/dev/null"
+	}
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(existing),
+		B:        splitLines(generated),
+		FromFile: from,
+		ToFile:   to,
+		Context:  3,
+	})
+	if diff == "" {
+		// An empty file is added or removed.
+		diff = fmt.Sprintf("--- %s\n+++ %s\n", from, to)
+	}
+	return diff
+}
+
+// splitLines splits the content in lines, including the new line, like git a missing new line
+// at the end of the file is reported.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] = last + "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
+
+// Files compares the generated files, by path, with the existing files, existing files in
+// removals that aren't generated are reported as removed.
+// Paths in the diff are relative to root.
+func Files(root string, generated map[string][]byte, removals []string) (string, error) {
+	paths := make([]string, 0, len(generated))
+	for path := range generated {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	b := &strings.Builder{}
+	for _, path := range paths {
+		existing, err := readFile(path)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(Diff(relative(root, path), existing, generated[path]))
+	}
+
+	removed := make([]string, 0, len(removals))
+	for _, path := range removals {
+		if _, ok := generated[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+	for i, path := range removed {
+		if i > 0 && removed[i-1] == path {
+			continue
+		}
+		existing, err := readFile(path)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(Diff(relative(root, path), existing, nil))
+	}
+	return b.String(), nil
+}
+
+// Dirs compares every file in the generated directory with the corresponding file in the
+// existing directory, existing files matched by removed (when not nil) that aren't generated
+// are reported as removed.
+// Paths in the diff are relative to the working directory, when possible.
+func Dirs(existing string, generated string, removed func(rel string) bool) (string, error) {
+	root, err := os.Getwd()
+	if err != nil {
+		root = existing
+	}
+	return DirsRelativeTo(root, existing, generated, removed)
+}
+
+// DirsRelativeTo is like Dirs, paths in the diff are relative to root, when possible.
+func DirsRelativeTo(root string, existing string, generated string, removed func(rel string) bool) (string, error) {
+	files, removals, err := DirFiles(existing, generated, removed)
+	if err != nil {
+		return "", err
+	}
+	return Files(root, files, removals)
+}
+
+// DirFiles returns the files in the generated directory, by the path of the corresponding file
+// in the existing directory, and the existing files matched by removed (when not nil), see
+// Files.
+func DirFiles(existing string, generated string, removed func(rel string) bool) (map[string][]byte, []string, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(generated, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(generated, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %q: %w", path, err)
+		}
+		files[filepath.Join(existing, rel)] = content
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	var removals []string
+	if removed != nil {
+		err := filepath.WalkDir(existing, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(existing, path)
+			if err != nil {
+				return err
+			}
+			if removed(filepath.ToSlash(rel)) {
+				removals = append(removals, path)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, err
+		}
+	}
+	return files, removals, nil
+}
+
+// CopyDir copies the files in src to dst, a missing src directory is empty.
+//
+// Generators preserving parts of existing files generate into a copy of the existing directory.
+func CopyDir(src string, dst string) error {
+	if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+		return err
+	}
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %q: %w", path, err)
+		}
+		return os.WriteFile(target, content, os.ModePerm)
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Check writes the diff to w and returns ErrDrift when it isn't empty.
+func Check(w io.Writer, diff string) error {
+	if diff == "" {
+		return nil
+	}
+	if _, err := io.WriteString(w, diff); err != nil {
+		return err
+	}
+	return ErrDrift
+}
+
+// readFile returns nil content when the file doesn't exist.
+func readFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+	return content, nil
+}
+
+func relative(root string, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
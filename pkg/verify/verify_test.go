@@ -0,0 +1,168 @@
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  []byte
+		generated []byte
+		want      string
+	}{
+		{
+			name:      "equal",
+			existing:  []byte("a\nb\n"),
+			generated: []byte("a\nb\n"),
+		},
+		{
+			name: "both missing",
+		},
+		{
+			name:      "changed",
+			existing:  []byte("a\nb\n"),
+			generated: []byte("a\nc\n"),
+			want:      "--- a/file\n+++ b/file\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+		},
+		{
+			name:      "added",
+			generated: []byte("a\n"),
+			want:      "--- /dev/null\n+++ b/file\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:     "removed",
+			existing: []byte("a\n"),
+			want:     "--- a/file\n+++ /dev/null\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name:      "missing new line",
+			existing:  []byte("a\n"),
+			generated: []byte("a"),
+			want:      "--- a/file\n+++ b/file\n@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n",
+		},
+		{
+			name:      "empty file added",
+			generated: []byte{},
+			want:      "--- /dev/null\n+++ b/file\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff("file", tt.existing, tt.generated); got != tt.want {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirs(t *testing.T) {
+	existing := t.TempDir()
+	generated := t.TempDir()
+
+	writeFiles(t, existing, map[string]string{
+		"unchanged.yaml":      "a\n",
+		"changed.yaml":        "a\n",
+		"removed.yaml":        "a\n",
+		"releases/kept.yaml":  "a\n",
+		"nested/changed.yaml": "a\n",
+	})
+	writeFiles(t, generated, map[string]string{
+		"unchanged.yaml":      "a\n",
+		"changed.yaml":        "b\n",
+		"added.yaml":          "a\n",
+		"nested/changed.yaml": "b\n",
+	})
+
+	diff, err := Dirs(existing, generated, func(rel string) bool {
+		return !strings.HasPrefix(rel, "releases/")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rel := func(name string) string {
+		wd, _ := os.Getwd()
+		return relative(wd, filepath.Join(existing, name))
+	}
+	want := Diff(rel("added.yaml"), nil, []byte("a\n")) +
+		Diff(rel("changed.yaml"), []byte("a\n"), []byte("b\n")) +
+		Diff(rel("nested/changed.yaml"), []byte("a\n"), []byte("b\n")) +
+		Diff(rel("removed.yaml"), []byte("a\n"), nil)
+	if diff != want {
+		t.Errorf("Dirs() =\n%s\nwant:\n%s", diff, want)
+	}
+
+	diff, err = Dirs(existing, generated, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(diff, "removed.yaml") {
+		t.Errorf("expected no removed files without a matcher, got:\n%s", diff)
+	}
+
+	diff, err = Dirs(filepath.Join(existing, "missing"), filepath.Join(generated, "missing"), nil)
+	if err != nil || diff != "" {
+		t.Errorf("expected missing directories to be equal, got %q, %v", diff, err)
+	}
+
+	diff, err = DirsRelativeTo(existing, filepath.Join(existing, "nested"), filepath.Join(generated, "nested"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := Diff("nested/changed.yaml", []byte("a\n"), []byte("b\n")); diff != want {
+		t.Errorf("DirsRelativeTo() =\n%s\nwant:\n%s", diff, want)
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "copy")
+	writeFiles(t, src, map[string]string{
+		"a.yaml":   "a\n",
+		"b/c.yaml": "c\n",
+	})
+
+	if err := CopyDir(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	diff, err := Dirs(src, dst, func(string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Errorf("expected copy to be equal, got:\n%s", diff)
+	}
+
+	if err := CopyDir(filepath.Join(src, "missing"), filepath.Join(dst, "missing")); err != nil {
+		t.Errorf("expected missing directory to be copied as empty, got %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := Check(out, ""); err != nil || out.Len() != 0 {
+		t.Errorf("expected no error and no output for an empty diff, got %v, %q", err, out.String())
+	}
+	if err := Check(out, "diff\n"); !errors.Is(err, ErrDrift) || out.String() != "diff\n" {
+		t.Errorf("expected ErrDrift and the diff, got %v, %q", err, out.String())
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+}